@@ -17,6 +17,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/enr"
 	"github.com/protolambda/rumor/control/actor/gossip"
 	"github.com/protolambda/rumor/control/actor/host"
+	actormetrics "github.com/protolambda/rumor/control/actor/metrics"
 	"github.com/protolambda/rumor/control/actor/peer"
 	"github.com/protolambda/rumor/control/actor/peer/metadata"
 	"github.com/protolambda/rumor/control/actor/peer/status"
@@ -25,6 +26,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/states"
 	"github.com/protolambda/rumor/control/tool"
 	"github.com/protolambda/rumor/p2p/addrutil"
+	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/protolambda/rumor/metrics"
     "github.com/protolambda/rumor/p2p/track"
 	"github.com/sirupsen/logrus"
@@ -59,8 +61,10 @@ type Actor struct {
 
 	Dv5State dv5.Dv5State
 
-	GossipState metrics.GossipState
-	RPCState    rpc.RPCState
+	GossipState   metrics.GossipState
+	GossipSubnets gossip.SubnetsState
+	RPCState      rpc.RPCState
+	RPCMetrics    metrics.RPCMetrics
 
 	HostState host.HostState
 
@@ -118,6 +122,20 @@ func (r *Actor) GetNode() (n *enode.Node, ok bool) {
 	}
 }
 
+// CurrentForkDigest gets the fork digest from the eth2 entry of the local ENR,
+// or falls back to the fork digest of the local status.
+func (r *Actor) CurrentForkDigest() (digest beacon.ForkDigest, ok bool) {
+	if n, ok := r.GetNode(); ok {
+		if dat, exists, err := addrutil.ParseEnrEth2Data(n); err == nil && exists {
+			return dat.ForkDigest, true
+		}
+	}
+	if fd := r.PeerStatusState.LocalStatus().ForkDigest; fd != (beacon.ForkDigest{}) {
+		return fd, true
+	}
+	return beacon.ForkDigest{}, false
+}
+
 func (r *Actor) MakeCmd(log logrus.FieldLogger, control base.Control, out io.Writer) *ActorCmd {
 	return &ActorCmd{
 		Actor:   r,
@@ -183,6 +201,8 @@ func (c *ActorCmd) Cmd(route string) (cmd interface{}, err error) {
 			PeerStatusState:   &c.PeerStatusState,
 			PeerMetadataState: &c.PeerMetadataState,
 			Store:             store,
+			Chains:            c.GlobalChains,
+			ChainState:        &c.ChainState,
 		}
 	case "peerstore":
 		cmd = &peerstore.PeerstoreCmd{
@@ -198,12 +218,26 @@ func (c *ActorCmd) Cmd(route string) (cmd interface{}, err error) {
 		cmd = &dv5.Dv5Cmd{Base: b, Dv5State: &c.Dv5State, Dv5Settings: settings, CurrentPeerstore: c.CurrentPeerstore}
 	case "gossip":
 		store := c.CurrentPeerstore
-        if !store.Initialized() {
-            store = nil
-        }
-        cmd = &gossip.GossipCmd{Base: b, GossipState: &c.GossipState, Store: store}
+		if !store.Initialized() {
+			store = nil
+		}
+		cmd = &gossip.GossipCmd{Base: b, GossipState: &c.GossipState, WithForkDigest: c, Store: store,
+			Chains: c.GlobalChains, ChainState: &c.ChainState,
+			SubnetsState: &c.GossipSubnets, Lazy: &c.LazyEnrState, PeerMetadataState: &c.PeerMetadataState}
 	case "rpc":
-		cmd = &rpc.RpcCmd{Base: b, RPCState: &c.RPCState}
+		cmd = &rpc.RpcCmd{Base: b, RPCState: &c.RPCState,
+			PeerStatusState: &c.PeerStatusState, PeerMetadataState: &c.PeerMetadataState,
+			Scores: c.scores(), Chains: c.GlobalChains, ChainState: &c.ChainState}
+	case "metrics":
+		cmd = &actormetrics.MetricsCmd{
+			Base:        b,
+			GossipState: &c.GossipState,
+			RPCMetrics:  &c.RPCMetrics,
+			Dv5State:    &c.Dv5State,
+			Store:       c.CurrentPeerstore,
+			Chains:      c.GlobalChains,
+			ChainState:  &c.ChainState,
+		}
 	case "blocks":
 		cmd = &blocks.BlocksCmd{Base: b, DBs: c.GlobalBlocksDBs, DBState: &c.BlocksState}
 	case "states":
@@ -218,7 +252,7 @@ func (c *ActorCmd) Cmd(route string) (cmd interface{}, err error) {
 			return nil, errors.New("no states DB available, try 'states create'")
 		}
 		cmd = &chain.ChainCmd{Base: b, Chains: c.GlobalChains,
-			ChainState: &c.ChainState, Blocks: bl, States: st}
+			ChainState: &c.ChainState, Blocks: bl, States: st, Scores: c.scores()}
 	case "sleep":
 		cmd = &SleepCmd{Base: b}
 	case "tool":
@@ -230,7 +264,7 @@ func (c *ActorCmd) Cmd(route string) (cmd interface{}, err error) {
 }
 
 var topRoutes = []string{"host", "enr", "peer", "peerstore", "dv5", "gossip",
-	"rpc", "blocks", "states", "chain", "sleep", "tool"}
+	"rpc", "metrics", "blocks", "states", "chain", "sleep", "tool"}
 var topRoutesMap = map[string]struct{}{}
 
 func init() {
@@ -239,6 +273,14 @@ func init() {
 	}
 }
 
+// scores is the score book of the current peerstore, or nil if there is no peerstore yet
+func (c *ActorCmd) scores() track.ScoreBook {
+	if !c.CurrentPeerstore.Initialized() {
+		return nil
+	}
+	return c.CurrentPeerstore
+}
+
 func (c *ActorCmd) Routes() []string {
 	return topRoutes
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/sirupsen/logrus"
 	"io"
 )
@@ -53,6 +54,11 @@ type WithEnrNode interface {
 	GetNode() (n *enode.Node, ok bool)
 }
 
+type WithForkDigest interface {
+	// CurrentForkDigest returns the fork digest the actor is on, if it is known.
+	CurrentForkDigest() (digest beacon.ForkDigest, ok bool)
+}
+
 type PrivSettings interface {
 	// GetPriv, may be nil
 	GetPriv() *crypto.Secp256k1PrivateKey
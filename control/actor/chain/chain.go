@@ -8,6 +8,7 @@ import (
 	sdb "github.com/protolambda/rumor/chain/db/states"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/chain/chcmd"
+	"github.com/protolambda/rumor/p2p/track"
 )
 
 type ChainState struct {
@@ -20,6 +21,8 @@ type ChainCmd struct {
 	*ChainState
 	Blocks bdb.DB
 	States sdb.DB
+	// Peers are scored by the outcome of sync requests. May be nil.
+	Scores track.ScoreBook
 }
 
 // TODO: more chain command ideas:
@@ -42,9 +45,9 @@ func (c *ChainCmd) Cmd(route string) (cmd interface{}, err error) {
 		if !ok {
 			return nil, fmt.Errorf("current chain was not found. Use 'chain create' to create chains")
 		}
-		cmd = &chcmd.ChainCmd{Base: c.Base, Chain: currentChain, Blocks: c.Blocks, States: c.States}
+		cmd = &chcmd.ChainCmd{Base: c.Base, Chain: currentChain, Blocks: c.Blocks, States: c.States, Scores: c.Scores}
 	case "on":
-		cmd = &OnCmd{Base: c.Base, Chains: c.Chains, Blocks: c.Blocks, States: c.States}
+		cmd = &OnCmd{Base: c.Base, Chains: c.Chains, Blocks: c.Blocks, States: c.States, Scores: c.Scores}
 	default:
 		return nil, ask.UnrecognizedErr
 	}
@@ -11,6 +11,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/chain/chcmd/hot"
 	"github.com/protolambda/rumor/control/actor/chain/chcmd/serve"
 	"github.com/protolambda/rumor/control/actor/chain/chcmd/sync"
+	"github.com/protolambda/rumor/p2p/track"
 )
 
 type ChainCmd struct {
@@ -18,6 +19,7 @@ type ChainCmd struct {
 	Chain  chain.FullChain
 	Blocks bdb.DB
 	States sdb.DB
+	Scores track.ScoreBook
 }
 
 func (c *ChainCmd) Cmd(route string) (cmd interface{}, err error) {
@@ -33,9 +35,9 @@ func (c *ChainCmd) Cmd(route string) (cmd interface{}, err error) {
 	case "head":
 		cmd = &head.HeadCmd{Base: c.Base}
 	case "serve":
-		cmd = &serve.ServeCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks}
+		cmd = &serve.ServeCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks, Scores: c.Scores}
 	case "sync":
-		cmd = &sync.SyncCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks}
+		cmd = &sync.SyncCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks, Scores: c.Scores}
 	case "votes":
 		cmd = &VotesCmd{Base: c.Base}
 	default:
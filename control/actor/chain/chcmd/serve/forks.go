@@ -0,0 +1,23 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"io"
+)
+
+// The slot of a serialized signed block: after the message offset (4) and signature (96)
+const signedBlockSlotOffset = 4 + 96
+
+// peekBlockSlot reads the slot of a serialized signed block,
+// and returns a reader that still streams the complete block.
+func peekBlockSlot(r io.Reader) (beacon.Slot, io.Reader, error) {
+	var head [signedBlockSlotOffset + 8]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, fmt.Errorf("failed to read block slot: %v", err)
+	}
+	slot := beacon.Slot(binary.LittleEndian.Uint64(head[signedBlockSlotOffset:]))
+	return slot, io.MultiReader(bytes.NewReader(head[:]), r), nil
+}
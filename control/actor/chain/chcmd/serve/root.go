@@ -13,6 +13,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
 	"time"
 )
 
@@ -21,9 +22,13 @@ type ByRootCmd struct {
 
 	Blocks bdb.DB
 	Chain  chain.FullChain
+	Scores track.ScoreBook
 
 	Timeout     time.Duration         `ask:"--timeout" help:"Timeout for full request and response. 0 to disable"`
 	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	Version     string                `ask:"--version" help:"Version of the RPC method to serve"`
+
+	Limits flags.StreamLimitFlags `ask:"."`
 
 	MaxCount   uint64 `ask:"--max-count" help:"Max amount of roots to accept requests of"`
 	WithinView bool   `ask:"--within-view" help:"Only allow requests for blocks within view of chain. I.e. either canon cold, or any hot block."`
@@ -32,6 +37,8 @@ type ByRootCmd struct {
 func (c *ByRootCmd) Default() {
 	c.Timeout = 20 * time.Second
 	c.Compression.Compression = reqresp.SnappyCompression{}
+	c.Limits = flags.DefaultStreamLimits()
+	c.Version = "1"
 	c.MaxCount = methods.MAX_REQUEST_BLOCKS_BY_ROOT
 	c.WithinView = true
 }
@@ -50,6 +57,16 @@ func (c *ByRootCmd) Run(ctx context.Context, args ...string) error {
 		return errors.New("need a blocks DB to serve blocks from")
 	}
 
+	entry, err := methods.DefaultRegistry.Find("blocks-by-root", c.Version)
+	if err != nil {
+		return err
+	}
+	spec := c.Blocks.Spec()
+	forks, err := methods.HeadForkSchedule(ctx, spec, c.Chain)
+	if err != nil {
+		return err
+	}
+	method := entry.Method(forks)
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 	sCtxFn := func() context.Context {
 		if c.Timeout == 0 {
@@ -58,8 +75,6 @@ func (c *ByRootCmd) Run(ctx context.Context, args ...string) error {
 		reqCtx, _ := context.WithTimeout(bgCtx, c.Timeout)
 		return reqCtx
 	}
-	spec := c.Blocks.Spec()
-	method := methods.BlocksByRootRPCv1(spec)
 	prot := method.Protocol
 	if c.Compression.Compression != nil {
 		prot += protocol.ID("_" + c.Compression.Compression.Name())
@@ -113,19 +128,35 @@ func (c *ByRootCmd) Run(ctx context.Context, args ...string) error {
 				respondErr(reqresp.ServerErrCode, fmt.Sprintf("failed to find block %s", root))
 				return
 			}
-			if err := handler.StreamResponseChunk(reqresp.SuccessCode, size, r); err != nil {
+			if method.ContextBytesLen > 0 {
+				slot, r, err := peekBlockSlot(r)
+				if err != nil {
+					c.Log.WithFields(f).WithField("block", hex.EncodeToString(root[:])).WithError(err).Warn("failed to read block")
+					respondErr(reqresp.ServerErrCode, fmt.Sprintf("failed to read block %s", root))
+					return
+				}
+				fork := forks.AtSlot(slot)
+				digest := forks.Digest(&fork)
+				err = handler.StreamResponseChunkWithContext(reqresp.SuccessCode, digest[:], size, r)
+				if err != nil {
+					c.Log.WithFields(f).WithField("block", hex.EncodeToString(root[:])).WithError(err).Warn("failed to write block")
+					return
+				}
+			} else if err := handler.StreamResponseChunk(reqresp.SuccessCode, size, r); err != nil {
 				c.Log.WithFields(f).WithField("block", hex.EncodeToString(root[:])).WithError(err).Warn("failed to write block")
 				return
 			}
 		}
 	}
-	streamHandler := method.MakeStreamHandler(sCtxFn, c.Compression.Compression, listenReq)
+	limiter := c.Limits.Limiter(bgCtx, c.Log)
+	streamHandler := method.MakeLimitedStreamHandler(sCtxFn, c.Compression.Compression, limiter, track.ScoredRequestListener(c.Scores, listenReq))
 	h.SetStreamHandler(prot, streamHandler)
 	c.Log.WithField("started", true).Infof("Started by-root serving")
 
 	c.Control.RegisterStop(func(ctx context.Context) error {
 		bgCancel()
 		h.RemoveStreamHandler(prot)
+		flags.LogStreamStats(c.Log, limiter)
 		c.Log.Infof("Stopped by-root serving")
 		return nil
 	})
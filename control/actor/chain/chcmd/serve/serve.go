@@ -5,20 +5,22 @@ import (
 	"github.com/protolambda/rumor/chain"
 	bdb "github.com/protolambda/rumor/chain/db/blocks"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/track"
 )
 
 type ServeCmd struct {
 	*base.Base
 	Chain  chain.FullChain
 	Blocks bdb.DB
+	Scores track.ScoreBook
 }
 
 func (c *ServeCmd) Cmd(route string) (cmd interface{}, err error) {
 	switch route {
 	case "by-range":
-		cmd = &ByRangeCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks}
+		cmd = &ByRangeCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks, Scores: c.Scores}
 	case "by-root":
-		cmd = &ByRootCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks}
+		cmd = &ByRootCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks, Scores: c.Scores}
 	default:
 		return nil, ask.UnrecognizedErr
 	}
@@ -6,10 +6,35 @@ import (
 	"fmt"
 	"github.com/protolambda/rumor/chain"
 	bdb "github.com/protolambda/rumor/chain/db/blocks"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/sirupsen/logrus"
 )
 
+// decodeBlock decodes a block chunk with the type of the fork of the context bytes, or as phase0 block without context bytes.
+// The chain and block DB only hold phase0 blocks, blocks of later forks are rejected.
+func decodeBlock(forks *methods.ForkSchedule, chunk reqresp.ChunkedResponseHandler) (*beacon.SignedBeaconBlock, error) {
+	forkName := "phase0"
+	if ctxBytes := chunk.ContextBytes(); len(ctxBytes) > 0 {
+		fork, err := forks.ByDigest(ctxBytes)
+		if err != nil {
+			return nil, err
+		}
+		forkName = fork.Name
+	}
+	switch forkName {
+	case "phase0":
+		var block beacon.SignedBeaconBlock
+		if err := chunk.ReadObj(forks.Spec.Wrap(&block)); err != nil {
+			return nil, err
+		}
+		return &block, nil
+	default:
+		return nil, fmt.Errorf("cannot sync blocks of unsupported fork %s", forkName)
+	}
+}
+
 type SyncFn func(blocksCh chan<- *beacon.SignedBeaconBlock) error
 
 type handleSync struct {
@@ -11,6 +11,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/protolambda/ztyp/view"
 	"time"
@@ -21,8 +22,10 @@ type ByRangeCmd struct {
 
 	Blocks bdb.DB
 	Chain  chain.FullChain
+	Scores track.ScoreBook
 
 	PeerID         flags.PeerIDFlag      `ask:"--peer" help:"Peers to make blocks-by-range request to."`
+	Version        string                `ask:"--version" help:"Version of the blocks-by-range method to use"`
 	StartSlot      beacon.Slot           `ask:"--start" help:"Start slot of request"`
 	Count          uint64                `ask:"--count" help:"Count of blocks of request"`
 	Step           uint64                `ask:"--step" help:"Step between slots of blocks of request"`
@@ -34,6 +37,7 @@ type ByRangeCmd struct {
 }
 
 func (c *ByRangeCmd) Default() {
+	c.Version = "1"
 	c.Timeout = 20 * time.Second
 	c.ProcessTimeout = 20 * time.Second
 	c.Compression.Compression = reqresp.SnappyCompression{}
@@ -62,7 +66,15 @@ func (c *ByRangeCmd) Run(ctx context.Context, args ...string) error {
 	}
 
 	spec := c.Blocks.Spec()
-	method := methods.BlocksByRangeRPCv1(spec)
+	entry, err := methods.DefaultRegistry.Find("blocks-by-range", c.Version)
+	if err != nil {
+		return err
+	}
+	forks, err := methods.HeadForkSchedule(ctx, spec, c.Chain)
+	if err != nil {
+		return err
+	}
+	method := entry.Method(forks)
 	peerId := c.PeerID.PeerID
 
 	protocolId := method.Protocol
@@ -98,7 +110,7 @@ func (c *ByRangeCmd) Run(ctx context.Context, args ...string) error {
 		Process: c.Process,
 	}.handle(procCtx, func(blocksCh chan<- *beacon.SignedBeaconBlock) error {
 
-		return method.RunRequest(reqCtx, sFn, peerId, c.Compression.Compression, reqresp.RequestSSZInput{Obj: &req}, uint64(req.Count),
+		return track.ScoredRequest(c.Scores, method, reqCtx, sFn, peerId, c.Compression.Compression, reqresp.RequestSSZInput{Obj: &req}, uint64(req.Count),
 			func() error {
 				// TODO
 				return nil
@@ -121,8 +133,8 @@ func (c *ByRangeCmd) Run(ctx context.Context, args ...string) error {
 					c.Log.WithField("chunk", f).Warn("Received error response")
 					return fmt.Errorf("got error response %d on chunk %d: %s", resultCode, chunk.ChunkIndex(), msg)
 				case reqresp.SuccessCode:
-					var block beacon.SignedBeaconBlock
-					if err := chunk.ReadObj(spec.Wrap(&block)); err != nil {
+					block, err := decodeBlock(forks, chunk)
+					if err != nil {
 						return err
 					}
 					if block.Message.Slot < req.StartSlot || uint64(block.Message.Slot-req.StartSlot)%uint64(req.Step) != 0 || block.Message.Slot >= expectedEnd {
@@ -130,7 +142,7 @@ func (c *ByRangeCmd) Run(ctx context.Context, args ...string) error {
 							req.StartSlot, req.Step, req.Count, expectedEnd, block.Message.Slot)
 					}
 					c.Log.WithField("chunk", f).Debug("Received block")
-					blocksCh <- &block
+					blocksCh <- block
 					return nil
 				default:
 					return fmt.Errorf("received chunk (index %d, size %d) with unknown result code %d", chunk.ChunkIndex(), chunk.ChunkSize(), resultCode)
@@ -10,6 +10,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"time"
 )
@@ -19,11 +20,14 @@ type ByRootCmd struct {
 
 	Blocks bdb.DB
 	Chain  chain.FullChain
+	Scores track.ScoreBook
 
 	PeerID flags.PeerIDFlag `ask:"--peer" help:"Peers to make blocks-by-root request to."`
 
 	Roots []beacon.Root `ask:"--roots" help:"Block roots to request"`
 
+	Version string `ask:"--version" help:"Version of the blocks-by-root method to use"`
+
 	Timeout        time.Duration         `ask:"--timeout" help:"Timeout for full request and response. 0 to disable"`
 	ProcessTimeout time.Duration         `ask:"--process-timeout" help:"Timeout for parallel processing of blocks. 0 to disable."`
 	Compression    flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
@@ -32,6 +36,7 @@ type ByRootCmd struct {
 }
 
 func (c *ByRootCmd) Default() {
+	c.Version = "1"
 	c.Timeout = 20 * time.Second
 	c.ProcessTimeout = 20 * time.Second
 	c.Compression.Compression = reqresp.SnappyCompression{}
@@ -56,7 +61,15 @@ func (c *ByRootCmd) Run(ctx context.Context, args ...string) error {
 	}
 
 	spec := c.Blocks.Spec()
-	method := methods.BlocksByRootRPCv1(spec)
+	entry, err := methods.DefaultRegistry.Find("blocks-by-root", c.Version)
+	if err != nil {
+		return err
+	}
+	forks, err := methods.HeadForkSchedule(ctx, spec, c.Chain)
+	if err != nil {
+		return err
+	}
+	method := entry.Method(forks)
 	peerId := c.PeerID.PeerID
 
 	protocolId := method.Protocol
@@ -89,7 +102,7 @@ func (c *ByRootCmd) Run(ctx context.Context, args ...string) error {
 		Store:   c.Store,
 		Process: c.Process,
 	}.handle(procCtx, func(blocksCh chan<- *beacon.SignedBeaconBlock) error {
-		return method.RunRequest(reqCtx, sFn, peerId, c.Compression.Compression, reqresp.RequestSSZInput{Obj: &req}, uint64(len(req)),
+		return track.ScoredRequest(c.Scores, method, reqCtx, sFn, peerId, c.Compression.Compression, reqresp.RequestSSZInput{Obj: &req}, uint64(len(req)),
 			func() error {
 				// TODO
 				return nil
@@ -112,17 +125,17 @@ func (c *ByRootCmd) Run(ctx context.Context, args ...string) error {
 					c.Log.WithField("chunk", f).Warn("Received error response")
 					return fmt.Errorf("got error response %d on chunk %d: %s", resultCode, chunk.ChunkIndex(), msg)
 				case reqresp.SuccessCode:
-					var block beacon.SignedBeaconBlock
-					if err := chunk.ReadObj(spec.Wrap(&block)); err != nil {
+					block, err := decodeBlock(forks, chunk)
+					if err != nil {
 						return err
 					}
-					withRoot := bdb.WithRoot(spec, &block)
+					withRoot := bdb.WithRoot(spec, block)
 					expectedRoot := req[chunk.ChunkIndex()]
 					if withRoot.Root != expectedRoot {
 						return fmt.Errorf("bad block, expected root %s, got %s", withRoot.Root, expectedRoot)
 					}
 					c.Log.WithField("chunk", f).Debug("Received block")
-					blocksCh <- &block
+					blocksCh <- block
 					return nil
 				default:
 					return fmt.Errorf("received chunk (index %d, size %d) with unknown result code %d", chunk.ChunkIndex(), chunk.ChunkSize(), resultCode)
@@ -5,12 +5,14 @@ import (
 	"github.com/protolambda/rumor/chain"
 	bdb "github.com/protolambda/rumor/chain/db/blocks"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/track"
 )
 
 type SyncCmd struct {
 	*base.Base
 	Chain  chain.FullChain
 	Blocks bdb.DB
+	Scores track.ScoreBook
 }
 
 // TODO: implement an auto-sync command, that
@@ -18,9 +20,9 @@ type SyncCmd struct {
 func (c *SyncCmd) Cmd(route string) (cmd interface{}, err error) {
 	switch route {
 	case "by-range":
-		cmd = &ByRangeCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks}
+		cmd = &ByRangeCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks, Scores: c.Scores}
 	case "by-root":
-		cmd = &ByRootCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks}
+		cmd = &ByRootCmd{Base: c.Base, Chain: c.Chain, Blocks: c.Blocks, Scores: c.Scores}
 	default:
 		return nil, ask.UnrecognizedErr
 	}
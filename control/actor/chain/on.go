@@ -7,6 +7,7 @@ import (
 	sdb "github.com/protolambda/rumor/chain/db/states"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/chain/chcmd"
+	"github.com/protolambda/rumor/p2p/track"
 )
 
 type OnCmd struct {
@@ -14,6 +15,7 @@ type OnCmd struct {
 	chain.Chains
 	Blocks bdb.DB
 	States sdb.DB
+	Scores track.ScoreBook
 }
 
 func (c *OnCmd) Help() string {
@@ -32,5 +34,5 @@ func (c *OnCmd) Cmd(route string) (cmd interface{}, err error) {
 	if !ok {
 		return nil, errors.New("chain not available, create one with 'chains create'")
 	}
-	return &chcmd.ChainCmd{Base: c.Base, Chain: ch, Blocks: c.Blocks, States: c.States}, nil
+	return &chcmd.ChainCmd{Base: c.Base, Chain: ch, Blocks: c.Blocks, States: c.States, Scores: c.Scores}, nil
 }
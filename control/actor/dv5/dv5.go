@@ -10,7 +10,10 @@ import (
 )
 
 type Dv5State struct {
-	Dv5Node dv5.Discv5
+	// Number of nodes found by lookups and random node iteration, accessed atomically.
+	// First in the struct, to be 64-bit aligned.
+	NodesFound uint64
+	Dv5Node    dv5.Discv5
 }
 
 type Dv5Cmd struct {
@@ -5,6 +5,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/flags"
+	"sync/atomic"
 )
 
 type Dv5LookupCmd struct {
@@ -26,6 +27,7 @@ func (c *Dv5LookupCmd) Run(ctx context.Context, args ...string) error {
 	}
 
 	res := c.Dv5State.Dv5Node.Lookup(c.Target.ID)
+	atomic.AddUint64(&c.Dv5State.NodesFound, uint64(len(res)))
 	enrs := make([]string, 0, len(res))
 	for _, v := range res {
 		enrs = append(enrs, v.String())
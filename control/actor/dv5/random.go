@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/sirupsen/logrus"
+	"sync/atomic"
 	"time"
 )
 
@@ -39,6 +40,7 @@ func (c *Dv5RandomCmd) Run(ctx context.Context, args ...string) error {
 		if res == nil {
 			return true, errors.New("no new node available")
 		}
+		atomic.AddUint64(&c.Dv5State.NodesFound, 1)
 		entry := c.Log.WithFields(logrus.Fields{"enr": res.String(), "id": res.ID().String()})
 		if err := c.HandleENR.handle(c.Log, res); err != nil {
 			return false, err
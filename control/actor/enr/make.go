@@ -12,6 +12,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/addrutil"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/rumor/p2p/peering/enrstate"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"net"
@@ -37,10 +38,11 @@ type EnrMakeCmd struct {
 	GenPriv bool                 `ask:"--gen-priv" help:"If no private key is known, and none is provided, then generate one"`
 	Priv    flags.P2pPrivKeyFlag `ask:"--priv" help:"Private key, in raw hex encoded format (32 bytes -> 64 hex chars with optional 0x prefix). Cannot overwrite a current private key."`
 
-	Attnets         beacon.AttnetBits `ask:"--attnets" help:"Attnet bitfield, as bytes."`
-	ForkDigest      beacon.ForkDigest `ask:"--fork-digest" help:"Eth2 fork digest"`
-	NextForkVersion beacon.Version    `ask:"--next-fork-version" help:"Eth2 next fork version"`
-	NextForkEpoch   beacon.Epoch      `ask:"--next-fork-epoch" help:"Eth2 next fork epoch"`
+	Attnets         beacon.AttnetBits  `ask:"--attnets" help:"Attnet bitfield, as bytes."`
+	Syncnets        altair.SyncnetBits `ask:"--syncnets" help:"Syncnet bitfield, as bytes."`
+	ForkDigest      beacon.ForkDigest  `ask:"--fork-digest" help:"Eth2 fork digest"`
+	NextForkVersion beacon.Version     `ask:"--next-fork-version" help:"Eth2 next fork version"`
+	NextForkEpoch   beacon.Epoch       `ask:"--next-fork-epoch" help:"Eth2 next fork epoch"`
 
 	IPChanged          bool `changed:"ip"`
 	StaticIPChanged    bool `changed:"static-ip"`
@@ -50,6 +52,7 @@ type EnrMakeCmd struct {
 	UDPChanged         bool `changed:"udp"`
 
 	AttnetsChanged         bool `changed:"attnets"`
+	SyncnetsChanged        bool `changed:"syncnets"`
 	ForkDigestChanged      bool `changed:"fork-digest"`
 	NextForkVersionChanged bool `changed:"next-fork-version"`
 	NextForkEpochChanged   bool `changed:"next-fork-epoch"`
@@ -167,6 +170,10 @@ func (c *EnrMakeCmd) Run(ctx context.Context, args ...string) error {
 		c.Lazy.Current.SetAttnets(&c.Attnets)
 	}
 
+	if c.SyncnetsChanged {
+		c.Lazy.Current.SetSyncnets(&c.Syncnets)
+	}
+
 	if c.ForkDigestChanged || c.NextForkVersionChanged || c.NextForkEpochChanged {
 		// If they didn't all change, merge in the existing data (if any)
 		if !(c.ForkDigestChanged && c.NextForkVersionChanged && c.NextForkEpochChanged) {
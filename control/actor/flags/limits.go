@@ -0,0 +1,81 @@
+package flags
+
+import (
+	"context"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// StreamLimitFlags configure the concurrency limits of a served RPC method, and how to report the handled streams.
+// Embed them in a serve command with `ask:"."`.
+type StreamLimitFlags struct {
+	MaxStreams     int           `ask:"--max-streams" help:"Max number of concurrently handled streams. 0 for no limit"`
+	MaxPeerStreams int           `ask:"--max-peer-streams" help:"Max number of concurrently handled streams per peer. 0 for no limit"`
+	LogStreams     bool          `ask:"--log-streams" help:"Log the size and outcome of every handled stream"`
+	StatsInterval  time.Duration `ask:"--stats-interval" help:"Interval to log the stream counters at. 0 to only log them when stopping"`
+}
+
+// DefaultStreamLimits allows up to two concurrent streams per peer, as the spec allows requesters
+// to make two concurrent requests with the same protocol ID, and up to 100 streams in total.
+func DefaultStreamLimits() StreamLimitFlags {
+	return StreamLimitFlags{MaxStreams: 100, MaxPeerStreams: 2}
+}
+
+// Limiter creates a stream limiter with the configured limits, and logs the counters at the configured interval until ctx is done.
+func (f *StreamLimitFlags) Limiter(ctx context.Context, log logrus.FieldLogger) *reqresp.StreamLimiter {
+	l := &reqresp.StreamLimiter{
+		MaxStreams:     f.MaxStreams,
+		MaxPeerStreams: f.MaxPeerStreams,
+		OnStream: func(ev *reqresp.StreamEvent) {
+			if !f.LogStreams && !ev.Rejected {
+				return
+			}
+			entry := log.WithFields(logrus.Fields{
+				"from":          ev.Peer.String(),
+				"protocol":      ev.Protocol,
+				"rejected":      ev.Rejected,
+				"timed_out":     ev.TimedOut,
+				"bytes_read":    ev.BytesRead,
+				"bytes_written": ev.BytesWritten,
+				"duration":      ev.Duration.String(),
+			})
+			if !ev.Rejected {
+				entry.Info("Handled stream")
+			} else if f.LogStreams {
+				entry.Info("Rejected stream")
+			} else {
+				// rejections may come in floods, only log them when debugging
+				entry.Debug("Rejected stream")
+			}
+		},
+	}
+	if f.StatsInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(f.StatsInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					LogStreamStats(log, l)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return l
+}
+
+// LogStreamStats logs the current counters of the stream limiter
+func LogStreamStats(log logrus.FieldLogger, l *reqresp.StreamLimiter) {
+	stats := l.Stats()
+	log.WithFields(logrus.Fields{
+		"active":        l.Active(),
+		"accepted":      stats.Accepted,
+		"rejected":      stats.Rejected,
+		"timed_out":     stats.TimedOut,
+		"bytes_read":    stats.BytesRead,
+		"bytes_written": stats.BytesWritten,
+	}).Info("Stream stats")
+}
@@ -2,7 +2,10 @@ package flags
 
 import (
 	"fmt"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"strings"
 )
 
 type CompressionFlag struct {
@@ -20,17 +23,51 @@ func (f *CompressionFlag) String() string {
 }
 
 func (f *CompressionFlag) Set(v string) error {
-	if v == "snappy" {
-		f.Compression = reqresp.SnappyCompression{}
-		return nil
-	} else if v == "none" || v == "" {
+	if v == "none" || v == "ssz" || v == "" {
 		f.Compression = nil
 		return nil
-	} else {
-		return fmt.Errorf("unrecognized compression: %s", v)
 	}
+	// Offer an encoding that is not actually known, to test how peers respond to it.
+	if strings.HasPrefix(v, "passthrough:") {
+		suffix := v[len("passthrough:"):]
+		if suffix == "" || strings.Contains(suffix, "/") {
+			return fmt.Errorf("invalid passthrough compression name: %q", suffix)
+		}
+		f.Compression = reqresp.PassthroughCompression{Suffix: suffix}
+		return nil
+	}
+	if c, ok := reqresp.DefaultCompressions.Get(v); ok {
+		f.Compression = c
+		return nil
+	}
+	return fmt.Errorf("unrecognized compression: %s, expected 'none', 'passthrough:<name>' or one of: %s",
+		v, strings.Join(reqresp.DefaultCompressions.Names(), ", "))
 }
 
 func (f *CompressionFlag) Type() string {
 	return "RPC compression"
 }
+
+type GoodbyeReasonFlag struct {
+	Reason beacon.Goodbye
+}
+
+func (f *GoodbyeReasonFlag) String() string {
+	if f == nil {
+		return "nil goodbye reason"
+	}
+	return fmt.Sprintf("%d (%s)", uint64(f.Reason), methods.GoodbyeReasonName(f.Reason))
+}
+
+func (f *GoodbyeReasonFlag) Set(v string) error {
+	reason, err := methods.ParseGoodbyeReason(v)
+	if err != nil {
+		return err
+	}
+	f.Reason = reason
+	return nil
+}
+
+func (f *GoodbyeReasonFlag) Type() string {
+	return "goodbye reason"
+}
@@ -5,14 +5,15 @@ import (
 	"fmt"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/track"
 	"github.com/sirupsen/logrus"
-    "github.com/protolambda/rumor/p2p/track"
-    "github.com/protolambda/rumor/metrics"
 )
 
 type GossipEventsCmd struct {
 	*base.Base
 	*metrics.GossipState
+	base.WithForkDigest
     Store   track.ExtendedPeerstore
 	TopicName string `ask:"<topic>" help:"The name of the topic to track events of"`
 }
@@ -25,9 +26,13 @@ func (c *GossipEventsCmd) Run(ctx context.Context, args ...string) error {
 	if c.GossipState.GsNode == nil {
 		return NoGossipErr
 	}
-	top, ok := c.GossipState.Topics.Load(c.TopicName)
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	top, ok := c.GossipState.Topics.Load(topicName)
 	if !ok {
-		return fmt.Errorf("not on gossip topic %s", c.TopicName)
+		return fmt.Errorf("not on gossip topic %s", topicName)
 	}
 	evHandler, err := top.(*pubsub.Topic).EventHandler()
 	if err != nil {
@@ -35,21 +40,21 @@ func (c *GossipEventsCmd) Run(ctx context.Context, args ...string) error {
 	}
 	ctx, cancelEvs := context.WithCancel(ctx)
 	go func() {
-		c.Log.Infof("Started listening for peer join/leave events for topic %s", c.TopicName)
+		c.Log.Infof("Started listening for peer join/leave events for topic %s", topicName)
 		for {
 			ev, err := evHandler.NextPeerEvent(ctx)
 			if err != nil {
-				c.Log.Infof("Stopped listening for peer join/leave events for topic %s", c.TopicName)
+				c.Log.Infof("Stopped listening for peer join/leave events for topic %s", topicName)
 				return
 			}
 			switch ev.Type {
 			case pubsub.PeerJoin:
 				c.GossipState.AddNewPeer(ev.Peer, c.Store)
                 c.GossipState.AddConnectionEvent(ev.Peer, "Connection")
-                c.Log.WithFields(logrus.Fields{"peer_id": ev.Peer, "topic": c.TopicName}).Info("topic joined")
+				c.Log.WithFields(logrus.Fields{"peer_id": ev.Peer, "topic": topicName}).Info("topic joined")
 			case pubsub.PeerLeave:
                 c.GossipState.AddConnectionEvent(ev.Peer, "Disconnection")
-				c.Log.WithFields(logrus.Fields{"peer_id": ev.Peer, "topic": c.TopicName}).Info("topic left")
+				c.Log.WithFields(logrus.Fields{"peer_id": ev.Peer, "topic": topicName}).Info("topic left")
 			}
 		}
 	}()
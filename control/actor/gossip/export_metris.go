@@ -2,24 +2,28 @@ package gossip
 
 import (
 	"context"
-    "time"
+	"fmt"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/metrics"
-    "github.com/protolambda/rumor/p2p/track"
+	"github.com/protolambda/rumor/p2p/track"
+	"time"
 )
 
 type GossipExportMetricsCmd struct {
 	*base.Base
 	*metrics.GossipState
-    Store track.ExtendedPeerstore
-    ExportPeriod time.Duration `ask:"--export-period" help:"Requets the frecuency in witch the Metrics will be exported to the files"`
-	FilePath string `ask:"--file-path" help:"The path of the file where to export the metrics."`
-	PeerstorePath string `ask:"--peerstore-path" help:"The path of the file where to export the peerstore."`
-
+	Store         track.ExtendedPeerstore
+	ExportPeriod  time.Duration `ask:"--export-period" help:"Period to export the metrics with"`
+	Format        string        `ask:"--format" help:"Export format: json, csv or jsonl. json replaces the file with a full snapshot, csv and jsonl append the changes since the last export."`
+	FilePath      string        `ask:"--file-path" help:"The path of the file where to export the metrics. Empty to only upload."`
+	PeerstorePath string        `ask:"--peerstore-path" help:"The path of the file where to export the peerstore. Empty to not export the peerstore."`
+	UploadURL     string        `ask:"--upload-url" help:"Optional HTTP endpoint to POST the exported metrics to, as JSON"`
+	UploadAuth    string        `ask:"--upload-auth" help:"Optional Authorization header value for uploads"`
 }
 
-func (c *GossipExportMetricsCmd) Defaul() {
-    c.ExportPeriod = 30 * time.Second
+func (c *GossipExportMetricsCmd) Default() {
+	c.ExportPeriod = 30 * time.Second
+	c.Format = metrics.ExportJSON
 }
 
 func (c *GossipExportMetricsCmd) Help() string {
@@ -27,36 +31,60 @@ func (c *GossipExportMetricsCmd) Help() string {
 }
 
 func (c *GossipExportMetricsCmd) Run(ctx context.Context, args ...string) error {
-    if c.GossipState.GsNode == nil {
-        return NoGossipErr
-    }
-    stopping := false
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	if c.ExportPeriod <= 0 {
+		return fmt.Errorf("invalid export period: %s", c.ExportPeriod)
+	}
+	if c.FilePath == "" && c.UploadURL == "" {
+		return fmt.Errorf("nothing to export to, set --file-path or --upload-url")
+	}
+	exporter := &metrics.Exporter{
+		State:         c.GossipState,
+		Store:         c.Store,
+		Format:        c.Format,
+		FilePath:      c.FilePath,
+		PeerstorePath: c.PeerstorePath,
+		UploadURL:     c.UploadURL,
+		UploadAuth:    c.UploadAuth,
+	}
+	// check the format before starting
+	switch c.Format {
+	case metrics.ExportJSON, metrics.ExportCSV, metrics.ExportJSONL:
+	default:
+		return fmt.Errorf("unrecognized export format: %s", c.Format)
+	}
+	export := func() {
+		if err := exporter.Export(time.Now()); err != nil {
+			c.Log.WithError(err).Error("Problems exporting the metrics")
+		} else {
+			c.Log.Debug("Metrics exported")
+		}
+	}
+	exportCtx, cancelExport := context.WithCancel(c.ActorContext)
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.ExportPeriod)
+		defer ticker.Stop()
+		export()
 		for {
-            if stopping {
-                c.Log.Infof("Metrics Export Stopped")
-                return
-            }
-			start := time.Now()
-
-            c.Log.Infof("Exporting Metrics")
-	        err := c.GossipState.ExportMetrics(c.FilePath, c.PeerstorePath, c.Store)
-            if err != nil {
-                c.Log.Infof("Problems exporting the Metrics to the given file path")
-            } else {
-                c.Log.Infof("Metrics Exported")
-            }
-            exportStepDuration := time.Since(start)
-			if exportStepDuration < c.ExportPeriod{
-				time.Sleep(c.ExportPeriod - exportStepDuration)
+			select {
+			case <-ticker.C:
+				export()
+			case <-exportCtx.Done():
+				return
 			}
 		}
 	}()
 	c.Control.RegisterStop(func(ctx context.Context) error {
-		stopping = true
-		c.Log.Infof("Stoped Exporting")
+		cancelExport()
+		<-done
+		// export the last changes before stopping
+		export()
+		c.Log.Info("Stopped exporting metrics")
 		return nil
 	})
-
 	return nil
 }
@@ -2,16 +2,30 @@ package gossip
 
 import (
 	"errors"
+	"fmt"
 	"github.com/protolambda/ask"
+	"github.com/protolambda/rumor/chain"
 	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/control/actor/enr"
+	"github.com/protolambda/rumor/control/actor/peer/metadata"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/gossip"
     "github.com/protolambda/rumor/p2p/track"
-    "github.com/protolambda/rumor/metrics"
+	"strings"
 )
 
 type GossipCmd struct {
 	*base.Base
 	*metrics.GossipState
+	base.WithForkDigest
     Store track.ExtendedPeerstore
+	Chains     chain.Chains
+	ChainState *actorchain.ChainState
+	// For subnet management
+	SubnetsState      *SubnetsState
+	Lazy              *enr.LazyEnrState
+	PeerMetadataState *metadata.PeerMetadataState
 }
 
 func (c *GossipCmd) Cmd(route string) (cmd interface{}, err error) {
@@ -21,19 +35,36 @@ func (c *GossipCmd) Cmd(route string) (cmd interface{}, err error) {
 	case "list":
 		cmd = &GossipListCmd{Base: c.Base, GossipState: c.GossipState}
 	case "join":
-		cmd = &GossipJoinCmd{Base: c.Base, GossipState: c.GossipState}
+		cmd = &GossipJoinCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest}
 	case "events":
-		cmd = &GossipEventsCmd{Base: c.Base, GossipState: c.GossipState, Store: c.Store}
+		cmd = &GossipEventsCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest, Store: c.Store}
 	case "list-peers":
-		cmd = &GossipListPeersCmd{Base: c.Base, GossipState: c.GossipState}
+		cmd = &GossipListPeersCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest}
+	case "mesh":
+		cmd = &GossipMeshCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest, Store: c.Store}
 	case "blacklist":
 		cmd = &GossipBlacklistCmd{Base: c.Base, GossipState: c.GossipState}
 	case "leave":
-		cmd = &GossipLeaveCmd{Base: c.Base, GossipState: c.GossipState}
+		cmd = &GossipLeaveCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest}
 	case "log":
-		cmd = &GossipLogCmd{Base: c.Base, GossipState: c.GossipState}
+		cmd = &GossipLogCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest}
 	case "publish":
-		cmd = &GossipPublishCmd{Base: c.Base, GossipState: c.GossipState}
+		cmd = &GossipPublishCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest}
+	case "trace":
+		cmd = &GossipTraceCmd{Base: c.Base, GossipState: c.GossipState}
+	case "propagation-track":
+		cmd = &GossipPropagationTrackCmd{Base: c.Base, GossipState: c.GossipState, Chains: c.Chains, ChainState: c.ChainState}
+	case "propagation-report":
+		cmd = &GossipPropagationReportCmd{Base: c.Base, GossipState: c.GossipState, Store: c.Store}
+	case "subnets":
+		cmd = &GossipSubnetsCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest,
+			SubnetsState: c.SubnetsState, Lazy: c.Lazy, PeerMetadataState: c.PeerMetadataState}
+	case "record":
+		cmd = &GossipRecordCmd{Base: c.Base, GossipState: c.GossipState, WithForkDigest: c.WithForkDigest}
+	case "replay":
+		cmd = &GossipReplayCmd{Base: c.Base, GossipState: c.GossipState}
+	case "score":
+		cmd = &GossipScoreCmd{Base: c.Base, GossipState: c.GossipState, Store: c.Store}
     case "export-metrics":
 		cmd = &GossipExportMetricsCmd{Base: c.Base, GossipState: c.GossipState, Store: c.Store}
     default:
@@ -43,7 +74,7 @@ func (c *GossipCmd) Cmd(route string) (cmd interface{}, err error) {
 }
 
 func (c *GossipCmd) Routes() []string {
-	return []string{"start", "list", "join", "events", "list-peers", "blacklist", "leave", "log", "publish", "export_metrics"}
+	return []string{"start", "list", "join", "events", "list-peers", "mesh", "blacklist", "leave", "log", "publish", "trace", "propagation-track", "propagation-report", "subnets", "record", "replay", "score", "export-metrics"}
 }
 
 func (c *GossipCmd) Help() string {
@@ -51,3 +82,17 @@ func (c *GossipCmd) Help() string {
 }
 
 var NoGossipErr = errors.New("Must start gossip-sub first. Try 'gossip start'")
+
+// fullTopic expands a short eth2 topic name, e.g. "beacon_block", to a full topic name,
+// using the fork digest of the actor and the ssz_snappy encoding.
+// Topic names starting with a "/" are already complete, and returned as-is.
+func fullTopic(fd base.WithForkDigest, name string) (string, error) {
+	if strings.HasPrefix(name, "/") {
+		return name, nil
+	}
+	digest, ok := fd.CurrentForkDigest()
+	if !ok {
+		return "", fmt.Errorf("cannot expand topic %s without a fork digest, set an eth2 ENR entry or a local status, or use the full topic name", name)
+	}
+	return gossip.Eth2Topic(digest, name, gossip.SSZSnappyEncoding), nil
+}
@@ -3,33 +3,39 @@ package gossip
 import (
 	"context"
 	"fmt"
+	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/metrics"
-    "github.com/protolambda/rumor/control/actor/base"
 )
 
 type GossipJoinCmd struct {
 	*base.Base
 	*metrics.GossipState
+	base.WithForkDigest
 	TopicName string `ask:"<topic>" help:"The name of the topic to join"`
 }
 
 func (c *GossipJoinCmd) Help() string {
-	return "Join a gossip topic. This only sets up the topic, it does not actively find peers. See `gossip log start` and `gossip publish`."
+	return "Join a gossip topic. This only sets up the topic, it does not actively find peers. See `gossip log start` and `gossip publish`. " +
+		"Short eth2 topic names, e.g. 'beacon_block', are expanded with the fork digest of the actor."
 }
 
 func (c *GossipJoinCmd) Run(ctx context.Context, args ...string) error {
 	if c.GossipState.GsNode == nil {
 		return NoGossipErr
 	}
-	_, ok := c.GossipState.Topics.Load(c.TopicName)
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	_, ok := c.GossipState.Topics.Load(topicName)
 	if ok {
-		return fmt.Errorf("already on gossip topic %s", c.TopicName)
+		return fmt.Errorf("already on gossip topic %s", topicName)
 	}
-	top, err := c.GossipState.GsNode.Join(c.TopicName)
+	top, err := c.GossipState.GsNode.Join(topicName)
 	if err != nil {
 		return err
 	}
-	c.GossipState.Topics.Store(c.TopicName, top)
-	c.Log.Infof("joined topic %s", c.TopicName)
+	c.GossipState.Topics.Store(topicName, top)
+	c.Log.Infof("joined topic %s", topicName)
 	return nil
 }
@@ -11,6 +11,7 @@ import (
 type GossipLeaveCmd struct {
 	*base.Base
 	*metrics.GossipState
+	base.WithForkDigest
 	TopicName string `ask:"<topic>" help:"The name of the topic to leave"`
 }
 
@@ -22,14 +23,18 @@ func (c *GossipLeaveCmd) Run(ctx context.Context, args ...string) error {
 	if c.GossipState.GsNode == nil {
 		return NoGossipErr
 	}
-	if top, ok := c.GossipState.Topics.Load(c.TopicName); !ok {
-		return fmt.Errorf("not on gossip topic %s", c.TopicName)
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	if top, ok := c.GossipState.Topics.Load(topicName); !ok {
+		return fmt.Errorf("not on gossip topic %s", topicName)
 	} else {
 		err := top.(*pubsub.Topic).Close()
 		if err != nil {
 			return err
 		}
-		c.GossipState.Topics.Delete(c.TopicName)
+		c.GossipState.Topics.Delete(topicName)
 		return nil
 	}
 }
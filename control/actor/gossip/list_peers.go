@@ -11,6 +11,7 @@ import (
 type GossipListPeersCmd struct {
 	*base.Base
 	*metrics.GossipState
+	base.WithForkDigest
 	TopicName string `ask:"<topic>" help:"The name of the topic to list peers of"`
 }
 
@@ -22,11 +23,15 @@ func (c *GossipListPeersCmd) Run(ctx context.Context, args ...string) error {
 	if c.GossipState.GsNode == nil {
 		return NoGossipErr
 	}
-	if top, ok := c.GossipState.Topics.Load(c.TopicName); !ok {
-		return fmt.Errorf("not on gossip topic %s", c.TopicName)
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	if top, ok := c.GossipState.Topics.Load(topicName); !ok {
+		return fmt.Errorf("not on gossip topic %s", topicName)
 	} else {
 		peers := top.(*pubsub.Topic).ListPeers()
-		c.Log.WithField("peers", peers).Infof("%d peers on topic %s", len(peers), c.TopicName)
+		c.Log.WithField("peers", peers).Infof("%d peers on topic %s", len(peers), topicName)
 		return nil
 	}
 }
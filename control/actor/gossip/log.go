@@ -7,14 +7,15 @@ import (
 	"github.com/golang/snappy"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
     "github.com/sirupsen/logrus"
-    "github.com/protolambda/rumor/metrics"
 	"strings"
 )
 
 type GossipLogCmd struct {
 	*base.Base
 	*metrics.GossipState
+	base.WithForkDigest
 	TopicName string `ask:"<topic>" help:"The name of the topic to log messages of"`
 }
 
@@ -26,12 +27,16 @@ func (c *GossipLogCmd) Run(ctx context.Context, args ...string) error {
 	if c.GossipState.GsNode == nil {
 		return NoGossipErr
 	}
-	if top, ok := c.GossipState.Topics.Load(c.TopicName); !ok {
-		return fmt.Errorf("not on gossip topic %s", c.TopicName)
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	if top, ok := c.GossipState.Topics.Load(topicName); !ok {
+		return fmt.Errorf("not on gossip topic %s", topicName)
 	} else {
 		sub, err := top.(*pubsub.Topic).Subscribe()
 		if err != nil {
-			return fmt.Errorf("cannot open subscription on topic %s: %v", c.TopicName, err)
+			return fmt.Errorf("cannot open subscription on topic %s: %v", topicName, err)
 		}
 		ctx, cancelLog := context.WithCancel(ctx)
 		go func() {
@@ -42,26 +47,26 @@ func (c *GossipLogCmd) Run(ctx context.Context, args ...string) error {
 					if err == ctx.Err() { // expected quit, context stopped.
 						break
 					}
-					c.Log.WithError(err).WithField("topic", c.TopicName).Error("Gossip logging encountered error")
+					c.Log.WithError(err).WithField("topic", topicName).Error("Gossip logging encountered error")
 					return
 				} else {
 					var msgData []byte
-					if strings.HasSuffix(c.TopicName, "_snappy") {
+					if strings.HasSuffix(topicName, "_snappy") {
 						msgData, err = snappy.Decode(nil, msg.Data)
 						if err != nil {
-							c.Log.WithError(err).WithField("topic", c.TopicName).Error("Cannot decompress snappy message")
+							c.Log.WithError(err).WithField("topic", topicName).Error("Cannot decompress snappy message")
 							continue
 						}
 					} else {
 						msgData = msg.Data
 					}
-                    c.GossipState.IncomingMessageManager(msg.ReceivedFrom, c.TopicName)
+					c.GossipState.IncomingMessageManager(msg.ReceivedFrom, topicName)
                     c.Log.WithFields(logrus.Fields{
 						"from":      msg.ReceivedFrom.String(),
 						"data":      hex.EncodeToString(msgData),
 						"signature": hex.EncodeToString(msg.Signature),
 						"seq_no":    hex.EncodeToString(msg.Seqno),
-					}).Infof("new message on %s", c.TopicName)
+					}).Infof("new message on %s", topicName)
 				}
 			}
 		}()
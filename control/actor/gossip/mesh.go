@@ -0,0 +1,109 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/sirupsen/logrus"
+	"sort"
+)
+
+type GossipMeshCmd struct {
+	*base.Base
+	*metrics.GossipState
+	base.WithForkDigest
+	Store     track.ExtendedPeerstore
+	TopicName string `ask:"<topic>" help:"The name of the topic to inspect the mesh of"`
+	History   bool   `ask:"--history" help:"Also log the graft/prune history of the topic"`
+}
+
+func (c *GossipMeshCmd) Help() string {
+	return "Show the peers of a topic: in our mesh, in our fanout, or just subscribed. With message counts and client types."
+}
+
+func (c *GossipMeshCmd) Default() {
+	c.History = true
+}
+
+func (c *GossipMeshCmd) Run(ctx context.Context, args ...string) error {
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	top, ok := c.GossipState.Topics.Load(topicName)
+	if !ok {
+		return fmt.Errorf("not on gossip topic %s", topicName)
+	}
+	info := c.GossipState.GsNode.Mesh(topicName)
+
+	peers := make(map[peer.ID]string)
+	for _, p := range top.(*pubsub.Topic).ListPeers() {
+		peers[p] = "subscribed"
+	}
+	for p := range info.Fanout {
+		peers[p] = "fanout"
+	}
+	for p := range info.Mesh {
+		peers[p] = "mesh"
+	}
+	ids := make([]peer.ID, 0, len(peers))
+	for p := range peers {
+		ids = append(ids, p)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if peers[ids[i]] != peers[ids[j]] {
+			return peers[ids[i]] < peers[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	counts := make(map[string]int)
+	for _, p := range ids {
+		counts[peers[p]] += 1
+		f := logrus.Fields{
+			"peer":   p.String(),
+			"status": peers[p],
+			"client": "unknown",
+		}
+		if at, ok := info.Mesh[p]; ok {
+			f["grafted"] = at.UnixNano()
+		}
+		if at, ok := info.Fanout[p]; ok {
+			f["last_published"] = at.UnixNano()
+		}
+		if c.Store != nil {
+			if ua, err := c.Store.UserAgent(p); err == nil {
+				f["client"] = metrics.ClientName(ua)
+			}
+		}
+		if v, ok := c.GossipState.GossipMetrics.Load(p.String()); ok {
+			if m, ok := v.(*metrics.PeerMetrics).Copy().Topics[topicName]; ok {
+				f["messages"] = m.Cnt
+				f["last_message"] = m.LastMessageTime
+			}
+		}
+		c.Log.WithFields(f).Info("peer")
+	}
+	if c.History {
+		for _, ev := range info.History {
+			c.Log.WithFields(logrus.Fields{
+				"type": ev.Type,
+				"peer": ev.Peer.String(),
+				"time": ev.Time.UnixNano(),
+			}).Info("mesh event")
+		}
+	}
+	c.Log.WithFields(logrus.Fields{
+		"joined":     info.Joined,
+		"mesh":       counts["mesh"],
+		"fanout":     counts["fanout"],
+		"subscribed": counts["subscribed"],
+	}).Infof("mesh of %s", topicName)
+	return nil
+}
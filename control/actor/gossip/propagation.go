@@ -0,0 +1,143 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/chain"
+	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"time"
+)
+
+type GossipPropagationTrackCmd struct {
+	*base.Base
+	*metrics.GossipState
+	Chains     chain.Chains
+	ChainState *actorchain.ChainState
+
+	GenesisTime    uint64 `ask:"--genesis-time" help:"Genesis time (unix seconds) to compute slots with. If 0, the genesis time of the current chain is used."`
+	SecondsPerSlot uint64 `ask:"--seconds-per-slot" help:"Seconds per slot"`
+	MaxMessages    int    `ask:"--max-messages" help:"Maximum number of messages to track, the oldest are forgotten first"`
+}
+
+func (c *GossipPropagationTrackCmd) Default() {
+	c.SecondsPerSlot = 12
+	c.MaxMessages = 100000
+}
+
+func (c *GossipPropagationTrackCmd) Help() string {
+	return "Track how received gossip messages propagate: arrival time since slot start, duplicates, and which peers deliver first"
+}
+
+func (c *GossipPropagationTrackCmd) genesisTime(ctx context.Context) (time.Time, error) {
+	if c.GenesisTime != 0 {
+		return time.Unix(int64(c.GenesisTime), 0), nil
+	}
+	ch, ok := c.Chains.Find(c.ChainState.CurrentChain)
+	if !ok {
+		return time.Time{}, errors.New("no current chain to get the genesis time from, use --genesis-time or 'chain create'")
+	}
+	head, err := ch.Head()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get chain head: %v", err)
+	}
+	state, err := head.State(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get head state: %v", err)
+	}
+	genesis, err := state.GenesisTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(genesis), 0), nil
+}
+
+func (c *GossipPropagationTrackCmd) Run(ctx context.Context, args ...string) error {
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	genesis, err := c.genesisTime(ctx)
+	if err != nil {
+		return err
+	}
+	tracker := metrics.NewPropagationTracker(genesis, c.SecondsPerSlot, c.MaxMessages)
+	gs := c.GossipState.GsNode
+	if prev := c.GossipState.SetPropagationTracker(tracker); prev != nil {
+		gs.RmTracer(prev)
+	}
+	_ = gs.AddTracer(tracker)
+	c.Log.WithField("genesis_time", genesis.Unix()).Info("Started tracking gossip propagation")
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		// keep the tracker in the state, the report is still available after stopping.
+		gs.RmTracer(tracker)
+		c.Log.Info("Stopped tracking gossip propagation")
+		return nil
+	})
+	return nil
+}
+
+type GossipPropagationReportCmd struct {
+	*base.Base
+	*metrics.GossipState
+	Store track.ExtendedPeerstore
+
+	Out string `ask:"--out" help:"Optional path to write the full report to, as JSON"`
+}
+
+func (c *GossipPropagationReportCmd) Help() string {
+	return "Report message propagation per topic and per client type, tracked with 'gossip propagation-track'"
+}
+
+func (c *GossipPropagationReportCmd) Run(ctx context.Context, args ...string) error {
+	tracker := c.GossipState.PropagationTracker()
+	if tracker == nil {
+		return errors.New("propagation is not tracked. Try 'gossip propagation-track'")
+	}
+	report := tracker.Report(func(p peer.ID) string {
+		if c.Store == nil {
+			return "unknown"
+		}
+		ua, err := c.Store.UserAgent(p)
+		if err != nil {
+			return "unknown"
+		}
+		return metrics.ClientName(ua)
+	})
+	for topic, tp := range report.Topics {
+		c.Log.WithFields(logrus.Fields{
+			"topic":             topic,
+			"messages":          tp.Messages,
+			"slot_delay_ms":     tp.SlotDelayMs,
+			"delivery_delay_ms": tp.DeliveryDelayMs,
+			"duplicates":        tp.Duplicates,
+		}).Info("topic propagation")
+		for client, cp := range report.Clients[topic] {
+			c.Log.WithFields(logrus.Fields{
+				"topic":             topic,
+				"client":            client,
+				"deliveries":        cp.Deliveries,
+				"first_deliveries":  cp.FirstDeliveries,
+				"slot_delay_ms":     cp.SlotDelayMs,
+				"delivery_delay_ms": cp.DeliveryDelayMs,
+			}).Info("client propagation")
+		}
+	}
+	if c.Out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(c.Out, data, 0644); err != nil {
+			return err
+		}
+		c.Log.WithField("out", c.Out).Info("Wrote propagation report")
+	}
+	return nil
+}
@@ -5,35 +5,131 @@ import (
 	"fmt"
 	"github.com/golang/snappy"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/gossip"
+	"github.com/sirupsen/logrus"
 	"strings"
-    "github.com/protolambda/rumor/metrics"
+	"time"
 )
 
 type GossipPublishCmd struct {
 	*base.Base
 	*metrics.GossipState
+	base.WithForkDigest
 	TopicName string `ask:"<topic>" help:"The name of the topic to publish to"`
 	Message   []byte `ask:"<message>" help:"The uncompressed message bytes, hex-encoded"`
+
+	Mode      string  `ask:"--mode" help:"How to publish the message: normal, malformed-ssz, oversized, corrupt-snappy, duplicate-ids, slot-shift"`
+	Count     uint64  `ask:"--count" help:"Number of messages to publish"`
+	Rate      float64 `ask:"--rate" help:"Maximum number of messages to publish per second. 0 to publish all messages in a burst."`
+	Size      uint64  `ask:"--size" help:"Size to pad the uncompressed message to, for the oversized mode"`
+	SlotShift int64   `ask:"--slot-shift" help:"Number of slots to shift the message slot by, for the slot-shift mode. Negative for past slots."`
+}
+
+func (c *GossipPublishCmd) Default() {
+	c.Mode = "normal"
+	c.Count = 1
+	c.Size = gossip.GossipMaxSize + 1
 }
 
 func (c *GossipPublishCmd) Help() string {
 	return "Publish a message to the topic. The message should be hex-encoded."
 }
 
+func (c *GossipPublishCmd) HelpLong() string {
+	return `
+Modes, to test the penalties and scoring of other clients:
+- normal: publish the message as-is
+- malformed-ssz: corrupt the first offset of the SSZ message, and drop its last byte
+- oversized: pad the message with zeroes to --size bytes, by default just over the max gossip size
+- corrupt-snappy: corrupt the snappy length header, so the message does not decompress
+- duplicate-ids: publish the same message, each time with a different snappy encoding, and thus a different message ID
+- slot-shift: shift the slot of the message by --slot-shift, e.g. to a far future or past slot.
+  Supported on beacon_block, beacon_aggregate_and_proof and beacon_attestation_{n} topics.
+  The shift is multiplied by the message index plus one when publishing more than one message.
+
+Every published message is logged with its message ID, to correlate with scores and disconnects, see 'host notify'.
+`
+}
+
+// message builds the i-th message to publish, in its final (compressed if necessary) form.
+func (c *GossipPublishCmd) message(topicName string, i uint64) ([]byte, error) {
+	snappyEnc := strings.HasSuffix(topicName, "_snappy")
+	data := c.Message
+	switch c.Mode {
+	case "normal":
+	case "malformed-ssz":
+		data = gossip.MalformSSZ(data)
+	case "oversized":
+		data = gossip.PadSSZ(data, int(c.Size))
+	case "corrupt-snappy":
+		if !snappyEnc {
+			return nil, fmt.Errorf("topic %s is not snappy encoded", topicName)
+		}
+		return gossip.CorruptSnappy(data), nil
+	case "duplicate-ids":
+		if !snappyEnc {
+			return nil, fmt.Errorf("topic %s is not snappy encoded", topicName)
+		}
+		if i >= uint64(len(data)) {
+			return nil, fmt.Errorf("message of %d bytes has only %d unique encodings", len(data), len(data))
+		}
+		return gossip.SnappyLiteralEncode(data, int(i)), nil
+	case "slot-shift":
+		shifted, err := gossip.ShiftSlot(topicName, data, c.SlotShift*int64(i+1))
+		if err != nil {
+			return nil, err
+		}
+		data = shifted
+	default:
+		return nil, fmt.Errorf("unrecognized publish mode: %s", c.Mode)
+	}
+	if snappyEnc {
+		data = snappy.Encode(nil, data)
+	}
+	return data, nil
+}
+
 func (c *GossipPublishCmd) Run(ctx context.Context, args ...string) error {
 	if c.GossipState.GsNode == nil {
 		return NoGossipErr
 	}
-	if top, ok := c.GossipState.Topics.Load(c.TopicName); !ok {
-		return fmt.Errorf("not on gossip topic %s", c.TopicName)
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	if top, ok := c.GossipState.Topics.Load(topicName); !ok {
+		return fmt.Errorf("not on gossip topic %s", topicName)
 	} else {
-		data := c.Message
-		if strings.HasSuffix(c.TopicName, "_snappy") {
-			data = snappy.Encode(nil, data)
+		var interval time.Duration
+		if c.Rate > 0 {
+			interval = time.Duration(float64(time.Second) / c.Rate)
 		}
-		if err := top.(*pubsub.Topic).Publish(ctx, data); err != nil {
-			return fmt.Errorf("failed to publish message, err: %v", err)
+		for i := uint64(0); i < c.Count; i++ {
+			if i > 0 && interval > 0 {
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			data, err := c.message(topicName, i)
+			if err != nil {
+				return err
+			}
+			if err := top.(*pubsub.Topic).Publish(ctx, data); err != nil {
+				return fmt.Errorf("failed to publish message, err: %v", err)
+			}
+			c.Log.WithFields(logrus.Fields{
+				"topic":  topicName,
+				"mode":   c.Mode,
+				"index":  i,
+				"msg_id": gossip.MsgIDFunction(&pubsub_pb.Message{Data: data}),
+				"size":   len(data),
+				"time":   time.Now().UnixNano(),
+			}).Info("published message")
 		}
 		return nil
 	}
@@ -0,0 +1,87 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/gossip"
+	"os"
+	"time"
+)
+
+type GossipRecordCmd struct {
+	*base.Base
+	*metrics.GossipState
+	base.WithForkDigest
+	TopicName string `ask:"<topic>" help:"The name of the topic to record messages of"`
+	Out       string `ask:"--out" help:"Path of the file to append the recorded messages to, as JSON lines"`
+}
+
+func (c *GossipRecordCmd) Help() string {
+	return "Record the raw messages of a gossip topic to a file, to replay them later with 'gossip replay'. Join a topic first."
+}
+
+func (c *GossipRecordCmd) Run(ctx context.Context, args ...string) error {
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	if c.Out == "" {
+		return fmt.Errorf("no output file, set --out")
+	}
+	topicName, err := fullTopic(c.WithForkDigest, c.TopicName)
+	if err != nil {
+		return err
+	}
+	top, ok := c.GossipState.Topics.Load(topicName)
+	if !ok {
+		return fmt.Errorf("not on gossip topic %s", topicName)
+	}
+	f, err := os.OpenFile(c.Out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	sub, err := top.(*pubsub.Topic).Subscribe()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot open subscription on topic %s: %v", topicName, err)
+	}
+	w := gossip.NewRecordWriter(f)
+	recordCtx, cancelRecord := context.WithCancel(c.ActorContext)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer sub.Cancel()
+		count := 0
+		for {
+			msg, err := sub.Next(recordCtx)
+			if err != nil {
+				if err != recordCtx.Err() {
+					c.Log.WithError(err).WithField("topic", topicName).Error("Gossip recording encountered error")
+				}
+				c.Log.WithField("count", count).Infof("Recorded messages of %s", topicName)
+				return
+			}
+			c.GossipState.IncomingMessageManager(msg.ReceivedFrom, topicName)
+			if err := w.Write(&gossip.RecordedMessage{
+				Topic: topicName,
+				From:  msg.ReceivedFrom,
+				Time:  time.Now(),
+				Data:  msg.Data,
+				SeqNo: msg.Seqno,
+			}); err != nil {
+				c.Log.WithError(err).Error("Failed to write recorded message")
+				return
+			}
+			count += 1
+		}
+	}()
+	c.Log.WithField("out", c.Out).Infof("Recording messages of %s", topicName)
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		cancelRecord()
+		<-done
+		return f.Close()
+	})
+	return nil
+}
@@ -0,0 +1,138 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/gossip"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"time"
+)
+
+type GossipReplayCmd struct {
+	*base.Base
+	*metrics.GossipState
+	In    string  `ask:"--in" help:"Path of the file with recorded messages, see 'gossip record'"`
+	Speed float64 `ask:"--speed" help:"Timing of the replay, relative to the recorded timing: 1 is the original timing, 2 is twice as fast. 0 to publish without delays."`
+	Rate  float64 `ask:"--rate" help:"Maximum number of messages to publish per second. 0 for no limit."`
+
+	RewriteDigest        beacon.ForkDigest `ask:"--rewrite-digest" help:"Fork digest to rewrite eth2 topics to, e.g. to replay messages on a different fork"`
+	RewriteDigestChanged bool              `changed:"rewrite-digest"`
+
+	Join bool `ask:"--join" help:"Join topics that are not joined yet"`
+}
+
+func (c *GossipReplayCmd) Default() {
+	c.Speed = 1
+	c.Join = true
+}
+
+func (c *GossipReplayCmd) Help() string {
+	return "Publish recorded gossip messages back onto their topics. The raw message data is published as-is."
+}
+
+func (c *GossipReplayCmd) topic(name string) (*pubsub.Topic, error) {
+	if top, ok := c.GossipState.Topics.Load(name); ok {
+		return top.(*pubsub.Topic), nil
+	}
+	if !c.Join {
+		return nil, fmt.Errorf("not on gossip topic %s", name)
+	}
+	top, err := c.GossipState.GsNode.Join(name)
+	if err != nil {
+		return nil, err
+	}
+	c.GossipState.Topics.Store(name, top)
+	c.Log.Infof("joined topic %s", name)
+	return top, nil
+}
+
+func (c *GossipReplayCmd) Run(ctx context.Context, args ...string) error {
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	if c.Speed < 0 {
+		return fmt.Errorf("invalid speed: %f", c.Speed)
+	}
+	f, err := os.Open(c.In)
+	if err != nil {
+		return err
+	}
+	r := gossip.NewRecordReader(f)
+	replayCtx, cancelReplay := context.WithCancel(c.ActorContext)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer f.Close()
+		var minInterval time.Duration
+		if c.Rate > 0 {
+			minInterval = time.Duration(float64(time.Second) / c.Rate)
+		}
+		var firstRecorded time.Time
+		var start, lastPublish time.Time
+		count := 0
+		for {
+			msg, err := r.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				c.Log.WithError(err).Error("Failed to read recorded message")
+				break
+			}
+			if count == 0 {
+				firstRecorded = msg.Time
+				start = time.Now()
+			}
+			wait := time.Duration(0)
+			if c.Speed > 0 {
+				offset := time.Duration(float64(msg.Time.Sub(firstRecorded)) / c.Speed)
+				wait = time.Until(start.Add(offset))
+			}
+			if count > 0 {
+				if d := time.Until(lastPublish.Add(minInterval)); d > wait {
+					wait = d
+				}
+			}
+			if wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-replayCtx.Done():
+					c.Log.WithField("count", count).Info("Stopped replay")
+					return
+				}
+			}
+			topicName := msg.Topic
+			if c.RewriteDigestChanged {
+				topicName = gossip.RewriteTopicDigest(topicName, c.RewriteDigest)
+			}
+			top, err := c.topic(topicName)
+			if err != nil {
+				c.Log.WithError(err).Error("Failed to replay message")
+				return
+			}
+			if err := top.Publish(replayCtx, msg.Data); err != nil {
+				c.Log.WithError(err).WithField("topic", topicName).Error("Failed to publish message")
+				return
+			}
+			lastPublish = time.Now()
+			count += 1
+			c.Log.WithFields(logrus.Fields{
+				"topic":         topicName,
+				"recorded_from": msg.From.String(),
+				"size":          len(msg.Data),
+			}).Debug("replayed message")
+		}
+		c.Log.WithField("count", count).Info("Finished replay")
+	}()
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		cancelReplay()
+		<-done
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,45 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/gossip"
+	"github.com/protolambda/rumor/p2p/track"
+)
+
+type GossipScoreCmd struct {
+	*base.Base
+	*metrics.GossipState
+	Store track.ExtendedPeerstore
+
+	MaxMessages int `ask:"--max-messages" help:"Maximum number of message senders to remember, to score deliveries with"`
+}
+
+func (c *GossipScoreCmd) Default() {
+	c.MaxMessages = 100000
+}
+
+func (c *GossipScoreCmd) Help() string {
+	return "Score peers by the validation results of the gossip messages they send. See 'peer score'."
+}
+
+func (c *GossipScoreCmd) Run(ctx context.Context, args ...string) error {
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	if c.Store == nil {
+		return errors.New("Not available. Create a peerstore first.")
+	}
+	tracer := gossip.NewScoreTracer(c.Store, c.MaxMessages)
+	gs := c.GossipState.GsNode
+	_ = gs.AddTracer(tracer)
+	c.Log.Info("Started scoring gossip")
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		gs.RmTracer(tracer)
+		c.Log.Info("Stopped scoring gossip")
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,266 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/enr"
+	"github.com/protolambda/rumor/control/actor/peer/metadata"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/altair"
+	"github.com/protolambda/rumor/p2p/gossip"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type subnetSub struct {
+	topicName string
+	topic     *pubsub.Topic
+	sub       *pubsub.Subscription
+	cancel    context.CancelFunc
+	// if the topic was joined for the subnet, and not by the user before, it is closed when leaving the subnet.
+	joined bool
+}
+
+// SubnetsState tracks the attestation subnets that are joined with 'gossip subnets'
+type SubnetsState struct {
+	lock    sync.Mutex
+	Current beacon.AttnetBits
+	subs    map[uint64]*subnetSub
+	// identifies the call that currently manages the subnets
+	owner uint64
+}
+
+type GossipSubnetsCmd struct {
+	*base.Base
+	*metrics.GossipState
+	base.WithForkDigest
+	SubnetsState      *SubnetsState
+	Lazy              *enr.LazyEnrState
+	PeerMetadataState *metadata.PeerMetadataState
+
+	Attnets beacon.AttnetBits `ask:"--attnets" help:"Attestation subnets to join, as bitfield bytes. Ignored when rotating."`
+	Rotate  uint64            `ask:"--rotate" help:"If non-zero, join this number of random subnets, and pick new random subnets every period"`
+	Period  time.Duration     `ask:"--period" help:"Period to rotate the random subnets with"`
+
+	UpdateENR      bool `ask:"--update-enr" help:"Update the attnets entry of the local ENR"`
+	UpdateMetadata bool `ask:"--update-metadata" help:"Update the attnets of the local metadata, and bump the seq number"`
+}
+
+func (c *GossipSubnetsCmd) Default() {
+	// EPOCHS_PER_RANDOM_SUBNET_SUBSCRIPTION (256) epochs of 32 slots of 12 seconds
+	c.Period = 256 * 32 * 12 * time.Second
+	c.UpdateENR = true
+	c.UpdateMetadata = true
+}
+
+func (c *GossipSubnetsCmd) Help() string {
+	return "Join and leave beacon_attestation_{n} subnet topics, and advertise them in the ENR and metadata."
+}
+
+func (c *GossipSubnetsCmd) HelpLong() string {
+	return `
+Either join a fixed set of subnets with --attnets, or join --rotate random subnets,
+and pick new ones every --period, like a validator node with random subnet subscriptions.
+
+Messages on the subnet topics are counted in the gossip metrics.
+Subnet topics that were already joined with 'gossip join' are kept when leaving the subnet.
+
+When the command is stopped, all subnets are left, and the advertised attnets are cleared.
+`
+}
+
+func (c *GossipSubnetsCmd) Run(ctx context.Context, args ...string) error {
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	if c.Rotate > beacon.ATTESTATION_SUBNET_COUNT {
+		return fmt.Errorf("cannot rotate %d subnets, there are only %d", c.Rotate, beacon.ATTESTATION_SUBNET_COUNT)
+	}
+	if c.Rotate > 0 && c.Period <= 0 {
+		return fmt.Errorf("invalid rotation period: %s", c.Period)
+	}
+	st := c.SubnetsState
+	st.lock.Lock()
+	st.owner += 1
+	owner := st.owner
+	st.lock.Unlock()
+
+	if c.Rotate == 0 {
+		if err := c.apply(owner, c.Attnets); err != nil {
+			return err
+		}
+	} else {
+		if err := c.apply(owner, randomAttnets(c.Rotate)); err != nil {
+			return err
+		}
+		rotateCtx, cancelRotate := context.WithCancel(c.ActorContext)
+		go func() {
+			ticker := time.NewTicker(c.Period)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := c.apply(owner, randomAttnets(c.Rotate)); err != nil {
+						c.Log.WithError(err).Error("failed to rotate subnets")
+					}
+				case <-rotateCtx.Done():
+					return
+				}
+			}
+		}()
+		c.Control.RegisterStop(func(ctx context.Context) error {
+			cancelRotate()
+			return nil
+		})
+	}
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		if err := c.apply(owner, beacon.AttnetBits{}); err != nil {
+			return err
+		}
+		c.Log.Info("Stopped subnets")
+		return nil
+	})
+	return nil
+}
+
+func randomAttnets(count uint64) (out beacon.AttnetBits) {
+	for _, i := range rand.Perm(beacon.ATTESTATION_SUBNET_COUNT)[:count] {
+		out[i/8] |= 1 << (uint(i) % 8)
+	}
+	return out
+}
+
+func hasSubnet(bits *beacon.AttnetBits, i uint64) bool {
+	return bits[i/8]&(1<<(i%8)) != 0
+}
+
+// apply joins and leaves subnets to match the given attnets, if the subnets are still managed by the given owner.
+func (c *GossipSubnetsCmd) apply(owner uint64, attnets beacon.AttnetBits) error {
+	st := c.SubnetsState
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if st.owner != owner {
+		// another call manages the subnets now
+		return nil
+	}
+	if st.subs == nil {
+		st.subs = make(map[uint64]*subnetSub)
+	}
+	// join the new subnets first, and undo the joins if any fails, to keep the previous subnets intact.
+	var joined []uint64
+	for i := uint64(0); i < beacon.ATTESTATION_SUBNET_COUNT; i++ {
+		if _, ok := st.subs[i]; ok || !hasSubnet(&attnets, i) {
+			continue
+		}
+		s, err := c.joinSubnet(i)
+		if err != nil {
+			for _, j := range joined {
+				if err := c.leaveSubnet(st.subs[j]); err != nil {
+					c.Log.WithError(err).WithField("subnet", j).Warn("failed to undo subnet join")
+				}
+				delete(st.subs, j)
+			}
+			return err
+		}
+		st.subs[i] = s
+		joined = append(joined, i)
+	}
+	var leaveErr error
+	for i, s := range st.subs {
+		if hasSubnet(&attnets, i) {
+			continue
+		}
+		if err := c.leaveSubnet(s); err != nil {
+			if leaveErr == nil {
+				leaveErr = err
+			}
+			continue
+		}
+		delete(st.subs, i)
+	}
+	// only advertise the subnets once all joins and leaves succeeded
+	if leaveErr != nil {
+		return leaveErr
+	}
+	if st.Current == attnets {
+		return nil
+	}
+	st.Current = attnets
+	if c.UpdateENR {
+		// the local node synchronizes its own record updates
+		if current := c.Lazy.Current; current != nil {
+			current.SetAttnets(&attnets)
+		} else {
+			c.Log.Warn("no local ENR to update the attnets of, try 'enr make'")
+		}
+	}
+	var md beacon.MetaData
+	if c.UpdateMetadata {
+		md = c.PeerMetadataState.UpdateLocal(func(md *beacon.MetaData, _ *altair.SyncnetBits) {
+			md.Attnets = attnets
+			md.SeqNumber += 1
+		})
+	} else {
+		md = c.PeerMetadataState.LocalV1()
+	}
+	c.Log.WithFields(logrus.Fields{
+		"attnets":      attnets.String(),
+		"metadata_seq": md.SeqNumber,
+	}).Info("Updated subnets")
+	return nil
+}
+
+func (c *GossipSubnetsCmd) joinSubnet(i uint64) (*subnetSub, error) {
+	topicName, err := fullTopic(c.WithForkDigest, gossip.AttestationSubnet(i))
+	if err != nil {
+		return nil, err
+	}
+	s := &subnetSub{topicName: topicName}
+	if top, ok := c.GossipState.Topics.Load(topicName); ok {
+		s.topic = top.(*pubsub.Topic)
+	} else {
+		top, err := c.GossipState.GsNode.Join(topicName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join subnet %d: %v", i, err)
+		}
+		c.GossipState.Topics.Store(topicName, top)
+		s.topic = top
+		s.joined = true
+	}
+	sub, err := s.topic.Subscribe()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open subscription on topic %s: %v", topicName, err)
+	}
+	s.sub = sub
+	ctx, cancel := context.WithCancel(c.ActorContext)
+	s.cancel = cancel
+	go func() {
+		for {
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				return
+			}
+			c.GossipState.IncomingMessageManager(msg.ReceivedFrom, topicName)
+		}
+	}()
+	c.Log.WithField("subnet", i).Debugf("joined subnet topic %s", topicName)
+	return s, nil
+}
+
+func (c *GossipSubnetsCmd) leaveSubnet(s *subnetSub) error {
+	s.cancel()
+	s.sub.Cancel()
+	if s.joined {
+		if err := s.topic.Close(); err != nil {
+			return fmt.Errorf("failed to leave topic %s: %v", s.topicName, err)
+		}
+		c.GossipState.Topics.Delete(s.topicName)
+	}
+	c.Log.Debugf("left subnet topic %s", s.topicName)
+	return nil
+}
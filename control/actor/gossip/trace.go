@@ -0,0 +1,264 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Time allowed to read the next pong message from the server.
+	pongWait = 60 * time.Second
+)
+
+type GossipTraceCmd struct {
+	*base.Base
+	*metrics.GossipState
+	Dest   string   `ask:"--dest" help:"Destination to direct trace events to. Could be: log,json,pb,wsjson"`
+	Path   string   `ask:"--path" help:"Path, address or other destination uri"`
+	Key    string   `ask:"--key" help:"Optional API key or authentication for the chosen destination type"`
+	Events []string `ask:"--events" help:"Event types to trace, e.g. recv_rpc,send_rpc,graft,prune,duplicate_message,deliver_message,reject_message. Empty to trace everything."`
+}
+
+func (c *GossipTraceCmd) Help() string {
+	return "Trace gossipsub events (RPCs, graft/prune, duplicates, deliveries, rejections) and tee them elsewhere"
+}
+
+func (c *GossipTraceCmd) HelpLong() string {
+	return `
+Destinations:
+- log: log every trace event
+- json: append trace events as JSON lines to the file at --path
+- pb: append trace events as delimited protobufs to the file at --path, compatible with tracestat
+- wsjson: send trace events as JSON to the websocket at --path, optionally authenticated with --key
+`
+}
+
+func (c *GossipTraceCmd) Default() {
+	c.Dest = "log"
+}
+
+type traceFilter struct {
+	inner pubsub.EventTracer
+	types map[pubsub_pb.TraceEvent_Type]struct{}
+}
+
+func (f *traceFilter) Trace(evt *pubsub_pb.TraceEvent) {
+	if evt.Type == nil {
+		return
+	}
+	if _, ok := f.types[*evt.Type]; ok {
+		f.inner.Trace(evt)
+	}
+}
+
+type traceLogger struct {
+	log logrus.FieldLogger
+}
+
+func (t *traceLogger) Trace(evt *pubsub_pb.TraceEvent) {
+	f := logrus.Fields{
+		"type":    evt.GetType().String(),
+		"time_ns": evt.GetTimestamp(),
+	}
+	switch evt.GetType() {
+	case pubsub_pb.TraceEvent_PUBLISH_MESSAGE:
+		f["msg_id"] = string(evt.PublishMessage.GetMessageID())
+		f["topics"] = evt.PublishMessage.GetTopics()
+	case pubsub_pb.TraceEvent_REJECT_MESSAGE:
+		f["msg_id"] = string(evt.RejectMessage.GetMessageID())
+		f["from"] = peer.ID(evt.RejectMessage.GetReceivedFrom()).String()
+		f["reason"] = evt.RejectMessage.GetReason()
+	case pubsub_pb.TraceEvent_DUPLICATE_MESSAGE:
+		f["msg_id"] = string(evt.DuplicateMessage.GetMessageID())
+		f["from"] = peer.ID(evt.DuplicateMessage.GetReceivedFrom()).String()
+	case pubsub_pb.TraceEvent_DELIVER_MESSAGE:
+		f["msg_id"] = string(evt.DeliverMessage.GetMessageID())
+	case pubsub_pb.TraceEvent_ADD_PEER:
+		f["peer_id"] = peer.ID(evt.AddPeer.GetPeerID()).String()
+		f["proto"] = evt.AddPeer.GetProto()
+	case pubsub_pb.TraceEvent_REMOVE_PEER:
+		f["peer_id"] = peer.ID(evt.RemovePeer.GetPeerID()).String()
+	case pubsub_pb.TraceEvent_RECV_RPC:
+		f["from"] = peer.ID(evt.RecvRPC.GetReceivedFrom()).String()
+		f["meta"] = evt.RecvRPC.GetMeta()
+	case pubsub_pb.TraceEvent_SEND_RPC:
+		f["to"] = peer.ID(evt.SendRPC.GetSendTo()).String()
+		f["meta"] = evt.SendRPC.GetMeta()
+	case pubsub_pb.TraceEvent_DROP_RPC:
+		f["to"] = peer.ID(evt.DropRPC.GetSendTo()).String()
+		f["meta"] = evt.DropRPC.GetMeta()
+	case pubsub_pb.TraceEvent_JOIN:
+		f["topic"] = evt.Join.GetTopic()
+	case pubsub_pb.TraceEvent_LEAVE:
+		f["topic"] = evt.Leave.GetTopic()
+	case pubsub_pb.TraceEvent_GRAFT:
+		f["peer_id"] = peer.ID(evt.Graft.GetPeerID()).String()
+		f["topic"] = evt.Graft.GetTopic()
+	case pubsub_pb.TraceEvent_PRUNE:
+		f["peer_id"] = peer.ID(evt.Prune.GetPeerID()).String()
+		f["topic"] = evt.Prune.GetTopic()
+	}
+	t.log.WithFields(f).Info("gossip trace event")
+}
+
+type traceForwarder struct {
+	lock sync.Mutex
+	open bool
+	ch   chan *pubsub_pb.TraceEvent
+	log  logrus.FieldLogger
+}
+
+func (t *traceForwarder) Trace(evt *pubsub_pb.TraceEvent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.open {
+		return
+	}
+	// never block the pubsub event loop, drop events if the websocket can't keep up.
+	select {
+	case t.ch <- evt:
+	default:
+		t.log.Warn("trace buffer is full, dropping trace event")
+	}
+}
+
+func (t *traceForwarder) Close() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.open {
+		t.open = false
+		close(t.ch)
+	}
+}
+
+func (c *GossipTraceCmd) wsForwarder() (*traceForwarder, func() error) {
+	h := http.Header{}
+	if c.Key != "" {
+		h["X-Api-Key"] = []string{c.Key}
+	}
+	reconnCtx, reconnCancel := context.WithCancel(context.Background())
+	fw := &traceForwarder{open: true, ch: make(chan *pubsub_pb.TraceEvent, 1024), log: c.Log}
+	go func() {
+	reconnectLoop:
+		for {
+			c.Log.Info("dialing websocket")
+			conn, _, err := websocket.DefaultDialer.Dial(c.Path, h)
+			if err != nil {
+				c.Log.WithError(err).Error("WS dial error")
+				select {
+				case <-time.After(time.Second * 5):
+					continue
+				case <-reconnCtx.Done():
+					return
+				}
+			}
+
+			conn.SetPingHandler(nil)
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(pongWait))
+			})
+
+			// keep forwarding trace events to the websocket
+			for evt := range fw.ch {
+				if err := conn.WriteJSON(evt); err != nil {
+					if _, ok := err.(*websocket.CloseError); ok && !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+						c.Log.WithError(err).Warn("failed to write trace event to json websocket")
+						continue
+					}
+					c.Log.WithError(err).Info("Websocket closed, reconnecting after 10 seconds...")
+					_ = conn.Close()
+					select {
+					case <-time.After(time.Second * 10):
+						continue reconnectLoop
+					case <-reconnCtx.Done():
+						return
+					}
+				}
+			}
+			if err := conn.Close(); err != nil {
+				c.Log.WithError(err).Error("failed to close websocket connection properly")
+			}
+			c.Log.Info("Done sending trace events to websocket")
+			return
+		}
+	}()
+	return fw, func() error {
+		reconnCancel()
+		fw.Close()
+		return nil
+	}
+}
+
+func (c *GossipTraceCmd) Run(ctx context.Context, args ...string) error {
+	if c.GossipState.GsNode == nil {
+		return NoGossipErr
+	}
+	var tracer pubsub.EventTracer
+	var clean func() error
+	switch c.Dest {
+	case "log":
+		tracer = &traceLogger{log: c.Log.WithField("tracer", "log")}
+	case "json":
+		tr, err := pubsub.OpenJSONTracer(c.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		tracer = tr
+		clean = func() error {
+			tr.Close()
+			return nil
+		}
+	case "pb":
+		tr, err := pubsub.OpenPBTracer(c.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		tracer = tr
+		clean = func() error {
+			tr.Close()
+			return nil
+		}
+	case "wsjson":
+		tracer, clean = c.wsForwarder()
+	default:
+		return fmt.Errorf("unrecognized trace output type: %s", c.Dest)
+	}
+	if len(c.Events) > 0 {
+		types := make(map[pubsub_pb.TraceEvent_Type]struct{})
+		for _, name := range c.Events {
+			v, ok := pubsub_pb.TraceEvent_Type_value[strings.ToUpper(name)]
+			if !ok {
+				if clean != nil {
+					_ = clean()
+				}
+				return fmt.Errorf("unrecognized trace event type: %s", name)
+			}
+			types[pubsub_pb.TraceEvent_Type(v)] = struct{}{}
+		}
+		tracer = &traceFilter{inner: tracer, types: types}
+	}
+	gs := c.GossipState.GsNode
+	_ = gs.AddTracer(tracer)
+	c.Log.WithField("dest", c.Dest).Info("Started gossip tracer")
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		c.Log.WithField("dest", c.Dest).Info("Stopping gossip tracer")
+		gs.RmTracer(tracer)
+		if clean != nil {
+			return clean()
+		}
+		return nil
+	})
+	return nil
+}
@@ -221,5 +221,7 @@ func (c *HostStartCmd) Run(ctx context.Context, args ...string) error {
 	if err != nil {
 		return err
 	}
+	// Track the connection history of peers in the peerstore
+	h.Network().Notify(track.ConnectionNotifiee(store))
 	return c.SetHost(h)
 }
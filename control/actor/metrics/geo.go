@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/metrics/geoloc"
+	"github.com/sirupsen/logrus"
+	"io"
+)
+
+type MetricsGeoCmd struct {
+	*base.Base
+	GossipState *metrics.GossipState
+
+	Source    string `ask:"--source" help:"Geolocation source: mmdb, ip-api or none"`
+	CityDB    string `ask:"--city-db" help:"Path to a MaxMind City MMDB file, for the mmdb source"`
+	ASNDB     string `ask:"--asn-db" help:"Path to a MaxMind ASN MMDB file, for the mmdb source"`
+	CacheSize int    `ask:"--cache-size" help:"Number of IP lookups to cache"`
+	Workers   int    `ask:"--workers" help:"Number of lookups to run concurrently"`
+	QueueSize int    `ask:"--queue-size" help:"Maximum number of pending lookups, new lookups are dropped when full"`
+}
+
+func (c *MetricsGeoCmd) Default() {
+	c.Source = "mmdb"
+	c.CacheSize = 4096
+	c.Workers = 2
+	c.QueueSize = 1024
+}
+
+func (c *MetricsGeoCmd) Help() string {
+	return "Configure the geolocation of new peers in the gossip metrics. Lookups run in the background."
+}
+
+func (c *MetricsGeoCmd) HelpLong() string {
+	return `
+Sources:
+- mmdb: offline lookups in local MaxMind DB files (e.g. GeoLite2-City and GeoLite2-ASN), provide --city-db and/or --asn-db
+- ip-api: online lookups with ip-api.com, rate-limited to 40 requests per minute
+- none: disable geolocation, only the IP of peers is recorded
+`
+}
+
+func (c *MetricsGeoCmd) Run(ctx context.Context, args ...string) error {
+	if c.Source != "none" {
+		if c.Workers <= 0 {
+			return fmt.Errorf("need at least 1 worker, got %d", c.Workers)
+		}
+		if c.QueueSize < 0 {
+			return fmt.Errorf("invalid queue size: %d", c.QueueSize)
+		}
+	}
+	var locator geoloc.Locator
+	switch c.Source {
+	case "mmdb":
+		m, err := geoloc.OpenMMDB(c.CityDB, c.ASNDB)
+		if err != nil {
+			return err
+		}
+		locator = m
+	case "ip-api":
+		locator = geoloc.NewIpApiLocator()
+	case "none":
+		if prev := c.GossipState.SetLocator(nil); prev != nil {
+			return prev.Close()
+		}
+		c.Log.Info("Disabled geolocation")
+		return nil
+	default:
+		return fmt.Errorf("unrecognized geolocation source: %s", c.Source)
+	}
+	// close the new locator if it does not get used
+	configured := false
+	defer func() {
+		if cl, ok := locator.(io.Closer); ok && !configured {
+			if err := cl.Close(); err != nil {
+				c.Log.WithError(err).Warn("failed to close unused geolocation source")
+			}
+		}
+	}()
+	if c.CacheSize > 0 {
+		cached, err := geoloc.NewCachedLocator(locator, c.CacheSize)
+		if err != nil {
+			return err
+		}
+		locator = cached
+	}
+	async := geoloc.NewAsyncLocator(locator, c.Workers, c.QueueSize)
+	configured = true
+	if prev := c.GossipState.SetLocator(async); prev != nil {
+		if err := prev.Close(); err != nil {
+			c.Log.WithError(err).Warn("failed to close previous geolocation source")
+		}
+	}
+	c.Log.WithFields(logrus.Fields{"source": c.Source, "cache_size": c.CacheSize}).Info("Configured geolocation")
+	return nil
+}
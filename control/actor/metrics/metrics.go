@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/protolambda/ask"
+	"github.com/protolambda/rumor/chain"
+	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/control/actor/dv5"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/track"
+)
+
+type MetricsCmd struct {
+	*base.Base
+	GossipState *metrics.GossipState
+	RPCMetrics  *metrics.RPCMetrics
+	Dv5State    *dv5.Dv5State
+	Store       track.DynamicPeerstore
+	Chains      chain.Chains
+	ChainState  *actorchain.ChainState
+}
+
+func (c *MetricsCmd) Cmd(route string) (cmd interface{}, err error) {
+	switch route {
+	case "serve":
+		cmd = &MetricsServeCmd{
+			Base:        c.Base,
+			GossipState: c.GossipState,
+			RPCMetrics:  c.RPCMetrics,
+			Dv5State:    c.Dv5State,
+			Store:       c.Store,
+			Chains:      c.Chains,
+			ChainState:  c.ChainState,
+		}
+	case "geo":
+		cmd = &MetricsGeoCmd{Base: c.Base, GossipState: c.GossipState}
+	default:
+		return nil, ask.UnrecognizedErr
+	}
+	return cmd, nil
+}
+
+func (c *MetricsCmd) Routes() []string {
+	return []string{"serve", "geo"}
+}
+
+func (c *MetricsCmd) Help() string {
+	return "Expose metrics of the actor"
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/chain"
+	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/control/actor/dv5"
+	"github.com/protolambda/rumor/metrics"
+	"github.com/protolambda/rumor/p2p/custom"
+	"github.com/protolambda/rumor/p2p/track"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+type MetricsServeCmd struct {
+	*base.Base
+	GossipState *metrics.GossipState
+	RPCMetrics  *metrics.RPCMetrics
+	Dv5State    *dv5.Dv5State
+	Store       track.DynamicPeerstore
+	Chains      chain.Chains
+	ChainState  *actorchain.ChainState
+
+	Addr string `ask:"--addr" help:"Address to serve the /metrics endpoint on"`
+}
+
+func (c *MetricsServeCmd) Default() {
+	c.Addr = "0.0.0.0:9090"
+}
+
+func (c *MetricsServeCmd) Help() string {
+	return "Serve a Prometheus/OpenMetrics /metrics endpoint with peer, gossip, rpc, discv5 and chain metrics"
+}
+
+// trackRPC makes sure the RPC streams of the host are counted, if there is a host that supports it.
+func (c *MetricsServeCmd) trackRPC() {
+	h, err := c.Host()
+	if err != nil {
+		return
+	}
+	if ih, ok := h.(custom.Interceptable); ok {
+		ih.AddStreamInterceptor(c.RPCMetrics)
+	}
+}
+
+func (c *MetricsServeCmd) writeMetrics(w *metrics.PromWriter) {
+	if h, err := c.Host(); err == nil {
+		directions := make(map[network.Direction]map[peer.ID]struct{})
+		for _, conn := range h.Network().Conns() {
+			dir := conn.Stat().Direction
+			if directions[dir] == nil {
+				directions[dir] = make(map[peer.ID]struct{})
+			}
+			directions[dir][conn.RemotePeer()] = struct{}{}
+		}
+		var samples []metrics.PromSample
+		for _, dir := range []network.Direction{network.DirInbound, network.DirOutbound, network.DirUnknown} {
+			name := "unknown"
+			switch dir {
+			case network.DirInbound:
+				name = "inbound"
+			case network.DirOutbound:
+				name = "outbound"
+			}
+			samples = append(samples, metrics.PromSample{
+				Labels: []metrics.PromLabel{{Name: "direction", Value: name}},
+				Value:  float64(len(directions[dir])),
+			})
+		}
+		w.Family("rumor_connected_peers", "gauge", "Number of connected peers, by connection direction", samples...)
+	}
+	if c.Store != nil && c.Store.Initialized() {
+		w.Gauge("rumor_peerstore_peers", "Number of peers in the peerstore", float64(len(c.Store.Peers())))
+	}
+
+	topicCounts := c.GossipState.TopicMessageCounts()
+	topics := make([]string, 0, len(topicCounts))
+	for topic := range topicCounts {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	gossipSamples := make([]metrics.PromSample, 0, len(topics))
+	for _, topic := range topics {
+		gossipSamples = append(gossipSamples, metrics.PromSample{
+			Labels: []metrics.PromLabel{{Name: "topic", Value: topic}},
+			Value:  float64(topicCounts[topic]),
+		})
+	}
+	w.Family("rumor_gossip_messages_total", "counter", "Number of received gossip messages, by topic", gossipSamples...)
+
+	keys, counts := c.RPCMetrics.Requests()
+	rpcSamples := make([]metrics.PromSample, 0, len(keys))
+	for i, k := range keys {
+		rpcSamples = append(rpcSamples, metrics.PromSample{
+			Labels: []metrics.PromLabel{
+				{Name: "method", Value: k.Method},
+				{Name: "version", Value: k.Version},
+				{Name: "direction", Value: k.Direction},
+				{Name: "result", Value: k.Result},
+			},
+			Value: float64(counts[i]),
+		})
+	}
+	w.Family("rumor_rpc_requests_total", "counter", "Number of RPC requests, by method, direction and result code", rpcSamples...)
+
+	w.Family("rumor_dv5_nodes_found_total", "counter", "Number of nodes found with discv5",
+		metrics.PromSample{Value: float64(atomic.LoadUint64(&c.Dv5State.NodesFound))})
+
+	if ch, ok := c.Chains.Find(c.ChainState.CurrentChain); ok {
+		if head, err := ch.Head(); err == nil {
+			w.Gauge("rumor_chain_head_slot", "Slot of the head of the current chain", float64(head.Slot()))
+		}
+		w.Gauge("rumor_chain_finalized_epoch", "Finalized epoch of the current chain", float64(ch.Finalized().Epoch))
+	}
+}
+
+func (c *MetricsServeCmd) Run(ctx context.Context, args ...string) error {
+	ln, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", c.Addr, err)
+	}
+	c.trackRPC()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(rw http.ResponseWriter, req *http.Request) {
+		// the host may have been started after the server, make sure it's tracked
+		c.trackRPC()
+		rw.Header().Set("Content-Type", metrics.PromContentType)
+		w := metrics.NewPromWriter(rw)
+		c.writeMetrics(w)
+		if err := w.Flush(); err != nil {
+			c.Log.WithError(err).Warn("failed to write metrics response")
+		}
+	})
+	server := &http.Server{
+		Addr:    c.Addr,
+		Handler: mux,
+	}
+	c.Log.WithField("addr", ln.Addr().String()).Info("Serving metrics")
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			c.Log.WithError(err).Error("Metrics server failed")
+		}
+	}()
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if h, err := c.Host(); err == nil {
+			if ih, ok := h.(custom.Interceptable); ok {
+				ih.RmStreamInterceptor(c.RPCMetrics)
+			}
+		}
+		c.Log.Info("Stopped serving metrics")
+		return server.Shutdown(shutdownCtx)
+	})
+	return nil
+}
@@ -2,6 +2,7 @@ package peer
 
 import (
 	"context"
+	"fmt"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/flags"
@@ -15,6 +16,8 @@ type PeerConnectCmd struct {
 	Timeout time.Duration          `ask:"--timeout" help:"connection timeout, 0 to disable"`
 	Addr    flags.FlexibleAddrFlag `ask:"<addr>" help:"ENR, enode or multi address to connect to"`
 	Tag     string                 `ask:"[tag]" help:"Optionally tag the peer upon connection, e.g. tag 'bootnode'"`
+
+	IgnoreBackoff bool `ask:"--ignore-backoff" help:"Connect even if the peer said goodbye recently, and reconnecting is backed off"`
 }
 
 func (c *PeerConnectCmd) Default() {
@@ -41,12 +44,18 @@ func (c *PeerConnectCmd) Run(ctx context.Context, args ...string) error {
 			c.Log.Info("added ENR data to peerstore")
 		}
 	}
+	if rec := c.Store.Goodbye(addrInfo.ID); rec.InBackoff(time.Now()) && !c.IgnoreBackoff {
+		return fmt.Errorf("peer %s said goodbye, not reconnecting until %s", addrInfo.ID.Pretty(), rec.BackoffUntil)
+	}
 	if c.Timeout != 0 {
 		ctx, _ = context.WithTimeout(ctx, c.Timeout)
 	}
 	if err := h.Connect(ctx, *addrInfo); err != nil {
+		c.Store.RegisterScore(addrInfo.ID, track.ScoreDialFailure)
+		c.Store.RegisterDialFailure(addrInfo.ID)
 		return err
 	}
+	c.Store.RegisterScore(addrInfo.ID, track.ScoreDialSuccess)
 	c.Log.WithField("peer_id", addrInfo.ID.Pretty()).Infof("connected to peer")
 	if c.Tag != "" {
 		h.ConnManager().Protect(addrInfo.ID, c.Tag)
@@ -12,6 +12,7 @@ import (
 	"github.com/protolambda/rumor/p2p/track"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 )
@@ -24,6 +25,7 @@ type PeerConnectAllCmd struct {
 	MaxRetries uint64        `ask:"--max-retries" help:"how many connection attempts until the peer is banned"`
 	Workers    uint64        `ask:"--workers" help:"how many parallel routines should be attempting connections"`
 	MaxPeers   uint64        `ask:"--max-peers" help:"max amount of peers, pause auto-connecting when above this"`
+	MinScore   float64       `ask:"--min-score" help:"Skip peers with a score below this. Peers without a score are always considered."`
 
 	FilterDigest beacon.ForkDigest `ask:"--filter-digest" help:"Only connect when the peer is known to have the given fork digest in ENR. Or connect to any if not specified."`
 	Filtering    bool              `changed:"filter-digest"`
@@ -35,6 +37,7 @@ func (c *PeerConnectAllCmd) Default() {
 	c.MaxRetries = 5
 	c.Workers = 1
 	c.MaxPeers = 200
+	c.MinScore = -20
 }
 
 func (c *PeerConnectAllCmd) Help() string {
@@ -196,6 +199,8 @@ func (c *PeerConnectAllCmd) run(ctx context.Context, h host.Host) {
 			attemptLog.WithField("addrs", addrInfo.Addrs).Debug("attempting connection to peer")
 			// Slight chance we're already connected due to duplicate scheduling, but that's ok, nothing happens.
 			if err := h.Connect(ctx, addrInfo); err != nil {
+				c.Store.RegisterScore(p, track.ScoreDialFailure)
+				c.Store.RegisterDialFailure(p)
 				// increment attempts
 				peerAttemptLock.Lock()
 				// default value is 0, that's ok
@@ -221,6 +226,7 @@ func (c *PeerConnectAllCmd) run(ctx context.Context, h host.Host) {
 				}
 			} else {
 				log.WithField("peer_id", p).Debug("successful connection made")
+				c.Store.RegisterScore(p, track.ScoreDialSuccess)
 				// reset attempts
 				peerAttemptLock.Lock()
 				peerAttempts[p] = 0
@@ -240,30 +246,52 @@ func (c *PeerConnectAllCmd) run(ctx context.Context, h host.Host) {
 		storedPeers := c.Store.PeersWithAddrs()
 
 		var schedules []peer.ID
+		scores := make(map[peer.ID]float64)
+		now := time.Now()
+		// Check if it didn't fail before (unknown peer or success last time).
+		// Only hold the lock to snapshot the attempts, the peerstore lookups may hit the datastore.
+		var candidates []peer.ID
 		peerAttemptLock.Lock()
 		for _, p := range storedPeers {
-			// Check if it didn't fail before (unknown peer or success last time)
 			if v, ok := peerAttempts[p]; !ok || v == 0 {
-				if c.Filtering { // optionally filter by fork-digest
-					enr := c.Store.LatestENR(p)
-					if enr == nil {
-						continue
-					}
-					eth2Data, ok, err := addrutil.ParseEnrEth2Data(enr)
-					if err != nil || !ok {
-						continue
-					}
-					if eth2Data.ForkDigest != c.FilterDigest {
-						continue
-					}
+				candidates = append(candidates, p)
+			}
+		}
+		peerAttemptLock.Unlock()
+		for _, p := range candidates {
+			// Respect the backoff of peers that said goodbye
+			if c.Store.Goodbye(p).InBackoff(now) {
+				continue
+			}
+			// Skip peers with a bad reputation
+			if sc := c.Store.Score(p); sc != nil {
+				if sc.Score < c.MinScore {
+					continue
 				}
-				// Check if we're connected already
-				if status := h.Network().Connectedness(p); status != network.Connected {
-					schedules = append(schedules, p)
+				scores[p] = sc.Score
+			}
+			if c.Filtering { // optionally filter by fork-digest
+				enr := c.Store.LatestENR(p)
+				if enr == nil {
+					continue
 				}
+				eth2Data, ok, err := addrutil.ParseEnrEth2Data(enr)
+				if err != nil || !ok {
+					continue
+				}
+				if eth2Data.ForkDigest != c.FilterDigest {
+					continue
+				}
+			}
+			// Check if we're connected already
+			if status := h.Network().Connectedness(p); status != network.Connected {
+				schedules = append(schedules, p)
 			}
 		}
-		peerAttemptLock.Unlock()
+		// Connect to the best peers first, unscored peers are neutral
+		sort.SliceStable(schedules, func(i, j int) bool {
+			return scores[schedules[i]] > scores[schedules[j]]
+		})
 
 		c.Log.Infof("scanned peerstore, found %d peers to schedule", len(schedules))
 		count := len(h.Network().Peers())
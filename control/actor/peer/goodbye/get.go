@@ -0,0 +1,39 @@
+package goodbye
+
+import (
+	"context"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type PeerGoodbyeGetCmd struct {
+	*base.Base
+	Book   track.GoodbyeBook
+	PeerID flags.PeerIDFlag `ask:"<peer-id>" help:"Peer to get the latest goodbye of"`
+}
+
+func (c *PeerGoodbyeGetCmd) Help() string {
+	return "Get the latest goodbye of a peer, and if reconnecting to the peer is backed off"
+}
+
+func (c *PeerGoodbyeGetCmd) Run(ctx context.Context, args ...string) error {
+	rec := c.Book.Goodbye(c.PeerID.PeerID)
+	if rec == nil {
+		c.Log.WithField("peer_id", c.PeerID.PeerID.String()).Info("Peer did not say goodbye")
+		return nil
+	}
+	c.Log.WithFields(logrus.Fields{
+		"peer_id":       c.PeerID.PeerID.String(),
+		"reason":        uint64(rec.Reason),
+		"reason_name":   methods.GoodbyeReasonName(rec.Reason),
+		"time":          rec.Time,
+		"count":         rec.Count,
+		"backoff_until": rec.BackoffUntil,
+		"in_backoff":    rec.InBackoff(time.Now()),
+	}).Info("Goodbye")
+	return nil
+}
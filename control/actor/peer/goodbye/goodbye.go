@@ -0,0 +1,61 @@
+package goodbye
+
+import (
+	"fmt"
+	"github.com/protolambda/ask"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"strings"
+	"time"
+)
+
+type PeerGoodbyeCmd struct {
+	*base.Base
+	Book   track.GoodbyeBook
+	Scores track.ScoreBook
+}
+
+func (c *PeerGoodbyeCmd) Help() string {
+	return "Send and serve goodbyes, and track the goodbyes of peers"
+}
+
+func (c *PeerGoodbyeCmd) Cmd(route string) (cmd interface{}, err error) {
+	switch route {
+	case "serve":
+		cmd = &PeerGoodbyeServeCmd{Base: c.Base, Book: c.Book, Scores: c.Scores}
+	case "send":
+		cmd = &PeerGoodbyeSendCmd{Base: c.Base}
+	case "get":
+		cmd = &PeerGoodbyeGetCmd{Base: c.Base, Book: c.Book}
+	default:
+		return nil, ask.UnrecognizedErr
+	}
+	return cmd, nil
+}
+
+func (c *PeerGoodbyeCmd) Routes() []string {
+	return []string{"serve", "send", "get"}
+}
+
+// parseBackoffs parses a list of "reason=duration" entries, the reason being a name or number.
+func parseBackoffs(entries []string) (map[beacon.Goodbye]time.Duration, error) {
+	out := make(map[beacon.Goodbye]time.Duration, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid backoff %q, expected 'reason=duration'", entry)
+		}
+		reason, err := methods.ParseGoodbyeReason(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid backoff duration of reason %s: %v", parts[0], err)
+		}
+		out[reason] = d
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package goodbye
+
+import (
+	"context"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"time"
+)
+
+type PeerGoodbyeSendCmd struct {
+	*base.Base
+	Timeout     time.Duration           `ask:"--timeout" help:"Timeout for sending the goodbye. 0 to disable"`
+	Compression flags.CompressionFlag   `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	Reason      flags.GoodbyeReasonFlag `ask:"--reason" help:"Goodbye reason: a name like client-shutdown, irrelevant-network, fault-error, too-many-peers, or a number"`
+	Disconnect  bool                    `ask:"--disconnect" help:"Disconnect from the peer after sending the goodbye"`
+	PeerID      flags.PeerIDFlag        `ask:"<peer-id>" help:"Peer to say goodbye to"`
+}
+
+func (c *PeerGoodbyeSendCmd) Default() {
+	c.Timeout = 10 * time.Second
+	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
+	c.Reason = flags.GoodbyeReasonFlag{Reason: methods.GoodbyeClientShutdown}
+	c.Disconnect = true
+}
+
+func (c *PeerGoodbyeSendCmd) Help() string {
+	return "Say goodbye to a peer with the given reason, and disconnect"
+}
+
+func (c *PeerGoodbyeSendCmd) Run(ctx context.Context, args ...string) error {
+	h, err := c.Host()
+	if err != nil {
+		return err
+	}
+	entry, err := methods.DefaultRegistry.Find("goodbye", "1")
+	if err != nil {
+		return err
+	}
+	m := entry.Method(nil)
+	if c.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	peerID := c.PeerID.PeerID
+	sFn := reqresp.NewStreamFn(h.NewStream)
+	err = m.RunRequest(ctx, sFn, peerID, c.Compression.Compression,
+		reqresp.RequestSSZInput{Obj: c.Reason.Reason}, 0,
+		func() error {
+			return nil
+		},
+		func(chunk reqresp.ChunkedResponseHandler) error {
+			return nil
+		})
+	log := c.Log.WithField("peer_id", peerID.String()).WithField("reason", c.Reason.String())
+	if err != nil {
+		// Still disconnect if asked to, the peer may not be listening for goodbyes
+		log.WithError(err).Warn("failed to send goodbye")
+	} else {
+		log.Info("sent goodbye")
+	}
+	if c.Disconnect {
+		if err := h.Network().ClosePeer(peerID); err != nil {
+			return err
+		}
+		log.Info("disconnected peer")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package goodbye
+
+import (
+	"context"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"time"
+)
+
+type PeerGoodbyeServeCmd struct {
+	*base.Base
+	Book        track.GoodbyeBook
+	Scores      track.ScoreBook
+	Timeout     time.Duration         `ask:"--timeout" help:"Apply timeout of n milliseconds to each stream (complete request <> response time). 0 to Disable timeout"`
+	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	Disconnect  bool                  `ask:"--disconnect" help:"Disconnect from the peer after receiving its goodbye"`
+
+	Backoff        []string      `ask:"--backoff" help:"Reconnect backoff per goodbye reason, as 'reason=duration' entries, e.g. 'client-shutdown=1m,129=5m'"`
+	DefaultBackoff time.Duration `ask:"--default-backoff" help:"Reconnect backoff for goodbye reasons without a backoff entry"`
+
+	Limits flags.StreamLimitFlags `ask:"."`
+}
+
+func (c *PeerGoodbyeServeCmd) Help() string {
+	return "Serve incoming goodbyes: record the reason in the peerstore, and back off from reconnecting to the peer"
+}
+
+func (c *PeerGoodbyeServeCmd) Default() {
+	c.Timeout = 10 * time.Second
+	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
+	c.Disconnect = true
+	c.Backoff = []string{
+		"client-shutdown=1m",
+		"irrelevant-network=1h",
+		"fault-error=10m",
+		"too-many-peers=5m",
+		"banned=24h",
+	}
+	c.DefaultBackoff = 10 * time.Minute
+	c.Limits = flags.DefaultStreamLimits()
+}
+
+func (c *PeerGoodbyeServeCmd) Run(ctx context.Context, args ...string) error {
+	h, err := c.Host()
+	if err != nil {
+		return err
+	}
+	backoffs, err := parseBackoffs(c.Backoff)
+	if err != nil {
+		return err
+	}
+	entry, err := methods.DefaultRegistry.Find("goodbye", "1")
+	if err != nil {
+		return err
+	}
+	m := entry.Method(nil) // the goodbye method does not depend on the spec
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	sCtxFn := func() context.Context {
+		if c.Timeout == 0 {
+			return bgCtx
+		}
+		reqCtx, cancel := context.WithTimeout(bgCtx, c.Timeout)
+		// the handler does not return the context, release it once it expires
+		time.AfterFunc(c.Timeout, cancel)
+		return reqCtx
+	}
+	comp := c.Compression.Compression
+	listenReq := func(ctx context.Context, peerId peer.ID, handler reqresp.ChunkedRequestHandler) {
+		f := map[string]interface{}{
+			"from": peerId.String(),
+		}
+		var reason beacon.Goodbye
+		if err := handler.ReadRequest(&reason); err != nil {
+			f["input_err"] = err.Error()
+			c.Log.WithFields(f).Warnf("failed to read goodbye request: %v", err)
+			return
+		}
+		backoff, ok := backoffs[reason]
+		if !ok {
+			backoff = c.DefaultBackoff
+		}
+		rec := c.Book.RegisterGoodbye(peerId, reason, backoff)
+		f["reason"] = uint64(reason)
+		f["reason_name"] = methods.GoodbyeReasonName(reason)
+		f["count"] = rec.Count
+		f["backoff_until"] = rec.BackoffUntil
+		c.Log.WithFields(f).Info("received goodbye")
+		if c.Disconnect {
+			// The goodbye has no response, the stream is closed by the handler. Close the connection after.
+			go func() {
+				if err := h.Network().ClosePeer(peerId); err != nil {
+					c.Log.WithFields(f).Warnf("failed to disconnect peer after goodbye: %v", err)
+				}
+			}()
+		}
+	}
+	limiter := c.Limits.Limiter(bgCtx, c.Log)
+	streamHandler := m.MakeLimitedStreamHandler(sCtxFn, comp, limiter, track.ScoredRequestListener(c.Scores, listenReq))
+	prot := m.Protocol
+	if comp != nil {
+		prot += protocol.ID("_" + comp.Name())
+	}
+	h.SetStreamHandler(prot, streamHandler)
+	c.Log.WithField("started", true).Info("Started serving goodbye")
+
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		bgCancel()
+		h.RemoveStreamHandler(prot)
+		flags.LogStreamStats(c.Log, limiter)
+		c.Log.Infof("Stopped serving goodbye")
+		return nil
+	})
+	return nil
+}
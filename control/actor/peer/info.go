@@ -54,15 +54,24 @@ func (c *PeerInfoCmd) Run(ctx context.Context, args ...string) error {
 	if info.Attnets != nil {
 		f["enr_attnets"] = info.Attnets
 	}
+	if info.Syncnets != nil {
+		f["enr_syncnets"] = info.Syncnets
+	}
 	if info.MetaData != nil {
 		f["metadata"] = info.MetaData
 	}
+	if info.MetaDataV2 != nil {
+		f["metadata_v2"] = info.MetaDataV2
+	}
 	if info.ClaimedSeq != 0 {
 		f["claimed_seq"] = info.ClaimedSeq
 	}
 	if info.Status != nil {
 		f["status"] = info.Status
 	}
+	if info.Connections != nil {
+		f["connections"] = info.Connections
+	}
 	if info.ENR != nil {
 		f["enr"] = info.ENR
 	}
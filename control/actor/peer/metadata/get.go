@@ -16,9 +16,12 @@ func (c *PeerMetadataGetCmd) Help() string {
 }
 
 func (c *PeerMetadataGetCmd) Run(ctx context.Context, args ...string) error {
+	md := c.PeerMetadataState.LocalV1()
+	mdV2 := c.PeerMetadataState.LocalV2()
 	c.Log.WithFields(logrus.Fields{
 		"following": c.PeerMetadataState.Following,
-		"metadata":  c.PeerMetadataState.Local.Data(),
+		"metadata":  md.Data(),
+		"syncnets":  mdV2.Syncnets.String(),
 	}).Info("Metadata settings")
 	return nil
 }
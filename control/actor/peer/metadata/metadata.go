@@ -3,18 +3,50 @@ package metadata
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/protolambda/ask"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
 	"github.com/protolambda/rumor/p2p/track"
 	"github.com/protolambda/zrnt/eth2/beacon"
+	"sync"
 )
 
 type PeerMetadataState struct {
 	Following bool
-	Local     beacon.MetaData
+	// guards Local and Syncnets, these are updated in the background by 'gossip subnets',
+	// while the serve handlers read them.
+	lock  sync.RWMutex
+	Local beacon.MetaData
+	// Sync committee subnets, only part of the Altair metadata, see LocalV2
+	Syncnets altair.SyncnetBits
+}
+
+// LocalV1 is a copy of the local metadata, as served with version 1 of the metadata RPC method
+func (c *PeerMetadataState) LocalV1() beacon.MetaData {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.Local
+}
+
+// LocalV2 is the local metadata, as served with version 2 of the metadata RPC method
+func (c *PeerMetadataState) LocalV2() altair.MetaDataV2 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	md := altair.UpgradeMetaData(&c.Local)
+	md.Syncnets = c.Syncnets
+	return md
+}
+
+// UpdateLocal modifies the local metadata and syncnets at once, and returns the updated metadata.
+func (c *PeerMetadataState) UpdateLocal(fn func(md *beacon.MetaData, syncnets *altair.SyncnetBits)) beacon.MetaData {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	fn(&c.Local, &c.Syncnets)
+	return c.Local
 }
 
 type PeerMetadataCmd struct {
@@ -32,17 +64,17 @@ func (c *PeerMetadataCmd) Cmd(route string) (cmd interface{}, err error) {
 	case "ping":
 		cmd = &PeerMetadataPingCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Store: c.Store}
 	case "pong":
-		cmd = &PeerMetadataPongCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Book: c.Store}
+		cmd = &PeerMetadataPongCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Book: c.Store, Scores: c.Store}
 	case "get":
 		cmd = &PeerMetadataGetCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState}
 	case "set":
 		cmd = &PeerMetadataSetCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState}
 	case "req":
-		cmd = &PeerMetadataReqCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Book: c.Store}
+		cmd = &PeerMetadataReqCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Book: c.Store, Scores: c.Store}
 	case "poll":
 		cmd = &PeerMetadataPollCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Store: c.Store}
 	case "serve":
-		cmd = &PeerMetadataServeCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState}
+		cmd = &PeerMetadataServeCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Scores: c.Store}
 	case "follow":
 		cmd = &PeerMetadataFollowCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState}
 	default:
@@ -55,10 +87,10 @@ func (c *PeerMetadataCmd) Routes() []string {
 	return []string{"ping", "pong", "get", "set", "req", "poll", "serve", "follow"}
 }
 
-func (c *PeerMetadataState) fetch(book track.MetadataBook, sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression) (
+func (c *PeerMetadataState) fetch(book track.MetadataBook, scores track.ScoreBook, sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression) (
 	resCode reqresp.ResponseCode, errMsg string, data *beacon.MetaData, err error) {
 	resCode = reqresp.ServerErrCode // error by default
-	err = methods.MetaDataRPCv1.RunRequest(ctx, sFn, peerID, comp, reqresp.RequestSSZInput{Obj: nil}, 1,
+	err = track.ScoredRequest(scores, &methods.MetaDataRPCv1, ctx, sFn, peerID, comp, reqresp.RequestSSZInput{Obj: nil}, 1,
 		func() error {
 			// TODO
 			return nil
@@ -87,11 +119,64 @@ func (c *PeerMetadataState) fetch(book track.MetadataBook, sFn reqresp.NewStream
 	return
 }
 
-func (c *PeerMetadataState) ping(sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression) (
+func (c *PeerMetadataState) fetchV2(book track.MetadataBook, scores track.ScoreBook, sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression) (
+	resCode reqresp.ResponseCode, errMsg string, data *altair.MetaDataV2, err error) {
+	resCode = reqresp.ServerErrCode // error by default
+	err = track.ScoredRequest(scores, &methods.MetaDataRPCv2, ctx, sFn, peerID, comp, reqresp.RequestSSZInput{Obj: nil}, 1,
+		func() error {
+			return nil
+		},
+		func(chunk reqresp.ChunkedResponseHandler) error {
+			resCode = chunk.ResultCode()
+			switch resCode {
+			case reqresp.ServerErrCode, reqresp.InvalidReqCode:
+				msg, err := chunk.ReadErrMsg()
+				if err != nil {
+					return err
+				}
+				errMsg = msg
+			case reqresp.SuccessCode:
+				var meta altair.MetaDataV2
+				if err := chunk.ReadObj(&meta); err != nil {
+					return err
+				}
+				data = &meta
+				book.RegisterMetadataV2(peerID, meta)
+			default:
+				return errors.New("unexpected result code")
+			}
+			return nil
+		})
+	return
+}
+
+// fetchVersion fetches the metadata with the given version of the RPC method: 1, or 2 for the Altair metadata.
+// The data is nil if the request was not successful.
+func (c *PeerMetadataState) fetchVersion(book track.MetadataBook, scores track.ScoreBook, sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression, version uint64) (
+	resCode reqresp.ResponseCode, errMsg string, data map[string]interface{}, err error) {
+	switch version {
+	case 1:
+		code, msg, md, err := c.fetch(book, scores, sFn, ctx, peerID, comp)
+		if md != nil {
+			data = md.Data()
+		}
+		return code, msg, data, err
+	case 2:
+		code, msg, md, err := c.fetchV2(book, scores, sFn, ctx, peerID, comp)
+		if md != nil {
+			data = md.Data()
+		}
+		return code, msg, data, err
+	default:
+		return reqresp.ServerErrCode, "", nil, fmt.Errorf("unknown metadata version: %d", version)
+	}
+}
+
+func (c *PeerMetadataState) ping(scores track.ScoreBook, sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression) (
 	resCode reqresp.ResponseCode, errMsg string, data beacon.Pong, err error) {
 	resCode = reqresp.ServerErrCode // error by default
-	p := beacon.Ping(c.Local.SeqNumber)
-	err = methods.PingRPCv1.RunRequest(ctx, sFn, peerID, comp, reqresp.RequestSSZInput{Obj: &p}, 1,
+	p := beacon.Ping(c.LocalV1().SeqNumber)
+	err = track.ScoredRequest(scores, &methods.PingRPCv1, ctx, sFn, peerID, comp, reqresp.RequestSSZInput{Obj: &p}, 1,
 		func() error {
 			return nil
 		},
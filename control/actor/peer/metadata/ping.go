@@ -26,6 +26,7 @@ type PeerMetadataPingCmd struct {
 	UpdateTimeout time.Duration         `ask:"--update-timeout" help:"If updating, use this timeout for the update request, 0 to disable."`
 	PeerID        flags.PeerIDFlag      `ask:"<peer-id>" help:"Peer to fetch metadata from."`
 	MaxTries      uint64                `ask:"--max-tries" help:"How many times an update should be attempted after learning about a pong"`
+	Version       uint64                `ask:"--version" help:"Version of the metadata method to update with: 1, or 2 for the Altair metadata with syncnets"`
 }
 
 func (c *PeerMetadataPingCmd) Help() string {
@@ -38,6 +39,7 @@ func (c *PeerMetadataPingCmd) Default() {
 	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
 	c.Update = true
 	c.MaxTries = 20
+	c.Version = 1
 }
 
 func (c *PeerMetadataPingCmd) Run(ctx context.Context, args ...string) error {
@@ -56,7 +58,7 @@ func (c *PeerMetadataPingCmd) Run(ctx context.Context, args ...string) error {
 		startTime = time.Now()
 		return h.NewStream(ctx, peerId, protocolId...)
 	})
-	code, msg, pong, err := c.ping(newStream, reqCtx, peerID, c.Compression.Compression)
+	code, msg, pong, err := c.ping(c.Store, newStream, reqCtx, peerID, c.Compression.Compression)
 	if err != nil {
 		return fmt.Errorf("failed to ping: %v", err)
 	} else {
@@ -77,7 +79,16 @@ func (c *PeerMetadataPingCmd) Run(ctx context.Context, args ...string) error {
 
 	updating := c.ForceUpdate
 	if !updating && c.Update {
-		current := c.Store.Metadata(peerID)
+		var current *beacon.MetaData
+		if c.Version == 2 {
+			// phase0 metadata may be known, but without the syncnets
+			if md := c.Store.MetadataV2(peerID); md != nil {
+				v1 := md.V1()
+				current = &v1
+			}
+		} else {
+			current = c.Store.Metadata(peerID)
+		}
 		if current == nil || current.SeqNumber < beacon.SeqNr(pong) {
 			fetches := c.Store.RegisterMetaFetch(peerID)
 			updating = fetches <= c.MaxTries
@@ -89,14 +100,14 @@ func (c *PeerMetadataPingCmd) Run(ctx context.Context, args ...string) error {
 		if c.UpdateTimeout != 0 {
 			updateCtx, _ = context.WithTimeout(updateCtx, c.UpdateTimeout)
 		}
-		code, msg, metadata, err := c.fetch(c.Store, h.NewStream, updateCtx, peerID, c.Compression.Compression)
+		code, msg, metadata, err := c.fetchVersion(c.Store, c.Store, h.NewStream, updateCtx, peerID, c.Compression.Compression, c.Version)
 		if err != nil {
 			return fmt.Errorf("failed to fetch metadata upon pong: %v", err)
 		} else {
 			if code == reqresp.SuccessCode {
 				c.Log.WithFields(logrus.Fields{
 					"code":     code,
-					"metadata": metadata.Data(),
+					"metadata": metadata,
 				}).Debug("metadata request upon pong success")
 			} else {
 				c.Log.WithFields(logrus.Fields{
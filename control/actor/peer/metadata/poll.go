@@ -22,6 +22,7 @@ type PeerMetadataPollCmd struct {
 	UpdateTimeout time.Duration         `ask:"--update-timeout" help:"If updating, use this timeout for the update request, 0 to disable."`
 	MaxTries      uint64                `ask:"--max-tries" help:"How many times an update should be attempted after learning about a pong"`
 	Compression   flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	Version       uint64                `ask:"--version" help:"Version of the metadata method to update with: 1, or 2 for the Altair metadata with syncnets"`
 }
 
 func (c *PeerMetadataPollCmd) Default() {
@@ -31,6 +32,7 @@ func (c *PeerMetadataPollCmd) Default() {
 	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
 	c.Update = true
 	c.MaxTries = 20
+	c.Version = 1
 }
 
 func (c *PeerMetadataPollCmd) Help() string {
@@ -69,6 +71,7 @@ func (c *PeerMetadataPollCmd) Run(ctx context.Context, args ...string) error {
 						ForceUpdate:       c.ForceUpdate,
 						UpdateTimeout:     c.UpdateTimeout,
 						MaxTries:          c.MaxTries,
+						Version:           c.Version,
 						PeerID:            flags.PeerIDFlag{PeerID: peerID},
 					}
 					if err := pingCmd.Run(reqCtx); err != nil {
@@ -17,6 +17,7 @@ type PeerMetadataPongCmd struct {
 	*base.Base
 	*PeerMetadataState
 	Book          track.MetadataBook
+	Scores        track.ScoreBook
 	Timeout       time.Duration         `ask:"--timeout" help:"Apply timeout of n milliseconds to each stream (complete request <> response time). 0 to Disable timeout"`
 	Compression   flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
 	Update        bool                  `ask:"--update" help:"If the seq nr ping is higher than known, request metadata"`
@@ -62,7 +63,7 @@ func (c *PeerMetadataPongCmd) Run(ctx context.Context, args ...string) error {
 			_ = handler.WriteErrorChunk(reqresp.InvalidReqCode, "could not parse ping request")
 			c.Log.WithFields(f).Warnf("failed to read ping request: %v", err)
 		} else {
-			pong := beacon.Pong(c.PeerMetadataState.Local.SeqNumber)
+			pong := beacon.Pong(c.PeerMetadataState.LocalV1().SeqNumber)
 			if err := handler.WriteResponseChunk(reqresp.SuccessCode, &pong); err != nil {
 				c.Log.WithFields(f).Warnf("failed to respond to ping request: %v", err)
 			} else {
@@ -82,6 +83,7 @@ func (c *PeerMetadataPongCmd) Run(ctx context.Context, args ...string) error {
 					Base:              c.Base,
 					PeerMetadataState: c.PeerMetadataState,
 					Book:              c.Book,
+					Scores:            c.Scores,
 					Timeout:           c.UpdateTimeout,
 					Compression:       c.Compression,
 					PeerID:            flags.PeerIDFlag{PeerID: peerId},
@@ -96,7 +98,7 @@ func (c *PeerMetadataPongCmd) Run(ctx context.Context, args ...string) error {
 		}
 	}
 	m := methods.PingRPCv1
-	streamHandler := m.MakeStreamHandler(sCtxFn, comp, listenReq)
+	streamHandler := m.MakeStreamHandler(sCtxFn, comp, track.ScoredRequestListener(c.Scores, listenReq))
 	prot := m.Protocol
 	if comp != nil {
 		prot += protocol.ID("_" + comp.Name())
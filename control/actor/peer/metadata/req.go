@@ -15,8 +15,10 @@ type PeerMetadataReqCmd struct {
 	*base.Base
 	*PeerMetadataState
 	Book        track.MetadataBook
+	Scores      track.ScoreBook
 	Timeout     time.Duration         `ask:"--timeout" help:"request timeout, 0 to disable"`
 	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	Version     uint64                `ask:"--version" help:"Version of the metadata method: 1, or 2 for the Altair metadata with syncnets"`
 	PeerID      flags.PeerIDFlag      `ask:"<peer-id>" help:"Peer to fetch metadata from."`
 }
 
@@ -27,6 +29,7 @@ func (c *PeerMetadataReqCmd) Help() string {
 func (c *PeerMetadataReqCmd) Default() {
 	c.Timeout = 10 * time.Second
 	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
+	c.Version = 1
 }
 
 func (c *PeerMetadataReqCmd) Run(ctx context.Context, args ...string) error {
@@ -38,12 +41,12 @@ func (c *PeerMetadataReqCmd) Run(ctx context.Context, args ...string) error {
 	if c.Timeout != 0 {
 		reqCtx, _ = context.WithTimeout(reqCtx, c.Timeout)
 	}
-	code, msg, metadata, err := c.fetch(c.Book, h.NewStream, reqCtx, c.PeerID.PeerID, c.Compression.Compression)
+	code, msg, metadata, err := c.fetchVersion(c.Book, c.Scores, h.NewStream, reqCtx, c.PeerID.PeerID, c.Compression.Compression, c.Version)
 	if err != nil {
 		return fmt.Errorf("failed to fetch metadata: %v", err)
 	} else {
 		if code == reqresp.SuccessCode {
-			c.Log.WithField("code", code).WithFields(metadata.Data()).Debug("metadata request success")
+			c.Log.WithField("code", code).WithFields(metadata).Debug("metadata request success")
 		} else {
 			c.Log.WithFields(logrus.Fields{
 				"code": code,
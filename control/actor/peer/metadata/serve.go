@@ -8,23 +8,29 @@ import (
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/protolambda/ztyp/codec"
 	"time"
 )
 
 type PeerMetadataServeCmd struct {
 	*base.Base
 	*PeerMetadataState
+	Scores      track.ScoreBook
 	Timeout     time.Duration         `ask:"--timeout" help:"Apply timeout of n milliseconds to each stream (complete request <> response time). 0 to Disable timeout"`
 	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+
+	Limits flags.StreamLimitFlags `ask:"."`
 }
 
 func (c *PeerMetadataServeCmd) Help() string {
-	return "Serve incoming metadata requests"
+	return "Serve incoming metadata requests, of both the phase0 and the Altair version"
 }
 
 func (c *PeerMetadataServeCmd) Default() {
 	c.Timeout = 10 * time.Second
 	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
+	c.Limits = flags.DefaultStreamLimits()
 }
 
 func (c *PeerMetadataServeCmd) Run(ctx context.Context, args ...string) error {
@@ -41,36 +47,59 @@ func (c *PeerMetadataServeCmd) Run(ctx context.Context, args ...string) error {
 		return reqCtx
 	}
 	comp := c.Compression.Compression
-	listenReq := func(ctx context.Context, peerId peer.ID, handler reqresp.ChunkedRequestHandler) {
-		f := map[string]interface{}{
-			"from": peerId.String(),
-		}
-		// Metadata requests have no data, but we do need to check the general format.
-		err := handler.ReadRequest(nil)
-		if err != nil {
-			f["input_err"] = err.Error()
-			_ = handler.WriteErrorChunk(reqresp.InvalidReqCode, "could not parse metadata request")
-			c.Log.WithFields(f).Warnf("failed to read metadata request: %v", err)
-		} else {
-			if err := handler.WriteResponseChunk(reqresp.SuccessCode, &c.PeerMetadataState.Local); err != nil {
-				c.Log.WithFields(f).Warnf("failed to respond to metadata request: %v", err)
+	// listenReq handles a metadata request of the given version: 1, or 2 for the Altair metadata with syncnets
+	listenReq := func(version string) reqresp.OnRequestListener {
+		return func(ctx context.Context, peerId peer.ID, handler reqresp.ChunkedRequestHandler) {
+			f := map[string]interface{}{
+				"from":    peerId.String(),
+				"version": version,
+			}
+			// Metadata requests have no data, but we do need to check the general format.
+			err := handler.ReadRequest(nil)
+			if err != nil {
+				f["input_err"] = err.Error()
+				_ = handler.WriteErrorChunk(reqresp.InvalidReqCode, "could not parse metadata request")
+				c.Log.WithFields(f).Warnf("failed to read metadata request: %v", err)
 			} else {
-				c.Log.WithFields(f).Info("handled metadata request")
+				md := c.PeerMetadataState.LocalV1()
+				var local codec.Serializable = &md
+				if version == "2" {
+					mdV2 := c.PeerMetadataState.LocalV2()
+					local = &mdV2
+				}
+				if err := handler.WriteResponseChunk(reqresp.SuccessCode, local); err != nil {
+					c.Log.WithFields(f).Warnf("failed to respond to metadata request: %v", err)
+				} else {
+					c.Log.WithFields(f).Info("handled metadata request")
+				}
 			}
 		}
 	}
-	m := methods.MetaDataRPCv1
-	streamHandler := m.MakeStreamHandler(sCtxFn, comp, listenReq)
-	prot := m.Protocol
-	if comp != nil {
-		prot += protocol.ID("_" + comp.Name())
+	limiter := c.Limits.Limiter(bgCtx, c.Log)
+	var prots []protocol.ID
+	for _, version := range []string{"1", "2"} {
+		entry, err := methods.DefaultRegistry.Find("metadata", version)
+		if err != nil {
+			bgCancel()
+			return err
+		}
+		m := entry.Method(nil) // the metadata method does not depend on the spec
+		streamHandler := m.MakeLimitedStreamHandler(sCtxFn, comp, limiter, track.ScoredRequestListener(c.Scores, listenReq(version)))
+		prot := m.Protocol
+		if comp != nil {
+			prot += protocol.ID("_" + comp.Name())
+		}
+		h.SetStreamHandler(prot, streamHandler)
+		prots = append(prots, prot)
 	}
-	h.SetStreamHandler(prot, streamHandler)
 	c.Log.WithField("started", true).Info("Started serving metadata")
 
 	c.Control.RegisterStop(func(ctx context.Context) error {
 		bgCancel()
-		h.RemoveStreamHandler(prot)
+		for _, prot := range prots {
+			h.RemoveStreamHandler(prot)
+		}
+		flags.LogStreamStats(c.Log, limiter)
 		c.Log.Infof("Stopped serving metadata")
 		return nil
 	})
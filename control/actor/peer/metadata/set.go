@@ -3,6 +3,7 @@ package metadata
 import (
 	"context"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/sirupsen/logrus"
 )
@@ -10,9 +11,10 @@ import (
 type PeerMetadataSetCmd struct {
 	*base.Base
 	*PeerMetadataState
-	SeqNumber beacon.SeqNr      `ask:"--seq-number" help:"Seq Number of metadata"`
-	Attnets   beacon.AttnetBits `ask:"--attnets" help:"Attestation nets bitfield as bytes"`
-	Merge     bool              `ask:"--merge" help:"If true, only apply non-zero options to state"`
+	SeqNumber beacon.SeqNr       `ask:"--seq-number" help:"Seq Number of metadata"`
+	Attnets   beacon.AttnetBits  `ask:"--attnets" help:"Attestation nets bitfield as bytes"`
+	Syncnets  altair.SyncnetBits `ask:"--syncnets" help:"Sync committee nets bitfield as bytes, only served with the Altair metadata"`
+	Merge     bool               `ask:"--merge" help:"If true, only apply non-zero options to state"`
 }
 
 func (c *PeerMetadataSetCmd) Default() {
@@ -24,22 +26,28 @@ func (c *PeerMetadataSetCmd) Help() string {
 }
 
 func (c *PeerMetadataSetCmd) Run(ctx context.Context, args ...string) error {
-	st := beacon.MetaData{}
-	if c.Merge {
-		st = c.PeerMetadataState.Local
-	}
-	if !c.Merge || c.Attnets != (beacon.AttnetBits{}) {
-		st.Attnets = c.Attnets
-	}
-	if !c.Merge || c.SeqNumber != 0 {
-		st.SeqNumber = c.SeqNumber
-	}
-	// mutate full metadata at once (TODO: do we need a lock?)
-	c.PeerMetadataState.Local = st
+	var syncnets altair.SyncnetBits
+	// mutate full metadata at once
+	st := c.PeerMetadataState.UpdateLocal(func(md *beacon.MetaData, sn *altair.SyncnetBits) {
+		if !c.Merge {
+			*md = beacon.MetaData{}
+		}
+		if !c.Merge || c.Attnets != (beacon.AttnetBits{}) {
+			md.Attnets = c.Attnets
+		}
+		if !c.Merge || c.SeqNumber != 0 {
+			md.SeqNumber = c.SeqNumber
+		}
+		if !c.Merge || c.Syncnets != (altair.SyncnetBits{}) {
+			*sn = c.Syncnets
+		}
+		syncnets = *sn
+	})
 
 	c.Log.WithFields(logrus.Fields{
 		"following": c.PeerMetadataState.Following,
-		"metadata":  c.PeerMetadataState.Local.Data(),
+		"metadata":  st.Data(),
+		"syncnets":  syncnets.String(),
 	}).Info("Metadata settings")
 	return nil
 }
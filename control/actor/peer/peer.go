@@ -3,8 +3,12 @@ package peer
 import (
 	"errors"
 	"github.com/protolambda/ask"
+	"github.com/protolambda/rumor/chain"
 	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/control/actor/peer/goodbye"
 	"github.com/protolambda/rumor/control/actor/peer/metadata"
+	"github.com/protolambda/rumor/control/actor/peer/score"
 	"github.com/protolambda/rumor/control/actor/peer/status"
 	trackcmd "github.com/protolambda/rumor/control/actor/peer/track"
 	"github.com/protolambda/rumor/p2p/track"
@@ -14,7 +18,9 @@ type PeerCmd struct {
 	*base.Base
 	*status.PeerStatusState
 	*metadata.PeerMetadataState
-	Store track.ExtendedPeerstore
+	Store      track.ExtendedPeerstore
+	Chains     chain.Chains
+	ChainState *actorchain.ChainState
 }
 
 func (c *PeerCmd) Cmd(route string) (cmd interface{}, err error) {
@@ -35,7 +41,7 @@ func (c *PeerCmd) Cmd(route string) (cmd interface{}, err error) {
 	case "add":
 		cmd = &PeerAddCmd{Base: c.Base, Store: c.Store}
 	case "trim":
-		cmd = &PeerTrimCmd{Base: c.Base}
+		cmd = &PeerTrimCmd{Base: c.Base, Store: c.Store}
 	case "list":
 		cmd = &PeerListCmd{Base: c.Base, Store: c.Store}
 	case "info":
@@ -47,7 +53,12 @@ func (c *PeerCmd) Cmd(route string) (cmd interface{}, err error) {
 	case "addrs":
 		cmd = &PeerAddrsCmd{Base: c.Base}
 	case "status":
-		cmd = &status.PeerStatusCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Store}
+		cmd = &status.PeerStatusCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Store, Scores: c.Store,
+			Chains: c.Chains, ChainState: c.ChainState}
+	case "goodbye":
+		cmd = &goodbye.PeerGoodbyeCmd{Base: c.Base, Book: c.Store, Scores: c.Store}
+	case "score":
+		cmd = &score.PeerScoreCmd{Base: c.Base, Book: c.Store}
 	case "metadata":
 		cmd = &metadata.PeerMetadataCmd{Base: c.Base, PeerMetadataState: c.PeerMetadataState, Store: c.Store}
 	default:
@@ -58,7 +69,7 @@ func (c *PeerCmd) Cmd(route string) (cmd interface{}, err error) {
 
 func (c *PeerCmd) Routes() []string {
 	return []string{"connect", "disconnect", "connectall", "protect", "unprotect", "add", "trim",
-		"list", "info", "identify", "track", "addrs", "status", "metadata"}
+		"list", "info", "identify", "track", "addrs", "status", "goodbye", "score", "metadata"}
 }
 
 func (c *PeerCmd) Help() string {
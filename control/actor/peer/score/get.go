@@ -0,0 +1,35 @@
+package score
+
+import (
+	"context"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/sirupsen/logrus"
+)
+
+type PeerScoreGetCmd struct {
+	*base.Base
+	Book   track.ScoreBook
+	PeerID flags.PeerIDFlag `ask:"<peer-id>" help:"Peer to get the score of"`
+}
+
+func (c *PeerScoreGetCmd) Help() string {
+	return "Get the current score of a peer, and the events it was scored with"
+}
+
+func (c *PeerScoreGetCmd) Run(ctx context.Context, args ...string) error {
+	sc := c.Book.Score(c.PeerID.PeerID)
+	if sc == nil {
+		c.Log.WithField("peer_id", c.PeerID.PeerID.String()).Info("Peer has no score")
+		return nil
+	}
+	c.Log.WithFields(logrus.Fields{
+		"peer_id":         c.PeerID.PeerID.String(),
+		"score":           sc.Score,
+		"latency_penalty": sc.LatencyPenalty,
+		"updated":         sc.Updated,
+		"counts":          sc.Counts,
+	}).Info("Score")
+	return nil
+}
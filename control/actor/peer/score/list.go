@@ -0,0 +1,48 @@
+package score
+
+import (
+	"context"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/track"
+	"sort"
+)
+
+type PeerScoreListCmd struct {
+	*base.Base
+	Book track.ScoreBook
+
+	Min      float64 `ask:"--min" help:"Only list peers with a score of at least this"`
+	Filtered bool    `changed:"min"`
+	Max      uint64  `ask:"--max" help:"Max amount of peers to list, 0 to list all"`
+}
+
+func (c *PeerScoreListCmd) Help() string {
+	return "List the scored peers, from best to worst."
+}
+
+type scoredPeer struct {
+	PeerID string  `json:"peer_id"`
+	Score  float64 `json:"score"`
+}
+
+func (c *PeerScoreListCmd) Run(ctx context.Context, args ...string) error {
+	var out []scoredPeer
+	for _, p := range c.Book.ScoredPeers() {
+		sc := c.Book.Score(p)
+		if sc == nil {
+			continue
+		}
+		if c.Filtered && sc.Score < c.Min {
+			continue
+		}
+		out = append(out, scoredPeer{PeerID: p.String(), Score: sc.Score})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Score > out[j].Score
+	})
+	if c.Max != 0 && uint64(len(out)) > c.Max {
+		out = out[:c.Max]
+	}
+	c.Log.WithField("peers", out).Infof("%d scored peers", len(out))
+	return nil
+}
@@ -0,0 +1,32 @@
+package score
+
+import (
+	"github.com/protolambda/ask"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/track"
+)
+
+type PeerScoreCmd struct {
+	*base.Base
+	Book track.ScoreBook
+}
+
+func (c *PeerScoreCmd) Help() string {
+	return "Inspect the scores of peers, based on their past behavior"
+}
+
+func (c *PeerScoreCmd) Cmd(route string) (cmd interface{}, err error) {
+	switch route {
+	case "list":
+		cmd = &PeerScoreListCmd{Base: c.Base, Book: c.Book}
+	case "get":
+		cmd = &PeerScoreGetCmd{Base: c.Base, Book: c.Book}
+	default:
+		return nil, ask.UnrecognizedErr
+	}
+	return cmd, nil
+}
+
+func (c *PeerScoreCmd) Routes() []string {
+	return []string{"list", "get"}
+}
@@ -0,0 +1,267 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/chain"
+	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/control/actor/peer/goodbye"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// StatusCompatKey is the peerstore key the status compatibility class of a peer is stored under
+const StatusCompatKey = "eth2_status_compat"
+
+// StatusCompat classifies the status of a peer, compared to the local chain
+type StatusCompat string
+
+const (
+	// Same fork digest, and the same head slot
+	StatusCompatible StatusCompat = "compatible"
+	// The peer is on another fork or network
+	StatusWrongForkDigest StatusCompat = "wrong-fork-digest"
+	// The finalized checkpoint of the peer is not part of the local chain
+	StatusFinalizedConflict StatusCompat = "finalized-conflict"
+	// Compatible, but the head of the peer is behind the local head
+	StatusBehind StatusCompat = "behind"
+	// Compatible, but the head of the peer is ahead of the local head
+	StatusAhead StatusCompat = "ahead"
+)
+
+// Incompatible returns the goodbye reason to disconnect the peer with, if the peer is incompatible.
+func (sc StatusCompat) Incompatible() (reason beacon.Goodbye, ok bool) {
+	switch sc {
+	case StatusWrongForkDigest, StatusFinalizedConflict:
+		return methods.GoodbyeIrrelevantNetwork, true
+	default:
+		return 0, false
+	}
+}
+
+// ChainStatus derives the status of the given chain, from its head and finalized checkpoint.
+func ChainStatus(ctx context.Context, ch chain.FullChain) (beacon.Status, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return beacon.Status{}, fmt.Errorf("failed to get chain head: %v", err)
+	}
+	state, err := head.State(ctx)
+	if err != nil {
+		return beacon.Status{}, fmt.Errorf("failed to get head state: %v", err)
+	}
+	fork, err := state.Fork()
+	if err != nil {
+		return beacon.Status{}, fmt.Errorf("failed to get fork of head state: %v", err)
+	}
+	version, err := fork.CurrentVersion()
+	if err != nil {
+		return beacon.Status{}, fmt.Errorf("failed to get fork version of head state: %v", err)
+	}
+	genesisValRoot, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return beacon.Status{}, fmt.Errorf("failed to get genesis validators root of head state: %v", err)
+	}
+	finalized := ch.Finalized()
+	// The spec uses a zero finalized root for the genesis checkpoint
+	if finalized.Epoch == beacon.GENESIS_EPOCH {
+		finalized.Root = beacon.Root{}
+	}
+	return beacon.Status{
+		ForkDigest:     beacon.ComputeForkDigest(version, genesisValRoot),
+		FinalizedRoot:  finalized.Root,
+		FinalizedEpoch: finalized.Epoch,
+		HeadRoot:       head.BlockRoot(),
+		HeadSlot:       head.Slot(),
+	}, nil
+}
+
+// classify compares the status of a peer with the given chain. Without chain, the local status is used instead.
+func (c *PeerStatusState) classify(ctx context.Context, ch chain.FullChain, remote *beacon.Status) (StatusCompat, error) {
+	local := c.LocalStatus()
+	digest := local.ForkDigest
+	headSlot := local.HeadSlot
+	finalized := beacon.Checkpoint{Epoch: local.FinalizedEpoch, Root: local.FinalizedRoot}
+	var spec *beacon.Spec
+	if ch != nil {
+		head, err := ch.Head()
+		if err != nil {
+			return "", fmt.Errorf("failed to get chain head: %v", err)
+		}
+		epc, err := head.EpochsContext(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get chain head epochs context: %v", err)
+		}
+		// The expected fork digest is that of the chain, regardless of the local status
+		chainStatus, err := ChainStatus(ctx, ch)
+		if err != nil {
+			return "", err
+		}
+		digest = chainStatus.ForkDigest
+		spec = epc.Spec
+		headSlot = head.Slot()
+		finalized = ch.Finalized()
+	}
+	if remote.ForkDigest != digest {
+		return StatusWrongForkDigest, nil
+	}
+	// The genesis checkpoint has a zero root in the status, and is always compatible.
+	// A peer finalized further than the local chain cannot be verified yet.
+	if remote.FinalizedEpoch != 0 && remote.FinalizedEpoch <= finalized.Epoch {
+		if remote.FinalizedEpoch == finalized.Epoch {
+			if remote.FinalizedRoot != finalized.Root {
+				return StatusFinalizedConflict, nil
+			}
+		} else if ch != nil {
+			// The finalized root is the latest block root at the start of the finalized epoch
+			entry, err := ch.BySlot(spec.EpochStartSlot(remote.FinalizedEpoch))
+			if err == nil && entry.BlockRoot() != remote.FinalizedRoot {
+				return StatusFinalizedConflict, nil
+			}
+		}
+	}
+	if remote.HeadSlot < headSlot {
+		return StatusBehind, nil
+	}
+	if remote.HeadSlot > headSlot {
+		return StatusAhead, nil
+	}
+	return StatusCompatible, nil
+}
+
+type PeerStatusCheckCmd struct {
+	*base.Base
+	*PeerStatusState
+	Book        track.StatusBook
+	Scores      track.ScoreBook
+	Chains      chain.Chains
+	ChainState  *actorchain.ChainState
+	Timeout     time.Duration         `ask:"--timeout" help:"request timeout, 0 to disable"`
+	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	Fetch       bool                  `ask:"--fetch" help:"Request the status of the peer first, instead of checking the last known status"`
+	Disconnect  bool                  `ask:"--disconnect" help:"Say goodbye to incompatible peers, and disconnect them"`
+	PeerID      flags.PeerIDFlag      `ask:"[peer-id]" help:"Peer to check. All connected peers if not specified."`
+}
+
+func (c *PeerStatusCheckCmd) Help() string {
+	return "Check if the status of peers is compatible with the current chain, and tag the peers with the result."
+}
+
+func (c *PeerStatusCheckCmd) Default() {
+	c.Timeout = 10 * time.Second
+	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
+	c.Fetch = true
+}
+
+// currentChain returns the current chain of the actor, or nil if there is none
+func (c *PeerStatusCheckCmd) currentChain() chain.FullChain {
+	if c.Chains == nil || c.ChainState == nil {
+		return nil
+	}
+	ch, ok := c.Chains.Find(c.ChainState.CurrentChain)
+	if !ok {
+		return nil
+	}
+	return ch
+}
+
+// checkLocal refuses to disconnect peers based on a local status that was never set,
+// which would classify every peer as being on the wrong fork.
+func (c *PeerStatusCheckCmd) checkLocal() error {
+	if c.Disconnect && c.LocalStatus().ForkDigest == (beacon.ForkDigest{}) {
+		return errors.New("no current chain, and no local status fork digest to check against. " +
+			"Refusing to disconnect, set the local status with 'peer status set' or create a chain first")
+	}
+	return nil
+}
+
+func (c *PeerStatusCheckCmd) Run(ctx context.Context, args ...string) error {
+	h, err := c.Host()
+	if err != nil {
+		return err
+	}
+	ch := c.currentChain()
+	if ch == nil {
+		if err := c.checkLocal(); err != nil {
+			return err
+		}
+		c.Log.Debug("no current chain, checking against local status")
+	}
+	if c.PeerID.PeerID != "" {
+		return c.check(ctx, ch, c.PeerID.PeerID)
+	}
+	var wg sync.WaitGroup
+	for _, p := range h.Network().Peers() {
+		wg.Add(1)
+		go func(peerID peer.ID) {
+			defer wg.Done()
+			if err := c.check(ctx, ch, peerID); err != nil {
+				c.Log.WithField("peer", peerID.String()).WithError(err).Warn("failed to check peer")
+			}
+		}(p)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (c *PeerStatusCheckCmd) check(ctx context.Context, ch chain.FullChain, peerID peer.ID) error {
+	h, err := c.Host()
+	if err != nil {
+		return err
+	}
+	if c.Fetch {
+		reqCtx := ctx
+		if c.Timeout != 0 {
+			var cancel context.CancelFunc
+			reqCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+			defer cancel()
+		}
+		if _, _, _, err := c.fetch(c.Book, c.Scores, h.NewStream, reqCtx, peerID, c.Compression.Compression); err != nil {
+			return fmt.Errorf("failed to fetch status: %v", err)
+		}
+	}
+	remote := c.Book.Status(peerID)
+	if remote == nil {
+		return errors.New("no status known of peer")
+	}
+	class, err := c.classify(ctx, ch, remote)
+	if err != nil {
+		return err
+	}
+	if err := h.Peerstore().Put(peerID, StatusCompatKey, string(class)); err != nil {
+		return fmt.Errorf("failed to tag peer: %v", err)
+	}
+	log := c.Log.WithFields(logrus.Fields{
+		"peer":            peerID.String(),
+		"class":           class,
+		"fork_digest":     remote.ForkDigest,
+		"finalized_epoch": remote.FinalizedEpoch,
+		"head_slot":       remote.HeadSlot,
+	})
+	reason, incompatible := class.Incompatible()
+	if !incompatible {
+		log.Info("peer status is compatible")
+		return nil
+	}
+	log.Warn("peer status is incompatible")
+	if c.Disconnect {
+		byeCmd := &goodbye.PeerGoodbyeSendCmd{
+			Base:        c.Base,
+			Timeout:     c.Timeout,
+			Compression: c.Compression,
+			Reason:      flags.GoodbyeReasonFlag{Reason: reason},
+			Disconnect:  true,
+			PeerID:      flags.PeerIDFlag{PeerID: peerID},
+		}
+		return byeCmd.Run(ctx)
+	}
+	return nil
+}
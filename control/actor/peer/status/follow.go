@@ -21,10 +21,10 @@ func (c *PeerStatusFollowCmd) Help() string {
 }
 
 func (c *PeerStatusFollowCmd) Run(ctx context.Context, args ...string) error {
-	c.PeerStatusState.Following = c.Following
+	c.PeerStatusState.SetFollowing(c.Following)
 
 	c.Log.WithFields(logrus.Fields{
-		"following": c.PeerStatusState.Following,
+		"following": c.Following,
 	}).Info("Status follow settings")
 	return nil
 }
@@ -0,0 +1,92 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"github.com/protolambda/rumor/chain"
+	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"time"
+)
+
+type PeerStatusFollowChainCmd struct {
+	*base.Base
+	*PeerStatusState
+	Chains     chain.Chains
+	ChainState *actorchain.ChainState
+	Interval   time.Duration `ask:"--interval" help:"Interval to check the current chain for a new head or finalized checkpoint on"`
+}
+
+func (c *PeerStatusFollowChainCmd) Default() {
+	c.Interval = 2 * time.Second
+}
+
+func (c *PeerStatusFollowChainCmd) Help() string {
+	return "Keep the local status up to date with the head of the current chain, until stopped. " +
+		"Updating is paused while following is disabled, see 'peer status follow'."
+}
+
+func (c *PeerStatusFollowChainCmd) Run(ctx context.Context, args ...string) error {
+	if c.Chains == nil || c.ChainState == nil {
+		return fmt.Errorf("no chains available")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("invalid interval: %s", c.Interval)
+	}
+	// restored when stopped
+	prevFollowing := c.PeerStatusState.SetFollowing(true)
+
+	update := func(ctx context.Context) {
+		if !c.PeerStatusState.IsFollowing() {
+			return
+		}
+		// The current chain is looked up every time, to follow along with 'chain switch'
+		ch, ok := c.Chains.Find(c.ChainState.CurrentChain)
+		if !ok {
+			c.Log.WithField("chain", c.ChainState.CurrentChain).Debug("current chain not found, not updating status")
+			return
+		}
+		st, err := ChainStatus(ctx, ch)
+		if err != nil {
+			c.Log.WithError(err).Warn("failed to derive status from chain")
+			return
+		}
+		changed := false
+		// mutate full status at once
+		c.PeerStatusState.UpdateLocalStatus(func(local *beacon.Status) {
+			changed = *local != st
+			*local = st
+		})
+		if !changed {
+			return
+		}
+		c.Log.WithFields(st.Data()).Info("Updated local status from chain")
+	}
+
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+		update(bgCtx)
+		for {
+			select {
+			case <-ticker.C:
+				update(bgCtx)
+			case <-bgCtx.Done():
+				return
+			}
+		}
+	}()
+
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		bgCancel()
+		<-done
+		c.PeerStatusState.SetFollowing(prevFollowing)
+		c.Log.Infof("Stopped following chain status")
+		return nil
+	})
+	return nil
+}
@@ -16,9 +16,10 @@ func (c *PeerStatusGetCmd) Help() string {
 }
 
 func (c *PeerStatusGetCmd) Run(ctx context.Context, args ...string) error {
+	local := c.PeerStatusState.LocalStatus()
 	c.Log.WithFields(logrus.Fields{
-		"following": c.PeerStatusState.Following,
-		"status":    c.PeerStatusState.Local.Data(),
+		"following": c.PeerStatusState.IsFollowing(),
+		"status":    local.Data(),
 	}).Info("Status settings")
 	return nil
 }
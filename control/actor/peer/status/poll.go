@@ -3,7 +3,9 @@ package status
 import (
 	"context"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/chain"
 	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
 	"github.com/protolambda/rumor/p2p/track"
@@ -15,9 +17,14 @@ type PeerStatusPollCmd struct {
 	*base.Base
 	*PeerStatusState
 	Book        track.StatusBook
+	Scores      track.ScoreBook
+	Chains      chain.Chains
+	ChainState  *actorchain.ChainState
 	Timeout     time.Duration         `ask:"--timeout" help:"request timeout, 0 to disable."`
 	Interval    time.Duration         `ask:"--interval" help:"interval to request status of peers on, applied as timeout to a round of work"`
 	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	Check       bool                  `ask:"--check" help:"Check the compatibility of each polled status with the current chain, and tag the peers with the result"`
+	Disconnect  bool                  `ask:"--disconnect" help:"With --check, say goodbye to incompatible peers, and disconnect them"`
 }
 
 func (c *PeerStatusPollCmd) Help() string {
@@ -35,6 +42,16 @@ func (c *PeerStatusPollCmd) Run(ctx context.Context, args ...string) error {
 	if err != nil {
 		return err
 	}
+	if c.Check {
+		// Refuse early, instead of failing every check of every round
+		checkCmd := &PeerStatusCheckCmd{PeerStatusState: c.PeerStatusState, Chains: c.Chains,
+			ChainState: c.ChainState, Disconnect: c.Disconnect}
+		if checkCmd.currentChain() == nil {
+			if err := checkCmd.checkLocal(); err != nil {
+				return err
+			}
+		}
+	}
 
 	stopping := false
 	bgCtx, bgCancel := context.WithCancel(context.Background())
@@ -52,10 +69,31 @@ func (c *PeerStatusPollCmd) Run(ctx context.Context, args ...string) error {
 			for _, p := range h.Network().Peers() {
 				wg.Add(1)
 				go func(peerID peer.ID) {
+					if c.Check {
+						checkCmd := &PeerStatusCheckCmd{
+							Base:            c.Base,
+							PeerStatusState: c.PeerStatusState,
+							Book:            c.Book,
+							Scores:          c.Scores,
+							Chains:          c.Chains,
+							ChainState:      c.ChainState,
+							Timeout:         c.Timeout,
+							Compression:     c.Compression,
+							Fetch:           true,
+							Disconnect:      c.Disconnect,
+							PeerID:          flags.PeerIDFlag{PeerID: peerID},
+						}
+						if err := checkCmd.Run(reqCtx); err != nil {
+							c.Log.WithField("peer", peerID.String()).WithError(err).Warn("failed to poll peer")
+						}
+						wg.Done()
+						return
+					}
 					statusCmd := &PeerStatusReqCmd{
 						Base:            c.Base,
 						PeerStatusState: c.PeerStatusState,
 						Book:            c.Book,
+						Scores:          c.Scores,
 						Timeout:         c.Timeout,
 						Compression:     c.Compression,
 						PeerID:          flags.PeerIDFlag{PeerID: peerID},
@@ -15,6 +15,7 @@ type PeerStatusReqCmd struct {
 	*base.Base
 	*PeerStatusState
 	Book        track.StatusBook
+	Scores      track.ScoreBook
 	Timeout     time.Duration         `ask:"--timeout" help:"request timeout, 0 to disable"`
 	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
 	PeerID      flags.PeerIDFlag      `ask:"<peer-id>" help:"Peer to fetch status from."`
@@ -38,7 +39,7 @@ func (c *PeerStatusReqCmd) Run(ctx context.Context, args ...string) error {
 	if c.Timeout != 0 {
 		reqCtx, _ = context.WithTimeout(reqCtx, c.Timeout)
 	}
-	code, msg, stat, err := c.fetch(c.Book, h.NewStream, reqCtx, c.PeerID.PeerID, c.Compression.Compression)
+	code, msg, stat, err := c.fetch(c.Book, c.Scores, h.NewStream, reqCtx, c.PeerID.PeerID, c.Compression.Compression)
 	if err != nil {
 		return fmt.Errorf("failed to fetch status: %v", err)
 	} else {
@@ -17,8 +17,11 @@ type PeerStatusServeCmd struct {
 	*base.Base
 	*PeerStatusState
 	Book        track.StatusBook
+	Scores      track.ScoreBook
 	Timeout     time.Duration         `ask:"--timeout" help:"Apply timeout of n milliseconds to each stream (complete request <> response time). 0 to Disable timeout"`
 	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+
+	Limits flags.StreamLimitFlags `ask:"."`
 }
 
 func (c *PeerStatusServeCmd) Help() string {
@@ -28,6 +31,7 @@ func (c *PeerStatusServeCmd) Help() string {
 func (c *PeerStatusServeCmd) Default() {
 	c.Timeout = 10 * time.Second
 	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
+	c.Limits = flags.DefaultStreamLimits()
 }
 
 func (c *PeerStatusServeCmd) Run(ctx context.Context, args ...string) error {
@@ -35,6 +39,11 @@ func (c *PeerStatusServeCmd) Run(ctx context.Context, args ...string) error {
 	if err != nil {
 		return err
 	}
+	entry, err := methods.DefaultRegistry.Find("status", "1")
+	if err != nil {
+		return err
+	}
+	m := entry.Method(nil) // the status method does not depend on the spec
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 	sCtxFn := func() context.Context {
 		if c.Timeout == 0 {
@@ -58,15 +67,16 @@ func (c *PeerStatusServeCmd) Run(ctx context.Context, args ...string) error {
 			f["data"] = reqStatus
 			c.Book.RegisterStatus(peerId, reqStatus)
 
-			if err := handler.WriteResponseChunk(reqresp.SuccessCode, &c.PeerStatusState.Local); err != nil {
+			local := c.PeerStatusState.LocalStatus()
+			if err := handler.WriteResponseChunk(reqresp.SuccessCode, &local); err != nil {
 				c.Log.WithFields(f).Warnf("failed to respond to status request: %v", err)
 			} else {
 				c.Log.WithFields(f).Info("handled status request")
 			}
 		}
 	}
-	m := methods.StatusRPCv1
-	streamHandler := m.MakeStreamHandler(sCtxFn, comp, listenReq)
+	limiter := c.Limits.Limiter(bgCtx, c.Log)
+	streamHandler := m.MakeLimitedStreamHandler(sCtxFn, comp, limiter, track.ScoredRequestListener(c.Scores, listenReq))
 	prot := m.Protocol
 	if comp != nil {
 		prot += protocol.ID("_" + comp.Name())
@@ -77,6 +87,7 @@ func (c *PeerStatusServeCmd) Run(ctx context.Context, args ...string) error {
 	c.Control.RegisterStop(func(ctx context.Context) error {
 		bgCancel()
 		h.RemoveStreamHandler(prot)
+		flags.LogStreamStats(c.Log, limiter)
 		c.Log.Infof("Stopped serving status")
 		return nil
 	})
@@ -27,31 +27,31 @@ func (c *PeerStatusSetCmd) Help() string {
 }
 
 func (c *PeerStatusSetCmd) Run(ctx context.Context, args ...string) error {
-	st := beacon.Status{}
-	if c.Merge {
-		st = c.PeerStatusState.Local
-	}
-	if !c.Merge || c.ForkDigest != (beacon.ForkDigest{}) {
-		st.ForkDigest = c.ForkDigest
-	}
-	if !c.Merge || c.HeadRoot != (beacon.Root{}) {
-		st.HeadRoot = c.HeadRoot
-	}
-	if !c.Merge || c.HeadSlot != 0 {
-		st.HeadSlot = c.HeadSlot
-	}
-	if !c.Merge || c.FinalizedEpoch != 0 {
-		st.FinalizedEpoch = c.FinalizedEpoch
-	}
-	if !c.Merge || c.FinalizedRoot != (beacon.Root{}) {
-		st.FinalizedRoot = c.FinalizedRoot
-	}
-	// mutate full status at once (TODO: do we need a lock?)
-	c.PeerStatusState.Local = st
+	// mutate full status at once
+	st := c.PeerStatusState.UpdateLocalStatus(func(st *beacon.Status) {
+		if !c.Merge {
+			*st = beacon.Status{}
+		}
+		if !c.Merge || c.ForkDigest != (beacon.ForkDigest{}) {
+			st.ForkDigest = c.ForkDigest
+		}
+		if !c.Merge || c.HeadRoot != (beacon.Root{}) {
+			st.HeadRoot = c.HeadRoot
+		}
+		if !c.Merge || c.HeadSlot != 0 {
+			st.HeadSlot = c.HeadSlot
+		}
+		if !c.Merge || c.FinalizedEpoch != 0 {
+			st.FinalizedEpoch = c.FinalizedEpoch
+		}
+		if !c.Merge || c.FinalizedRoot != (beacon.Root{}) {
+			st.FinalizedRoot = c.FinalizedRoot
+		}
+	})
 
 	c.Log.WithFields(logrus.Fields{
-		"following": c.PeerStatusState.Following,
-		"status":    c.PeerStatusState.Local.Data(),
+		"following": c.PeerStatusState.IsFollowing(),
+		"status":    st.Data(),
 	}).Info("Status settings")
 	return nil
 }
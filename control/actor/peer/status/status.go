@@ -5,22 +5,62 @@ import (
 	"errors"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/protolambda/ask"
+	"github.com/protolambda/rumor/chain"
 	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
 	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
 	"github.com/protolambda/rumor/p2p/track"
 	"github.com/protolambda/zrnt/eth2/beacon"
+	"sync"
 )
 
 type PeerStatusState struct {
 	Following bool
-	Local     beacon.Status
+	// guards Following and Local, they are used in the background by 'status follow-chain',
+	// while the serve handlers and fork digest lookups read the status.
+	lock  sync.RWMutex
+	Local beacon.Status
+}
+
+// IsFollowing returns if the local status should follow the current chain
+func (c *PeerStatusState) IsFollowing() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.Following
+}
+
+// SetFollowing changes if the local status should follow the current chain, and returns the previous setting.
+func (c *PeerStatusState) SetFollowing(following bool) (prev bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	prev = c.Following
+	c.Following = following
+	return prev
+}
+
+// LocalStatus is a copy of the local status
+func (c *PeerStatusState) LocalStatus() beacon.Status {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.Local
+}
+
+// UpdateLocalStatus modifies the local status at once, and returns the updated status.
+func (c *PeerStatusState) UpdateLocalStatus(fn func(st *beacon.Status)) beacon.Status {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	fn(&c.Local)
+	return c.Local
 }
 
 type PeerStatusCmd struct {
 	*base.Base
 	*PeerStatusState
-	Book track.StatusBook
+	Book       track.StatusBook
+	Scores     track.ScoreBook
+	Chains     chain.Chains
+	ChainState *actorchain.ChainState
 }
 
 func (c *PeerStatusCmd) Help() string {
@@ -34,13 +74,20 @@ func (c *PeerStatusCmd) Cmd(route string) (cmd interface{}, err error) {
 	case "set":
 		cmd = &PeerStatusSetCmd{Base: c.Base, PeerStatusState: c.PeerStatusState}
 	case "req":
-		cmd = &PeerStatusReqCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Book}
+		cmd = &PeerStatusReqCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Book, Scores: c.Scores}
 	case "poll":
-		cmd = &PeerStatusPollCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Book}
+		cmd = &PeerStatusPollCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Book, Scores: c.Scores,
+			Chains: c.Chains, ChainState: c.ChainState}
+	case "check":
+		cmd = &PeerStatusCheckCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Book, Scores: c.Scores,
+			Chains: c.Chains, ChainState: c.ChainState}
 	case "serve":
-		cmd = &PeerStatusServeCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Book}
+		cmd = &PeerStatusServeCmd{Base: c.Base, PeerStatusState: c.PeerStatusState, Book: c.Book, Scores: c.Scores}
 	case "follow":
 		cmd = &PeerStatusFollowCmd{Base: c.Base, PeerStatusState: c.PeerStatusState}
+	case "follow-chain":
+		cmd = &PeerStatusFollowChainCmd{Base: c.Base, PeerStatusState: c.PeerStatusState,
+			Chains: c.Chains, ChainState: c.ChainState}
 	default:
 		return nil, ask.UnrecognizedErr
 	}
@@ -48,14 +95,15 @@ func (c *PeerStatusCmd) Cmd(route string) (cmd interface{}, err error) {
 }
 
 func (c *PeerStatusState) Routes() []string {
-	return []string{"get", "set", "req", "poll", "serve", "follow"}
+	return []string{"get", "set", "req", "poll", "check", "serve", "follow", "follow-chain"}
 }
 
-func (c *PeerStatusState) fetch(book track.StatusBook, sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression) (
+func (c *PeerStatusState) fetch(book track.StatusBook, scores track.ScoreBook, sFn reqresp.NewStreamFn, ctx context.Context, peerID peer.ID, comp reqresp.Compression) (
 	resCode reqresp.ResponseCode, errMsg string, data *beacon.Status, err error) {
 	resCode = reqresp.ServerErrCode // error by default
-	err = methods.StatusRPCv1.RunRequest(ctx, sFn, peerID, comp,
-		reqresp.RequestSSZInput{Obj: &c.Local}, 1,
+	local := c.LocalStatus()
+	err = track.ScoredRequest(scores, &methods.StatusRPCv1, ctx, sFn, peerID, comp,
+		reqresp.RequestSSZInput{Obj: &local}, 1,
 		func() error {
 			return nil
 		},
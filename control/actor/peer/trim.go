@@ -3,20 +3,33 @@ package peer
 import (
 	"context"
 	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/control/actor/peer/goodbye"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
 	"time"
 )
 
+// Scores are scaled before tagging the peers, tag values are integers, and small scores should not be truncated to 0.
+const scoreTagScale = 100
+
 type PeerTrimCmd struct {
 	*base.Base
-	Timeout time.Duration `ask:"[timeout]" help:"Timeout for trimming."`
+	Store       track.ExtendedPeerstore
+	Timeout     time.Duration         `ask:"[timeout]" help:"Timeout for trimming."`
+	MinScore    float64               `ask:"--min-score" help:"Disconnect peers with a score below this, with a bad-score goodbye."`
+	Disconnect  bool                  `changed:"min-score"`
+	Compression flags.CompressionFlag `ask:"--compression" help:"Compression of the goodbye to low-score peers. 'none' to disable, 'snappy' for streaming-snappy"`
 }
 
 func (c *PeerTrimCmd) Help() string {
-	return "Trim peers, with timeout."
+	return "Trim peers, with timeout. The peers with the lowest scores are trimmed first."
 }
 
 func (c *PeerTrimCmd) Default() {
 	c.Timeout = time.Second * 2
+	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
 }
 
 func (c *PeerTrimCmd) Run(ctx context.Context, args ...string) error {
@@ -24,7 +37,34 @@ func (c *PeerTrimCmd) Run(ctx context.Context, args ...string) error {
 	if err != nil {
 		return err
 	}
-	trimCtx, _ := context.WithTimeout(ctx, c.Timeout)
+	// The connection manager trims the peers with the lowest tag values first
+	for _, p := range h.Network().Peers() {
+		sc := c.Store.Score(p)
+		// Remove the tag of peers that are not scored (anymore), to not trim by an outdated score
+		if sc == nil || int(sc.Score*scoreTagScale) == 0 {
+			h.ConnManager().UntagPeer(p, "score")
+		} else {
+			h.ConnManager().TagPeer(p, "score", int(sc.Score*scoreTagScale))
+		}
+		if sc != nil {
+			if c.Disconnect && sc.Score < c.MinScore {
+				c.Log.WithField("peer_id", p).WithField("score", sc.Score).Info("disconnecting peer with low score")
+				byeCmd := &goodbye.PeerGoodbyeSendCmd{
+					Base:        c.Base,
+					Timeout:     c.Timeout,
+					Compression: c.Compression,
+					Reason:      flags.GoodbyeReasonFlag{Reason: methods.GoodbyeBadScore},
+					Disconnect:  true,
+					PeerID:      flags.PeerIDFlag{PeerID: p},
+				}
+				if err := byeCmd.Run(ctx); err != nil {
+					c.Log.WithField("peer_id", p).WithError(err).Warn("failed to say goodbye to low score peer")
+				}
+			}
+		}
+	}
+	trimCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
 	c.Log.Info("trimming peers")
 	h.ConnManager().TrimOpenConns(trimCtx)
 	c.Log.Info("Done trimming peers")
@@ -0,0 +1,377 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/p2p/altair"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+type RpcCompareCmd struct {
+	*base.Base
+	Forks       *ForkSource
+	Scores      track.ScoreBook
+	ForkFlags   `ask:"."`
+	Timeout     time.Duration         `ask:"--timeout" help:"Timeout for the request and response of each peer. 0 to disable"`
+	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
+	MaxChunks   uint64                `ask:"--max-chunks" help:"Max response chunk count per peer. 0 to use the default of the method"`
+	Peers       []string              `ask:"--peers" help:"Peer IDs to send the request to, comma-separated"`
+	Method      string                `ask:"<method>" help:"Method to request, e.g. 'status' or 'blocks-by-range-v2'"`
+	Data        []byte                `ask:"[request-hex]" help:"Raw uncompressed hex-encoded request data"`
+}
+
+func (c *RpcCompareCmd) Default() {
+	c.Timeout = 10 * time.Second
+	c.Compression = flags.CompressionFlag{Compression: reqresp.SnappyCompression{}}
+}
+
+func (c *RpcCompareCmd) Help() string {
+	return "Send the same request to multiple peers, and report the differences between the responses. " +
+		"Blocks are compared by slot, other response chunks by index. Duplicate chunks of a peer are reported as differences."
+}
+
+// compareChunk is the part of a response chunk that is compared between peers
+type compareChunk struct {
+	// The key to align the chunks of different peers by, and the order of the key
+	key   string
+	order uint64
+	// Compared values, by name
+	fields map[string]string
+}
+
+type compareResponse struct {
+	peer   peer.ID
+	agent  string
+	chunks []compareChunk
+	err    error
+	// Number of differences where this peer did not agree with the majority
+	disagreements int
+	// Number of chunks with the same key as an earlier chunk of this peer, e.g. a block of the same slot
+	duplicates int
+}
+
+func (c *RpcCompareCmd) Run(ctx context.Context, args ...string) error {
+	h, err := c.Host()
+	if err != nil {
+		return err
+	}
+	if len(c.Peers) < 2 {
+		return fmt.Errorf("need at least 2 peers to compare, got %d", len(c.Peers))
+	}
+	entry, ok := methods.DefaultRegistry.Get(c.Method)
+	if !ok {
+		return fmt.Errorf("unknown method: %s", c.Method)
+	}
+	if entry.ContextBytes {
+		// load the fork schedule before building the method, its codecs are sized by the spec of the chain
+		if err := c.ForkFlags.Apply(ctx, c.Forks); err != nil {
+			return err
+		}
+	}
+	m := entry.Method(c.Forks.Forks)
+	maxChunks := c.MaxChunks
+	if maxChunks == 0 {
+		maxChunks = m.DefaultResponseChunkCount
+	}
+
+	responses := make([]*compareResponse, len(c.Peers))
+	for i, p := range c.Peers {
+		id, err := peer.Decode(p)
+		if err != nil {
+			return fmt.Errorf("invalid peer ID %q: %v", p, err)
+		}
+		resp := &compareResponse{peer: id}
+		if v, err := h.Peerstore().Get(id, "AgentVersion"); err == nil {
+			resp.agent, _ = v.(string)
+		}
+		responses[i] = resp
+	}
+
+	sFn := reqresp.NewStreamFn(h.NewStream)
+	var wg sync.WaitGroup
+	for _, resp := range responses {
+		wg.Add(1)
+		go func(resp *compareResponse) {
+			defer wg.Done()
+			reqCtx := ctx
+			if c.Timeout != 0 {
+				var cancel context.CancelFunc
+				reqCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+				defer cancel()
+			}
+			resp.err = track.ScoredRequest(c.Scores, m, reqCtx, sFn, resp.peer, c.Compression.Compression,
+				reqresp.RequestBytesInput(c.Data), maxChunks,
+				func() error {
+					return nil
+				},
+				func(chunk reqresp.ChunkedResponseHandler) error {
+					ch, err := c.readChunk(entry, m, chunk)
+					if err != nil {
+						return err
+					}
+					resp.chunks = append(resp.chunks, ch)
+					return nil
+				})
+		}(resp)
+	}
+	wg.Wait()
+
+	differences := compareResponses(c.Log, responses)
+	for _, resp := range responses {
+		f := logrus.Fields{
+			"peer":          resp.peer.String(),
+			"agent":         resp.agent,
+			"chunks":        len(resp.chunks),
+			"disagreements": resp.disagreements,
+			"duplicates":    resp.duplicates,
+		}
+		if resp.err != nil {
+			f["err"] = resp.err.Error()
+			if kind, ok := reqresp.ErrKind(resp.err); ok {
+				f["error_kind"] = kind
+			}
+		}
+		c.Log.WithFields(f).Info("Peer response")
+	}
+	c.Log.WithFields(logrus.Fields{
+		"method":      entry.Route(),
+		"peers":       len(responses),
+		"differences": differences,
+		"consistent":  differences == 0,
+	}).Info("Comparison report")
+	return nil
+}
+
+// readChunk reads the values to compare of a response chunk.
+func (c *RpcCompareCmd) readChunk(entry *methods.MethodEntry, m *reqresp.RPCMethod, chunk reqresp.ChunkedResponseHandler) (compareChunk, error) {
+	index := chunk.ChunkIndex()
+	out := compareChunk{
+		key:    fmt.Sprintf("chunk_%d", index),
+		order:  index,
+		fields: map[string]string{"result": fmt.Sprintf("%d", chunk.ResultCode())},
+	}
+	if chunk.ResultCode() != reqresp.SuccessCode {
+		msg, err := chunk.ReadErrMsg()
+		if err != nil {
+			return out, err
+		}
+		out.fields["msg"] = msg
+		return out, nil
+	}
+	if ctxBytes := chunk.ContextBytes(); len(ctxBytes) > 0 {
+		out.fields["context_bytes"] = hex.EncodeToString(ctxBytes)
+	}
+	data, err := chunk.ReadRaw()
+	if err != nil {
+		return out, err
+	}
+	if entry.Name == "blocks-by-range" || entry.Name == "blocks-by-root" {
+		// blocks are aligned by slot, as peers may skip different slots
+		block, err := c.decodeBlock(chunk.ContextBytes(), data)
+		if err != nil {
+			return out, err
+		}
+		out.key = fmt.Sprintf("slot_%d", block.slot)
+		out.order = uint64(block.slot)
+		out.fields["root"] = block.root.String()
+		out.fields["parent_root"] = block.parentRoot.String()
+		out.fields["state_root"] = block.stateRoot.String()
+		out.fields["proposer_index"] = fmt.Sprintf("%d", block.proposerIndex)
+		return out, nil
+	}
+	cod, err := m.ChunkCodec(chunk.ContextBytes())
+	if err != nil {
+		return out, err
+	}
+	obj := cod.Alloc()
+	if err := cod.Decode(bytes.NewReader(data), uint64(len(data)), obj); err != nil {
+		return out, err
+	}
+	// compare the top-level fields of the JSON representation, e.g. the head root of a status
+	enc, err := json.Marshal(obj)
+	if err != nil {
+		return out, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(enc, &fields); err != nil {
+		out.fields["value"] = string(enc)
+		return out, nil
+	}
+	for k, v := range fields {
+		out.fields[k] = string(v)
+	}
+	return out, nil
+}
+
+// compareBlock is the part of a block of any fork that is compared between peers
+type compareBlock struct {
+	slot          beacon.Slot
+	proposerIndex beacon.ValidatorIndex
+	parentRoot    beacon.Root
+	stateRoot     beacon.Root
+	root          beacon.Root
+}
+
+// decodeBlock decodes a block with the type of the fork of the context bytes, or as phase0 block without context bytes.
+func (c *RpcCompareCmd) decodeBlock(contextBytes []byte, data []byte) (*compareBlock, error) {
+	spec := c.Forks.Forks.Spec
+	forkName := "phase0"
+	if len(contextBytes) > 0 {
+		fork, err := c.Forks.Forks.ByDigest(contextBytes)
+		if err != nil {
+			return nil, err
+		}
+		forkName = fork.Name
+	}
+	dr := codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data)))
+	switch forkName {
+	case "phase0":
+		var block beacon.SignedBeaconBlock
+		if err := block.Deserialize(spec, dr); err != nil {
+			return nil, err
+		}
+		return &compareBlock{
+			slot:          block.Message.Slot,
+			proposerIndex: block.Message.ProposerIndex,
+			parentRoot:    block.Message.ParentRoot,
+			stateRoot:     block.Message.StateRoot,
+			root:          block.Message.HashTreeRoot(spec, tree.GetHashFn()),
+		}, nil
+	case "altair":
+		var block altair.SignedBeaconBlock
+		if err := block.Deserialize(spec, dr); err != nil {
+			return nil, err
+		}
+		return &compareBlock{
+			slot:          block.Message.Slot,
+			proposerIndex: block.Message.ProposerIndex,
+			parentRoot:    block.Message.ParentRoot,
+			stateRoot:     block.Message.StateRoot,
+			root:          block.Message.HashTreeRoot(spec, tree.GetHashFn()),
+		}, nil
+	default:
+		return nil, fmt.Errorf("cannot compare blocks of fork %s", forkName)
+	}
+}
+
+// compareResponses logs every chunk and field that the peers do not agree on, with the value of each peer,
+// and counts the disagreements of the peers that differ from the most common value. Returns the number of differences.
+func compareResponses(log logrus.FieldLogger, responses []*compareResponse) (differences int) {
+	// the chunks of each peer by key, and all keys in order
+	chunks := make([]map[string]*compareChunk, len(responses))
+	order := make(map[string]uint64)
+	for i, resp := range responses {
+		chunks[i] = make(map[string]*compareChunk, len(resp.chunks))
+		for j := range resp.chunks {
+			ch := &resp.chunks[j]
+			if _, seen := chunks[i][ch.key]; seen {
+				// Only the first chunk is compared, the duplicate itself is a difference
+				resp.duplicates += 1
+				resp.disagreements += 1
+				differences += 1
+				log.WithFields(logrus.Fields{
+					"key":         ch.key,
+					"peer":        resp.peer.String(),
+					"chunk_index": j,
+				}).Warn("Peer sent a duplicate chunk")
+				continue
+			}
+			chunks[i][ch.key] = ch
+			order[ch.key] = ch.order
+		}
+	}
+	keys := make([]string, 0, len(order))
+	for k := range order {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return order[keys[i]] < order[keys[j]]
+	})
+
+	// report compares the values of the peers, and logs them if they differ
+	report := func(key string, field string, peers []*compareResponse, values []string) {
+		counts := make(map[string]int)
+		for _, v := range values {
+			counts[v] += 1
+		}
+		if len(counts) == 1 {
+			return
+		}
+		differences += 1
+		// ties are broken by the order of the peers
+		majority, majorityCount := "", 0
+		for _, v := range values {
+			if counts[v] > majorityCount {
+				majority, majorityCount = v, counts[v]
+			}
+		}
+		perPeer := make(map[string]string, len(values))
+		for i, v := range values {
+			perPeer[peers[i].peer.String()] = v
+			if v != majority {
+				peers[i].disagreements += 1
+			}
+		}
+		log.WithFields(logrus.Fields{
+			"key":      key,
+			"field":    field,
+			"majority": majority,
+			"values":   perPeer,
+		}).Warn("Peers disagree")
+	}
+
+	for _, key := range keys {
+		// A missing chunk is reported once, the fields of the chunk are compared between the peers that have it.
+		presence := make([]string, len(responses))
+		fieldSet := make(map[string]struct{})
+		for i := range responses {
+			ch, ok := chunks[i][key]
+			if !ok {
+				presence[i] = "missing"
+				continue
+			}
+			presence[i] = "present"
+			for name := range ch.fields {
+				fieldSet[name] = struct{}{}
+			}
+		}
+		report(key, "chunk", responses, presence)
+
+		names := make([]string, 0, len(fieldSet))
+		for name := range fieldSet {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var have []*compareResponse
+			var values []string
+			for i, resp := range responses {
+				if ch, ok := chunks[i][key]; ok {
+					v, ok := ch.fields[name]
+					if !ok {
+						v = "<none>"
+					}
+					have = append(have, resp)
+					values = append(values, v)
+				}
+			}
+			report(key, name, have, values)
+		}
+	}
+	return differences
+}
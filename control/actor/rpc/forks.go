@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/protolambda/rumor/chain"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// ForkSource is the fork schedule that RPC methods with context bytes use.
+// The schedule is derived from the current chain if there is one, when it is first needed,
+// otherwise the genesis validators root has to be configured with ForkFlags before use.
+type ForkSource struct {
+	chains chain.Chains
+	state  *actorchain.ChainState
+	loaded bool
+	// Mainnet schedule until loaded. Methods keep a reference to it, loading completes it in place.
+	Forks     *methods.ForkSchedule
+	FromChain bool
+}
+
+func newForkSource(chains chain.Chains, state *actorchain.ChainState) *ForkSource {
+	return &ForkSource{chains: chains, state: state, Forks: &methods.ForkSchedule{Spec: configs.Mainnet}}
+}
+
+// Load builds the fork schedule of the current chain, if there is any. It only loads the chain once.
+func (src *ForkSource) Load(ctx context.Context) error {
+	if src.loaded {
+		return nil
+	}
+	if src.chains == nil || src.state == nil {
+		src.loaded = true
+		return nil
+	}
+	ch, ok := src.chains.Find(src.state.CurrentChain)
+	if !ok {
+		src.loaded = true
+		return nil
+	}
+	head, err := ch.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get chain head: %v", err)
+	}
+	epc, err := head.EpochsContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain head epochs context: %v", err)
+	}
+	forks, err := methods.HeadForkSchedule(ctx, epc.Spec, ch)
+	if err != nil {
+		return err
+	}
+	*src.Forks = *forks
+	src.FromChain = true
+	src.loaded = true
+	return nil
+}
+
+// ForkFlags configures the fork schedule, when there is no current chain to derive it from
+type ForkFlags struct {
+	GenesisValidatorsRoot beacon.Root `ask:"--genesis-validators-root" help:"Genesis validators root of the network, to compute the fork digests of context bytes with. Required if there is no current chain, overrides the chain otherwise."`
+	RootSet               bool        `changed:"genesis-validators-root"`
+}
+
+// Apply loads the fork schedule of the source, completes it with the flags,
+// and errors if the genesis validators root is not known.
+func (f *ForkFlags) Apply(ctx context.Context, src *ForkSource) error {
+	if err := src.Load(ctx); err != nil {
+		return err
+	}
+	if f.RootSet {
+		src.Forks.GenesisValidatorsRoot = f.GenesisValidatorsRoot
+		return nil
+	}
+	if !src.FromChain {
+		return errors.New("no current chain to compute fork digests with, specify --genesis-validators-root")
+	}
+	return nil
+}
@@ -3,11 +3,14 @@ package rpc
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/protocol"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/rpc/rules"
+	"github.com/protolambda/rumor/p2p/track"
 	"github.com/sirupsen/logrus"
 	"time"
 )
@@ -20,6 +23,7 @@ type RpcMethodListenCmd struct {
 	Raw         bool                  `ask:"--raw" help:"Do not decode the request, look at raw bytes"`
 	Drop        bool                  `ask:"--drop" help:"Drop the requests, do not queue for a response."`
 	Read        bool                  `ask:"--read" help:"Read the contents of the request."`
+	Rules       string                `ask:"--rules" help:"JSON or YAML file with rules to automatically respond to matching requests. Other requests are handled as usual."`
 }
 
 func (c *RpcMethodListenCmd) Help() string {
@@ -31,6 +35,13 @@ func (c *RpcMethodListenCmd) Run(ctx context.Context, args ...string) error {
 	if err != nil {
 		return err
 	}
+	var ruleSet *rules.RuleSet
+	if c.Rules != "" {
+		ruleSet, err = rules.Load(c.Rules)
+		if err != nil {
+			return fmt.Errorf("failed to load rules: %v", err)
+		}
+	}
 	prot := c.Method.Protocol
 	if c.Compression.Compression != nil {
 		prot += protocol.ID("_" + c.Compression.Compression.Name())
@@ -57,6 +68,9 @@ func (c *RpcMethodListenCmd) Run(ctx context.Context, args ...string) error {
 				bytez, err := handler.RawRequest()
 				if err != nil {
 					req["input_err"] = err.Error()
+					if kind, ok := reqresp.ErrKind(err); ok {
+						req["input_err_kind"] = kind
+					}
 				} else {
 					req["data"] = hex.EncodeToString(bytez)
 				}
@@ -65,6 +79,9 @@ func (c *RpcMethodListenCmd) Run(ctx context.Context, args ...string) error {
 				err := handler.ReadRequest(reqObj)
 				if err != nil {
 					req["input_err"] = err.Error()
+					if kind, ok := reqresp.ErrKind(err); ok {
+						req["input_err_kind"] = kind
+					}
 				} else {
 					req["data"] = reqObj
 				}
@@ -97,7 +114,32 @@ func (c *RpcMethodListenCmd) Run(ctx context.Context, args ...string) error {
 			}
 		}
 	}
-	streamHandler := c.Method.MakeStreamHandler(sCtxFn, c.Compression.Compression, listenReq)
+	if ruleSet != nil {
+		queueReq := listenReq
+		listenReq = func(ctx context.Context, peerId peer.ID, handler reqresp.ChunkedRequestHandler) {
+			req := rules.ReadRequest(c.Method, handler)
+			rule := ruleSet.Find(c.Entry, peerId, req)
+			if rule == nil {
+				queueReq(ctx, peerId, req)
+				return
+			}
+			log := c.Log.WithFields(logrus.Fields{
+				"from":     peerId.String(),
+				"protocol": prot,
+				"rule":     rule.Name,
+				"data":     hex.EncodeToString(req.Data),
+			})
+			if req.Err != nil {
+				log = log.WithField("input_err", req.Err.Error())
+			}
+			if err := rule.Apply(ctx, req, c.Compression.Compression); err != nil {
+				log.WithError(err).Warn("Failed to apply rule to request")
+			} else {
+				log.Info("Applied rule to request")
+			}
+		}
+	}
+	streamHandler := c.Method.MakeStreamHandler(sCtxFn, c.Compression.Compression, track.ScoredRequestListener(c.Scores, listenReq))
 	h.SetStreamHandler(prot, streamHandler)
 	c.Log.Infof("Opened listener")
 
@@ -5,7 +5,11 @@ import (
 	"github.com/protolambda/ask"
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/control/actor/peer/metadata"
+	"github.com/protolambda/rumor/control/actor/peer/status"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
 	"strconv"
 	"time"
 )
@@ -14,6 +18,15 @@ type RpcMethodData struct {
 	Name      string
 	Responder *Responder
 	Method    *reqresp.RPCMethod
+	// The registry entry of the method, to build typed requests
+	Entry *methods.MethodEntry
+	// The fork schedule of the method, for context bytes
+	Forks *ForkSource
+	// Local status and metadata, to build typed requests from. May be nil.
+	PeerStatusState   *status.PeerStatusState
+	PeerMetadataState *metadata.PeerMetadataState
+	// Peers are scored by the outcome of requests. May be nil.
+	Scores track.ScoreBook
 }
 
 func (c *RpcMethodData) checkAndGetReq(reqKeyStr string) (key RequestKey, req *RequestEntry, err error) {
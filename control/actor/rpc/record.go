@@ -0,0 +1,71 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/p2p/custom"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/recording"
+	"os"
+)
+
+type RpcRecordCmd struct {
+	*base.Base
+	Forks     *ForkSource
+	ForkFlags `ask:"."`
+	Dest      string `ask:"--dest" help:"Path of the file to write the recorded RPC streams to"`
+	Format    string `ask:"--format" help:"Recording format: jsonl or binary. Defaults to binary for .bin and .gob files, jsonl otherwise. JSON lines are appended, a binary recording replaces the file."`
+}
+
+func (c *RpcRecordCmd) Help() string {
+	return "Record all RPC streams the host opens or accepts, of all RPC methods: the request, every response chunk, and how the stream ended. Inspect the recording with 'tool rpc-inspect'."
+}
+
+func (c *RpcRecordCmd) Run(ctx context.Context, args ...string) error {
+	h, err := c.Host()
+	if err != nil {
+		return err
+	}
+	ih, ok := h.(custom.Interceptable)
+	if !ok {
+		return fmt.Errorf("host does not support recording streams")
+	}
+	if c.Dest == "" {
+		return fmt.Errorf("no destination file, set --dest")
+	}
+	// Streams of all methods are recorded, including those with context bytes
+	if err := c.ForkFlags.Apply(ctx, c.Forks); err != nil {
+		return err
+	}
+	format := c.Format
+	if format == "" {
+		format = recording.FormatOf(c.Dest)
+	}
+	flag := os.O_APPEND | os.O_CREATE | os.O_WRONLY
+	if format == recording.FormatBinary {
+		// a binary recording is a single stream of events, it cannot be appended to.
+		flag = os.O_TRUNC | os.O_CREATE | os.O_WRONLY
+	}
+	f, err := os.OpenFile(c.Dest, flag, 0644)
+	if err != nil {
+		return err
+	}
+	w, err := recording.NewEventWriter(f, format)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	rec := recording.NewRecorder(w, methods.DefaultRegistry, c.Forks.Forks)
+	ih.AddStreamInterceptor(rec)
+	c.Log.WithField("dest", c.Dest).WithField("format", format).Info("Recording RPC streams")
+	c.Control.RegisterStop(func(ctx context.Context) error {
+		ih.RmStreamInterceptor(rec)
+		if err := rec.Err(); err != nil {
+			c.Log.WithError(err).Error("Failed to write recording")
+		}
+		c.Log.Info("Stopped recording RPC streams")
+		return f.Close()
+	})
+	return nil
+}
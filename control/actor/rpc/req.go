@@ -9,6 +9,7 @@ import (
 	"github.com/protolambda/rumor/control/actor/base"
 	"github.com/protolambda/rumor/control/actor/flags"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/rumor/p2p/track"
 	"github.com/sirupsen/logrus"
 	"time"
 )
@@ -23,15 +24,23 @@ func (c *RpcMethodReqCmd) Help() string {
 }
 
 func (c *RpcMethodReqCmd) Cmd(route string) (cmd interface{}, err error) {
+	opts := ReqOpts{
+		Timeout:     10 * time.Second,
+		Compression: flags.CompressionFlag{Compression: reqresp.SnappyCompression{}},
+		MaxChunks:   c.Method.DefaultResponseChunkCount,
+		Raw:         false,
+	}
 	switch route {
 	case "raw":
 		cmd = &RpcMethodReqRawCmd{
 			Base:          c.Base,
 			RpcMethodData: c.RpcMethodData,
-			Timeout:       10 * time.Second,
-			Compression:   flags.CompressionFlag{Compression: reqresp.SnappyCompression{}},
-			MaxChunks:     c.Method.DefaultResponseChunkCount,
-			Raw:           false,
+			ReqOpts:       opts,
+		}
+	case "typed":
+		cmd = c.typedCmd(opts)
+		if cmd == nil {
+			return nil, ask.UnrecognizedErr
 		}
 	default:
 		return nil, ask.UnrecognizedErr
@@ -39,20 +48,31 @@ func (c *RpcMethodReqCmd) Cmd(route string) (cmd interface{}, err error) {
 	return cmd, nil
 }
 
-// TODO: it may make sense to just collapse this command route, and only support raw requests.
 func (c *RpcMethodReqCmd) Routes() []string {
+	if c.typedCmd(ReqOpts{}) != nil {
+		return []string{"raw", "typed"}
+	}
 	return []string{"raw"}
 }
 
-type RpcMethodReqRawCmd struct {
-	*base.Base
-	*RpcMethodData
+// ReqOpts are the options of a request, shared by raw and typed requests
+type ReqOpts struct {
 	Timeout     time.Duration         `ask:"--timeout" help:"Timeout for full request and response. 0 to disable"`
 	Compression flags.CompressionFlag `ask:"--compression" help:"Compression. 'none' to disable, 'snappy' for streaming-snappy"`
 	MaxChunks   uint64                `ask:"--max-chunks" help:"Max response chunk count, if 0, do not wait for a response at all."`
 	Raw         bool                  `ask:"--raw" help:"If chunks should be logged as raw hex-encoded byte strings"`
 	PeerID      flags.PeerIDFlag      `ask:"<peer-id>" help:"libp2p Peer-ID to request"`
-	Data        []byte                `ask:"<data>" help:"Raw uncompressed hex-encoded request data"`
+	// Typed requests may adjust the max chunk count to the request, if it is not set explicitly
+	MaxChunksChanged bool `changed:"max-chunks"`
+	// Only used by methods with context bytes
+	Forks ForkFlags `ask:"."`
+}
+
+type RpcMethodReqRawCmd struct {
+	*base.Base
+	*RpcMethodData
+	ReqOpts `ask:"."`
+	Data    []byte `ask:"<data>" help:"Raw uncompressed hex-encoded request data"`
 }
 
 func (c *RpcMethodReqRawCmd) Help() string {
@@ -60,42 +80,52 @@ func (c *RpcMethodReqRawCmd) Help() string {
 }
 
 func (c *RpcMethodReqRawCmd) Run(ctx context.Context, args ...string) error {
-	h, err := c.Host()
+	return c.RpcMethodData.request(ctx, c.Base, &c.ReqOpts, reqresp.RequestBytesInput(c.Data))
+}
+
+// request makes the request in the background, and logs the response chunks and the result.
+func (c *RpcMethodData) request(ctx context.Context, b *base.Base, opts *ReqOpts, input reqresp.RequestInput) error {
+	h, err := b.Host()
 	if err != nil {
 		return err
 	}
 	sFn := reqresp.NewStreamFn(h.NewStream)
+	if c.Method.ContextBytesLen > 0 {
+		if err := opts.Forks.Apply(ctx, c.Forks); err != nil {
+			return err
+		}
+	}
 
 	reqCtx := ctx
-	if c.Timeout != 0 {
-		reqCtx, _ = context.WithTimeout(reqCtx, c.Timeout)
+	if opts.Timeout != 0 {
+		reqCtx, _ = context.WithTimeout(reqCtx, opts.Timeout)
 	}
 
 	protocolId := c.Method.Protocol
-	if c.Compression.Compression != nil {
-		protocolId += protocol.ID("_" + c.Compression.Compression.Name())
+	if opts.Compression.Compression != nil {
+		protocolId += protocol.ID("_" + opts.Compression.Compression.Name())
 	}
 
 	go func() {
-		reqErr := c.Method.RunRequest(reqCtx, sFn, c.PeerID.PeerID, c.Compression.Compression,
-			reqresp.RequestBytesInput(c.Data), c.MaxChunks,
+		reqErr := track.ScoredRequest(c.Scores, c.Method, reqCtx, sFn, opts.PeerID.PeerID, opts.Compression.Compression,
+			input, opts.MaxChunks,
 			func() error {
-				return c.Control.Step(func(ctx context.Context) error {
-					c.Log.Debug("made request")
+				return b.Control.Step(func(ctx context.Context) error {
+					b.Log.Debug("made request")
 					return nil
 				})
 			},
 			func(chunk reqresp.ChunkedResponseHandler) error {
-				return c.Control.Step(func(ctx context.Context) error {
+				return b.Control.Step(func(ctx context.Context) error {
 					resultCode := chunk.ResultCode()
 					f := logrus.Fields{
 						"protocol":    protocolId,
-						"from":        c.PeerID.PeerID.String(),
+						"from":        opts.PeerID.PeerID.String(),
 						"chunk_index": chunk.ChunkIndex(),
 						"chunk_size":  chunk.ChunkSize(),
 						"result_code": resultCode,
 					}
-					if c.Raw {
+					if opts.Raw {
 						bytez, err := chunk.ReadRaw()
 						if err != nil {
 							return err
@@ -110,11 +140,17 @@ func (c *RpcMethodReqRawCmd) Run(ctx context.Context, args ...string) error {
 							}
 							f["msg"] = msg
 						case reqresp.SuccessCode:
-							data := c.Method.ResponseChunkCodec.Alloc()
+							data, err := chunk.AllocObj()
+							if err != nil {
+								return err
+							}
 							if err := chunk.ReadObj(data); err != nil {
 								return err
 							}
 							f["data"] = data
+							if ctxBytes := chunk.ContextBytes(); len(ctxBytes) > 0 {
+								f["context_bytes"] = hex.EncodeToString(ctxBytes)
+							}
 						default:
 							bytez, err := chunk.ReadRaw()
 							if err != nil {
@@ -123,7 +159,7 @@ func (c *RpcMethodReqRawCmd) Run(ctx context.Context, args ...string) error {
 							f["data"] = hex.EncodeToString(bytez)
 						}
 					}
-					c.Log.WithFields(f).Info("Received chunk")
+					b.Log.WithFields(f).Info("Received chunk")
 					if resultCode == reqresp.SuccessCode {
 						return nil
 					} else {
@@ -132,13 +168,17 @@ func (c *RpcMethodReqRawCmd) Run(ctx context.Context, args ...string) error {
 				})
 			})
 		if reqErr != nil {
-			c.Log.WithError(reqErr).Error("failed to make request")
+			l := b.Log.WithError(reqErr)
+			if kind, ok := reqresp.ErrKind(reqErr); ok {
+				l = l.WithField("error_kind", kind)
+			}
+			l.Error("failed to make request")
 		} else {
-			c.Log.Infof("Completed request")
+			b.Log.Infof("Completed request")
 		}
 	}()
 
-	c.Control.RegisterStop(func(ctx context.Context) error {
+	b.Control.RegisterStop(func(ctx context.Context) error {
 		return nil
 	})
 	return nil
@@ -1,40 +1,57 @@
 package rpc
 
 import (
+	"context"
 	"github.com/protolambda/ask"
+	"github.com/protolambda/rumor/chain"
 	"github.com/protolambda/rumor/control/actor/base"
+	actorchain "github.com/protolambda/rumor/control/actor/chain"
+	"github.com/protolambda/rumor/control/actor/peer/metadata"
+	"github.com/protolambda/rumor/control/actor/peer/status"
 	"github.com/protolambda/rumor/p2p/rpc/methods"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
-	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/rumor/p2p/track"
 )
 
 type RpcCmd struct {
 	*base.Base
 	*RPCState
+	PeerStatusState   *status.PeerStatusState
+	PeerMetadataState *metadata.PeerMetadataState
+	// Peers are scored by the outcome of requests. May be nil.
+	Scores track.ScoreBook
+	// The fork schedule is derived from the current chain, if any
+	Chains     chain.Chains
+	ChainState *actorchain.ChainState
 }
 
 func (c *RpcCmd) Cmd(route string) (cmd interface{}, err error) {
+	// The fork schedule is only loaded from the chain by the methods with context bytes
+	forks := newForkSource(c.Chains, c.ChainState)
 	switch route {
-	case "goodbye":
-		cmd = c.Method("goodbye", &c.RPCState.Goodbye, &methods.GoodbyeRPCv1)
-	case "status":
-		cmd = c.Method("status", &c.RPCState.Status, &methods.StatusRPCv1)
-	case "ping":
-		cmd = c.Method("ping", &c.RPCState.Ping, &methods.PingRPCv1)
-	case "metadata":
-		cmd = c.Method("metadata", &c.RPCState.Metadata, &methods.MetaDataRPCv1)
-	case "blocks-by-range":
-		cmd = c.Method("blocks-by-range", &c.RPCState.BlocksByRange, methods.BlocksByRangeRPCv1(configs.Mainnet)) // TODO configure spec
-	case "blocks-by-root":
-		cmd = c.Method("blocks-by-root", &c.RPCState.BlocksByRoot, methods.BlocksByRootRPCv1(configs.Mainnet))
-	default:
+	case "record":
+		return &RpcRecordCmd{Base: c.Base, Forks: forks}, nil
+	case "compare":
+		return &RpcCompareCmd{Base: c.Base, Forks: forks, Scores: c.Scores}, nil
+	}
+	entry, ok := methods.DefaultRegistry.Get(route)
+	if !ok {
 		return nil, ask.UnrecognizedErr
 	}
-	return cmd, nil
+	if entry.ContextBytes {
+		// The codecs of the method are sized by the spec of the chain
+		if err := forks.Load(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	methodCmd := c.Method(route, c.RPCState.Responder(route), entry.Method(forks.Forks))
+	methodCmd.Entry = entry
+	methodCmd.Forks = forks
+	return methodCmd, nil
 }
 
 func (c *RpcCmd) Routes() []string {
-	return []string{"goodbye", "status", "ping", "metadata", "blocks-by-range", "blocks-by-root"}
+	return append(methods.DefaultRegistry.Routes(), "record", "compare")
 }
 
 func (c *RpcCmd) Help() string {
@@ -45,8 +62,11 @@ func (c *RpcCmd) Method(name string, resp *Responder, method *reqresp.RPCMethod)
 	return &RpcMethodCmd{
 		Base: c.Base,
 		RpcMethodData: &RpcMethodData{
-			Name:      name,
-			Responder: resp,
-			Method:    method,
+			Name:              name,
+			Responder:         resp,
+			Method:            method,
+			PeerStatusState:   c.PeerStatusState,
+			PeerMetadataState: c.PeerMetadataState,
+			Scores:            c.Scores,
 		}}
 }
@@ -7,12 +7,24 @@ import (
 )
 
 type RPCState struct {
-	Goodbye       Responder
-	Status        Responder
-	Ping          Responder
-	Metadata      Responder
-	BlocksByRange Responder
-	BlocksByRoot  Responder
+	lock sync.Mutex
+	// method route -> responder, see methods.MethodEntry.Route
+	responders map[string]*Responder
+}
+
+// Responder gets the responder of the RPC method with the given route, and creates it if it does not exist yet.
+func (s *RPCState) Responder(route string) *Responder {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.responders == nil {
+		s.responders = make(map[string]*Responder)
+	}
+	r, ok := s.responders[route]
+	if !ok {
+		r = new(Responder)
+		s.responders[route] = r
+	}
+	return r
 }
 
 type RequestKey uint64
@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/protolambda/rumor/control/actor/base"
+	"github.com/protolambda/rumor/control/actor/flags"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/ztyp/view"
+)
+
+// typedCmd builds the typed request command of the method, or returns nil if the method has no typed requests.
+func (c *RpcMethodReqCmd) typedCmd(opts ReqOpts) interface{} {
+	if c.Entry == nil {
+		return nil
+	}
+	switch c.Entry.Name {
+	case "goodbye":
+		return &RpcReqGoodbyeCmd{Base: c.Base, RpcMethodData: c.RpcMethodData, ReqOpts: opts,
+			Reason: flags.GoodbyeReasonFlag{Reason: methods.GoodbyeClientShutdown}}
+	case "status":
+		return &RpcReqStatusCmd{Base: c.Base, RpcMethodData: c.RpcMethodData, ReqOpts: opts}
+	case "ping":
+		cmd := &RpcReqPingCmd{Base: c.Base, RpcMethodData: c.RpcMethodData, ReqOpts: opts}
+		if c.PeerMetadataState != nil {
+			cmd.SeqNumber = c.PeerMetadataState.LocalV1().SeqNumber
+		}
+		return cmd
+	case "metadata":
+		return &RpcReqMetadataCmd{Base: c.Base, RpcMethodData: c.RpcMethodData, ReqOpts: opts}
+	case "blocks-by-range":
+		return &RpcReqBlocksByRangeCmd{Base: c.Base, RpcMethodData: c.RpcMethodData, ReqOpts: opts, Step: 1}
+	case "blocks-by-root":
+		return &RpcReqBlocksByRootCmd{Base: c.Base, RpcMethodData: c.RpcMethodData, ReqOpts: opts}
+	default:
+		return nil
+	}
+}
+
+type RpcReqGoodbyeCmd struct {
+	*base.Base
+	*RpcMethodData
+	ReqOpts `ask:"."`
+	Reason  flags.GoodbyeReasonFlag `ask:"--reason" help:"Goodbye reason: a name like client-shutdown, irrelevant-network, fault-error, too-many-peers, or a number"`
+}
+
+func (c *RpcReqGoodbyeCmd) Help() string {
+	return "Say goodbye to a peer, with the given reason"
+}
+
+func (c *RpcReqGoodbyeCmd) Run(ctx context.Context, args ...string) error {
+	return c.RpcMethodData.request(ctx, c.Base, &c.ReqOpts, reqresp.RequestSSZInput{Obj: c.Reason.Reason})
+}
+
+type RpcReqStatusCmd struct {
+	*base.Base
+	*RpcMethodData
+	ReqOpts `ask:"."`
+}
+
+func (c *RpcReqStatusCmd) Help() string {
+	return "Request the status of a peer, sending the local status, see 'peer status set'"
+}
+
+func (c *RpcReqStatusCmd) Run(ctx context.Context, args ...string) error {
+	if c.PeerStatusState == nil {
+		return errors.New("no local status available")
+	}
+	local := c.PeerStatusState.LocalStatus()
+	return c.RpcMethodData.request(ctx, c.Base, &c.ReqOpts, reqresp.RequestSSZInput{Obj: &local})
+}
+
+type RpcReqPingCmd struct {
+	*base.Base
+	*RpcMethodData
+	ReqOpts   `ask:"."`
+	SeqNumber beacon.SeqNr `ask:"--seq-number" help:"Metadata sequence number to ping with. Defaults to the local metadata sequence number."`
+}
+
+func (c *RpcReqPingCmd) Help() string {
+	return "Ping a peer with a metadata sequence number"
+}
+
+func (c *RpcReqPingCmd) Run(ctx context.Context, args ...string) error {
+	ping := beacon.Ping(c.SeqNumber)
+	return c.RpcMethodData.request(ctx, c.Base, &c.ReqOpts, reqresp.RequestSSZInput{Obj: ping})
+}
+
+type RpcReqMetadataCmd struct {
+	*base.Base
+	*RpcMethodData
+	ReqOpts `ask:"."`
+}
+
+func (c *RpcReqMetadataCmd) Help() string {
+	return "Request the metadata of a peer"
+}
+
+func (c *RpcReqMetadataCmd) Run(ctx context.Context, args ...string) error {
+	return c.RpcMethodData.request(ctx, c.Base, &c.ReqOpts, reqresp.RequestSSZInput{Obj: nil})
+}
+
+type RpcReqBlocksByRangeCmd struct {
+	*base.Base
+	*RpcMethodData
+	ReqOpts   `ask:"."`
+	StartSlot beacon.Slot `ask:"--start" help:"Start slot of request"`
+	Count     uint64      `ask:"--count" help:"Count of blocks of request"`
+	Step      uint64      `ask:"--step" help:"Step between slots of blocks of request"`
+}
+
+func (c *RpcReqBlocksByRangeCmd) Help() string {
+	return "Request a range of blocks. Unless --max-chunks is set, up to --count blocks are accepted."
+}
+
+func (c *RpcReqBlocksByRangeCmd) Run(ctx context.Context, args ...string) error {
+	if c.Step == 0 {
+		return errors.New("step must not be 0")
+	}
+	req := methods.BlocksByRangeReqV1{
+		StartSlot: c.StartSlot,
+		Count:     view.Uint64View(c.Count),
+		Step:      view.Uint64View(c.Step),
+	}
+	if !c.MaxChunksChanged {
+		c.MaxChunks = c.Count
+	}
+	return c.RpcMethodData.request(ctx, c.Base, &c.ReqOpts, reqresp.RequestSSZInput{Obj: &req})
+}
+
+type RpcReqBlocksByRootCmd struct {
+	*base.Base
+	*RpcMethodData
+	ReqOpts `ask:"."`
+	Roots   []beacon.Root `ask:"--roots" help:"Block roots to request"`
+}
+
+func (c *RpcReqBlocksByRootCmd) Help() string {
+	return "Request blocks by root. Unless --max-chunks is set, a block per root is accepted."
+}
+
+func (c *RpcReqBlocksByRootCmd) Run(ctx context.Context, args ...string) error {
+	req := methods.BlocksByRootReq(c.Roots)
+	if len(req) > methods.MAX_REQUEST_BLOCKS_BY_ROOT {
+		return fmt.Errorf("too many roots: %d, max is %d", len(req), methods.MAX_REQUEST_BLOCKS_BY_ROOT)
+	}
+	if !c.MaxChunksChanged {
+		c.MaxChunks = uint64(len(req))
+	}
+	return c.RpcMethodData.request(ctx, c.Base, &c.ReqOpts, reqresp.RequestSSZInput{Obj: &req})
+}
@@ -24,7 +24,7 @@ func (c *EnrValueGetCmd) Help() string {
 	return `Get ENR value by key.
 			Any key can be displayed as raw RLP. Unknown keys do not have a deserialized form, but can be extracted as RLP.
 
-			ENR keys: secp256k1, tcp, tcp6, udp, udp6, id, ip, ip6, eth2, attnets
+			ENR keys: secp256k1, tcp, tcp6, udp, udp6, id, ip, ip6, eth2, attnets, syncnets
 			Special keys: seq, signature, node_id, pubkey, enode, multi, peer_id
 ` // indent is intentional, displays better in help text.
 }
@@ -0,0 +1,141 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/recording"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"io"
+	"os"
+)
+
+type RpcInspectCmd struct {
+	Path                  string      `ask:"<path>" help:"Path of the recording, see 'rpc record'"`
+	Format                string      `ask:"--format" help:"Recording format: jsonl or binary. Defaults to binary for .bin and .gob files, jsonl otherwise."`
+	Decode                bool        `ask:"--decode" help:"Decode requests and response chunks with the types of their RPC method"`
+	GenesisValidatorsRoot beacon.Root `ask:"--genesis-validators-root" help:"Genesis validators root of the recorded network, to recognize the fork digests in context bytes"`
+	Out                   io.Writer
+}
+
+func (c *RpcInspectCmd) Default() {
+	c.Decode = true
+}
+
+func (c *RpcInspectCmd) Help() string {
+	return "Inspect an RPC recording, and output the events as JSON lines, with decoded requests and response chunks."
+}
+
+func (c *RpcInspectCmd) Run(ctx context.Context, args ...string) error {
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	format := c.Format
+	if format == "" {
+		format = recording.FormatOf(c.Path)
+	}
+	r, err := recording.NewEventReader(f, format)
+	if err != nil {
+		return err
+	}
+	// TODO configure spec
+	forks := &methods.ForkSchedule{Spec: configs.Mainnet, GenesisValidatorsRoot: c.GenesisValidatorsRoot}
+	enc := json.NewEncoder(c.Out)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ev, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read recording: %v", err)
+		}
+		out, err := inspectEvent(ev, forks, c.Decode)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+}
+
+func inspectEvent(ev *recording.Event, forks *methods.ForkSchedule, decode bool) (map[string]interface{}, error) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if !decode || (ev.Type != recording.StreamRequest && ev.Type != recording.StreamChunk) {
+		return out, nil
+	}
+	entry, ok := methods.DefaultRegistry.ByProtocol(ev.Protocol)
+	if !ok {
+		out["decode_err"] = "unknown protocol"
+		return out, nil
+	}
+	m := entry.Method(forks)
+	var decoded interface{}
+	if ev.Type == recording.StreamRequest {
+		decoded, err = decodeRequest(ev, m)
+	} else if ev.Result == reqresp.SuccessCode {
+		decoded, err = decodeChunk(ev, m)
+	} else {
+		decoded = string(ev.Data)
+	}
+	if err != nil {
+		out["decode_err"] = err.Error()
+		if kind, ok := reqresp.ErrKind(err); ok {
+			out["decode_err_kind"] = kind
+		}
+	} else if decoded != nil {
+		out["decoded"] = decoded
+	}
+	return out, nil
+}
+
+func decodeRequest(ev *recording.Event, m *reqresp.RPCMethod) (interface{}, error) {
+	if m.RequestCodec.MaxByteLen() == 0 {
+		return nil, nil
+	}
+	_, _, encoding, _ := reqresp.ParseProtocolID(ev.Protocol)
+	comp, err := reqresp.DefaultCompressions.ByEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	reqLen, r, err := reqresp.ReadRequestHeader(bytes.NewReader(ev.Data), comp, m.RequestCodec.MinByteLen(), m.RequestCodec.MaxByteLen())
+	if err != nil {
+		return nil, err
+	}
+	if comp != nil {
+		r = comp.Decompress(r)
+	}
+	obj := m.RequestCodec.Alloc()
+	if err := m.RequestCodec.Decode(r, reqLen, obj); err != nil {
+		return nil, &reqresp.RPCError{Kind: reqresp.DecodeErrKind, Err: err}
+	}
+	return obj, nil
+}
+
+func decodeChunk(ev *recording.Event, m *reqresp.RPCMethod) (interface{}, error) {
+	cod, err := m.ChunkCodec(ev.ContextBytes)
+	if err != nil {
+		return nil, err
+	}
+	obj := cod.Alloc()
+	if err := cod.Decode(bytes.NewReader(ev.Data), uint64(len(ev.Data)), obj); err != nil {
+		return nil, &reqresp.RPCError{Kind: reqresp.DecodeErrKind, Err: err}
+	}
+	return obj, nil
+}
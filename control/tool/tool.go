@@ -14,6 +14,8 @@ func (c *ToolCmd) Cmd(route string) (cmd interface{}, err error) {
 	switch route {
 	case "enr-value":
 		cmd = &EnrValueGetCmd{Out: c.Out}
+	case "rpc-inspect":
+		cmd = &RpcInspectCmd{Out: c.Out}
 	default:
 		return nil, ask.UnrecognizedErr
 	}
@@ -21,7 +23,7 @@ func (c *ToolCmd) Cmd(route string) (cmd interface{}, err error) {
 }
 
 func (c *ToolCmd) Routes() []string {
-	return []string{"enr-value"}
+	return []string{"enr-value", "rpc-inspect"}
 }
 
 func (c *ToolCmd) Help() string {
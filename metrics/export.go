@@ -0,0 +1,253 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/protolambda/rumor/p2p/track"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Export formats
+const (
+	// Full snapshot of the metrics as JSON, atomically replacing the previous export
+	ExportJSON = "json"
+	// Rows appended to a CSV file, one per peer and topic that changed since the last export
+	ExportCSV = "csv"
+	// Lines appended to a JSON-lines file, one per peer and topic that changed since the last export
+	ExportJSONL = "jsonl"
+)
+
+// MetricsSample is the message count of a peer on a topic at the time of an export,
+// and the increase since the previous export.
+type MetricsSample struct {
+	Time       int64  `json:"time"`
+	PeerId     string `json:"peer_id"`
+	ClientType string `json:"client_type"`
+	Country    string `json:"country"`
+	Topic      string `json:"topic"`
+	Count      int64  `json:"count"`
+	Delta      int64  `json:"delta"`
+}
+
+var sampleCSVHeader = []string{"time", "peer_id", "client_type", "country", "topic", "count", "delta"}
+
+func (s *MetricsSample) csvRecord() []string {
+	return []string{
+		strconv.FormatInt(s.Time, 10),
+		s.PeerId,
+		s.ClientType,
+		s.Country,
+		s.Topic,
+		strconv.FormatInt(s.Count, 10),
+		strconv.FormatInt(s.Delta, 10),
+	}
+}
+
+// Exporter periodically exports the gossip metrics to a file, and optionally uploads them to an HTTP endpoint.
+type Exporter struct {
+	State *GossipState
+	// Optional, to export the peerstore alongside the metrics
+	Store track.ExtendedPeerstore
+
+	Format string
+	// Path of the metrics file, may be empty to only upload
+	FilePath string
+	// Optional path of the peerstore file, always replaced with a full JSON snapshot
+	PeerstorePath string
+
+	// Optional HTTP endpoint to POST the exported metrics to, as JSON
+	UploadURL string
+	// Optional value for the Authorization header of uploads
+	UploadAuth string
+	Client     *http.Client
+
+	// peer ID -> topic -> count at the last export
+	last map[string]map[string]int64
+}
+
+// Samples collects the message counts of every peer and topic, with the deltas since the last committed counts.
+// The counts are returned as well, to commit them as the new baseline once the samples are exported.
+func (e *Exporter) Samples(now time.Time) (out []MetricsSample, counts map[string]map[string]int64) {
+	counts = make(map[string]map[string]int64)
+	e.State.GossipMetrics.Range(func(k, v interface{}) bool {
+		pm := v.(*PeerMetrics).Copy()
+		prev := e.last[pm.PeerId]
+		peerCounts := make(map[string]int64, len(pm.Topics))
+		for topic, m := range pm.Topics {
+			peerCounts[topic] = m.Cnt
+			out = append(out, MetricsSample{
+				Time:       now.UnixNano() / int64(time.Millisecond),
+				PeerId:     pm.PeerId,
+				ClientType: pm.ClientType,
+				Country:    pm.Country,
+				Topic:      topic,
+				Count:      m.Cnt,
+				Delta:      m.Cnt - prev[topic],
+			})
+		}
+		counts[pm.PeerId] = peerCounts
+		return true
+	})
+	return out, counts
+}
+
+// Commit makes the counts of the exported samples the baseline of the deltas of the next samples.
+func (e *Exporter) Commit(counts map[string]map[string]int64) {
+	if e.last == nil {
+		e.last = make(map[string]map[string]int64)
+	}
+	for peerId, peerCounts := range counts {
+		e.last[peerId] = peerCounts
+	}
+}
+
+// Export runs a single export.
+func (e *Exporter) Export(now time.Time) error {
+	var payload []byte
+	// the counts of the exported samples, committed when the export succeeds
+	var counts map[string]map[string]int64
+	switch e.Format {
+	case ExportJSON:
+		data, err := e.State.MarshalMetrics()
+		if err != nil {
+			return err
+		}
+		if e.FilePath != "" {
+			if err := writeFileAtomic(e.FilePath, data); err != nil {
+				return err
+			}
+		}
+		payload = data
+	case ExportCSV, ExportJSONL:
+		var changed, samples []MetricsSample
+		samples, counts = e.Samples(now)
+		for _, s := range samples {
+			if s.Delta != 0 {
+				changed = append(changed, s)
+			}
+		}
+		if e.FilePath != "" {
+			if err := e.appendSamples(changed); err != nil {
+				return err
+			}
+		}
+		if changed == nil {
+			changed = []MetricsSample{}
+		}
+		data, err := json.Marshal(changed)
+		if err != nil {
+			return err
+		}
+		payload = data
+	default:
+		return fmt.Errorf("unrecognized export format: %s", e.Format)
+	}
+	if e.PeerstorePath != "" && e.Store != nil {
+		data, err := e.State.MarshalPeerStore(e.Store)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(e.PeerstorePath, data); err != nil {
+			return err
+		}
+	}
+	if e.UploadURL != "" {
+		if err := e.upload(payload); err != nil {
+			return err
+		}
+	}
+	if counts != nil {
+		e.Commit(counts)
+	}
+	return nil
+}
+
+func (e *Exporter) appendSamples(samples []MetricsSample) error {
+	f, err := os.OpenFile(e.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	switch e.Format {
+	case ExportCSV:
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(f)
+		if info.Size() == 0 {
+			if err := w.Write(sampleCSVHeader); err != nil {
+				return err
+			}
+		}
+		for i := range samples {
+			if err := w.Write(samples[i].csvRecord()); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		enc := json.NewEncoder(f)
+		for i := range samples {
+			if err := enc.Encode(&samples[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (e *Exporter) upload(payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, e.UploadURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.UploadAuth != "" {
+		req.Header.Set("Authorization", e.UploadAuth)
+	}
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to upload metrics: status %s", resp.Status)
+	}
+	return nil
+}
+
+// writeFileAtomic writes the data to a temporary file next to the destination, and then renames it,
+// so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExporterDeltasAndUpload(t *testing.T) {
+	var uploads [][]MetricsSample
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var samples []MetricsSample
+		if err := json.NewDecoder(r.Body).Decode(&samples); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		uploads = append(uploads, samples)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var state GossipState
+	e := &Exporter{
+		State:      &state,
+		Format:     ExportCSV,
+		FilePath:   filepath.Join(dir, "metrics.csv"),
+		UploadURL:  srv.URL,
+		UploadAuth: "secret",
+	}
+	a, b := peer.ID("a"), peer.ID("b")
+	state.IncomingMessageManager(a, "foo")
+	state.IncomingMessageManager(a, "foo")
+	state.IncomingMessageManager(b, "bar")
+	if err := e.Export(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	state.IncomingMessageManager(a, "foo")
+	if err := e.Export(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// nothing changed
+	if err := e.Export(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uploads) != 3 {
+		t.Fatalf("expected 3 uploads, got %d", len(uploads))
+	}
+	if len(uploads[0]) != 2 {
+		t.Errorf("expected 2 samples in first upload, got %d", len(uploads[0]))
+	}
+	if len(uploads[1]) != 1 || uploads[1][0].Count != 3 || uploads[1][0].Delta != 1 {
+		t.Errorf("unexpected second upload: %+v", uploads[1])
+	}
+	if len(uploads[2]) != 0 {
+		t.Errorf("expected empty third upload, got %+v", uploads[2])
+	}
+
+	f, err := os.Open(e.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// header + 2 + 1 rows
+	if len(records) != 4 {
+		t.Fatalf("expected 4 csv records, got %d", len(records))
+	}
+	if records[3][4] != "foo" || records[3][5] != "3" || records[3][6] != "1" {
+		t.Errorf("unexpected last csv record: %v", records[3])
+	}
+}
+
+func TestExporterJSONAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var state GossipState
+	state.IncomingMessageManager(peer.ID("a"), "foo")
+	e := &Exporter{State: &state, Format: ExportJSON, FilePath: filepath.Join(dir, "metrics.json")}
+	for i := 0; i < 2; i++ {
+		if err := e.Export(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the metrics file, no temporary files, got %d files", len(files))
+	}
+	data, err := ioutil.ReadFile(e.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]*PeerMetrics
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out[peer.ID("a").String()].Topics["foo"].Cnt != 1 {
+		t.Errorf("unexpected export: %s", data)
+	}
+}
+
+func TestExporterUploadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	var state GossipState
+	e := &Exporter{State: &state, Format: ExportJSONL, UploadURL: srv.URL}
+	if err := e.Export(time.Now()); err == nil {
+		t.Fatal("expected upload error")
+	}
+}
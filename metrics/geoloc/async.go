@@ -0,0 +1,70 @@
+package geoloc
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+type lookupJob struct {
+	ip net.IP
+	fn func(loc *Location, err error)
+}
+
+// AsyncLocator runs lookups in a pool of workers, callers never wait for a lookup.
+type AsyncLocator struct {
+	inner Locator
+	jobs  chan lookupJob
+
+	lock   sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewAsyncLocator starts the given number of workers, with a queue of pending lookups of the given size.
+func NewAsyncLocator(inner Locator, workers int, queueSize int) *AsyncLocator {
+	a := &AsyncLocator{inner: inner, jobs: make(chan lookupJob, queueSize)}
+	a.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer a.wg.Done()
+			for job := range a.jobs {
+				job.fn(a.inner.Locate(job.ip))
+			}
+		}()
+	}
+	return a
+}
+
+// LocateAsync schedules a lookup, and calls fn with the result when it completes.
+// It does not block: false is returned if the lookup was dropped because the queue is full or the locator is closed.
+func (a *AsyncLocator) LocateAsync(ip net.IP, fn func(loc *Location, err error)) (queued bool) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if a.closed {
+		return false
+	}
+	select {
+	case a.jobs <- lookupJob{ip: ip, fn: fn}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting new lookups, waits for the pending lookups to complete, and then closes the inner locator.
+func (a *AsyncLocator) Close() error {
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.jobs)
+	a.lock.Unlock()
+	a.wg.Wait()
+	if cl, ok := a.inner.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
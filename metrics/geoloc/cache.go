@@ -0,0 +1,48 @@
+package geoloc
+
+import (
+	lru "github.com/hashicorp/golang-lru"
+	"io"
+	"net"
+)
+
+type cacheEntry struct {
+	loc *Location
+	err error
+}
+
+// CachedLocator caches the results of another locator, in a LRU cache.
+// Lookups that were not found are cached too, other errors are not.
+type CachedLocator struct {
+	inner Locator
+	cache *lru.Cache
+}
+
+func NewCachedLocator(inner Locator, size int) (*CachedLocator, error) {
+	c, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &CachedLocator{inner: inner, cache: c}, nil
+}
+
+func (c *CachedLocator) Locate(ip net.IP) (*Location, error) {
+	key := ip.String()
+	if v, ok := c.cache.Get(key); ok {
+		e := v.(cacheEntry)
+		return e.loc, e.err
+	}
+	loc, err := c.inner.Locate(ip)
+	if err == nil || err == NotFoundErr {
+		c.cache.Add(key, cacheEntry{loc: loc, err: err})
+	}
+	return loc, err
+}
+
+// Close closes the inner locator, if it can be closed
+func (c *CachedLocator) Close() error {
+	if cl, ok := c.inner.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package geoloc
+
+import (
+	"errors"
+	"net"
+)
+
+// Location of an IP address, fields are empty if unknown.
+type Location struct {
+	Country     string
+	CountryCode string
+	City        string
+	// Autonomous system number, 0 if unknown
+	ASN   uint
+	ASOrg string
+}
+
+// Locator looks up the location of an IP address
+type Locator interface {
+	Locate(ip net.IP) (*Location, error)
+}
+
+var NotFoundErr = errors.New("ip address not found")
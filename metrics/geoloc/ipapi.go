@@ -0,0 +1,75 @@
+package geoloc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IP-API message structure
+type IpApiMessage struct {
+	Query         string
+	Status        string
+	Message       string
+	Continent     string
+	ContinentCode string
+	Country       string
+	CountryCode   string
+	Region        string
+	RegionName    string
+	City          string
+	District      string
+	Zip           string
+	Timezone      string
+	Currency      string
+	Isp           string
+	Org           string
+	As            string
+	Asname        string
+}
+
+// IpApiLocator looks up locations with the ip-api.com HTTP API.
+// Note that the free API is limited to 40 requests per minute.
+type IpApiLocator struct {
+	Client *http.Client
+}
+
+func NewIpApiLocator() *IpApiLocator {
+	return &IpApiLocator{Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (l *IpApiLocator) Locate(ip net.IP) (*Location, error) {
+	resp, err := l.Client.Get("http://ip-api.com/json/" + ip.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get location from ip-api: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ip-api responded with status %d, the rate limit may be exceeded", resp.StatusCode)
+	}
+	var msg IpApiMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to decode ip-api response: %v", err)
+	}
+	if msg.Status != "success" {
+		return nil, fmt.Errorf("ip-api lookup failed: %s", msg.Message)
+	}
+	loc := &Location{
+		Country:     msg.Country,
+		CountryCode: msg.CountryCode,
+		City:        msg.City,
+		ASOrg:       msg.Asname,
+	}
+	// The AS field is formatted like "AS1234 Some Org"
+	if strings.HasPrefix(msg.As, "AS") {
+		asn := strings.SplitN(msg.As[2:], " ", 2)[0]
+		if v, err := strconv.ParseUint(asn, 10, 32); err == nil {
+			loc.ASN = uint(v)
+		}
+	}
+	return loc, nil
+}
@@ -0,0 +1,106 @@
+package geoloc
+
+import (
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"net"
+)
+
+type mmdbCityRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+type mmdbASNRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// MMDBLocator looks up locations in local MaxMind DB files, e.g. GeoLite2-City and GeoLite2-ASN.
+// Either database is optional.
+type MMDBLocator struct {
+	city *maxminddb.Reader
+	asn  *maxminddb.Reader
+}
+
+// OpenMMDB opens the city and ASN databases. An empty path skips the database.
+func OpenMMDB(cityPath string, asnPath string) (*MMDBLocator, error) {
+	var out MMDBLocator
+	if cityPath != "" {
+		r, err := maxminddb.Open(cityPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open city database: %v", err)
+		}
+		out.city = r
+	}
+	if asnPath != "" {
+		r, err := maxminddb.Open(asnPath)
+		if err != nil {
+			_ = out.Close()
+			return nil, fmt.Errorf("failed to open ASN database: %v", err)
+		}
+		out.asn = r
+	}
+	if out.city == nil && out.asn == nil {
+		return nil, fmt.Errorf("need at least a city or an ASN database")
+	}
+	return &out, nil
+}
+
+func (m *MMDBLocator) Locate(ip net.IP) (*Location, error) {
+	var loc Location
+	found := false
+	if m.city != nil {
+		var rec mmdbCityRecord
+		offset, err := m.city.LookupOffset(ip)
+		if err != nil {
+			return nil, err
+		}
+		if offset != maxminddb.NotFound {
+			if err := m.city.Decode(offset, &rec); err != nil {
+				return nil, err
+			}
+			found = true
+			loc.Country = rec.Country.Names["en"]
+			loc.CountryCode = rec.Country.ISOCode
+			loc.City = rec.City.Names["en"]
+		}
+	}
+	if m.asn != nil {
+		var rec mmdbASNRecord
+		offset, err := m.asn.LookupOffset(ip)
+		if err != nil {
+			return nil, err
+		}
+		if offset != maxminddb.NotFound {
+			if err := m.asn.Decode(offset, &rec); err != nil {
+				return nil, err
+			}
+			found = true
+			loc.ASN = rec.AutonomousSystemNumber
+			loc.ASOrg = rec.AutonomousSystemOrganization
+		}
+	}
+	if !found {
+		return nil, NotFoundErr
+	}
+	return &loc, nil
+}
+
+func (m *MMDBLocator) Close() error {
+	var err error
+	if m.city != nil {
+		err = m.city.Close()
+	}
+	if m.asn != nil {
+		if asnErr := m.asn.Close(); err == nil {
+			err = asnErr
+		}
+	}
+	return err
+}
@@ -5,13 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"net"
 	"sync"
 	"time"
-    "strings"
-    "net/http"
 
 	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+	"github.com/protolambda/rumor/metrics/geoloc"
 	pgossip "github.com/protolambda/rumor/p2p/gossip"
 	"github.com/protolambda/rumor/p2p/track"
 )
@@ -21,12 +22,20 @@ type GossipState struct {
 	CloseGS context.CancelFunc
 	// string -> *pubsub.Topic
 	Topics sync.Map
-	// Metrics for Gossip Messages
+	// Metrics for Gossip Messages, peer ID string -> *PeerMetrics
 	GossipMetrics sync.Map
+	// Optional geolocation of new peers
+	locatorLock sync.RWMutex
+	locator     *geoloc.AsyncLocator
+	// Optional tracking of message propagation
+	propagationLock sync.RWMutex
+	propagation     *PropagationTracker
 }
 
 // Base Struct for the topic name and the received messages on the different topics
 type PeerMetrics struct {
+	lock sync.Mutex
+
 	PeerId     string
 	NodeId     string
 	ClientType string
@@ -35,16 +44,13 @@ type PeerMetrics struct {
 	Ip         string
     Country    string
     City       string
+	ASN        uint
+	ASOrg      string
     Latency    int64
 
 	ConnectionEvents []ConnectionEvents
-	// Counters for the different topics
-	BeaconBlock          MessageMetrics
-	BeaconAggregateProof MessageMetrics
-	VoluntaryExit        MessageMetrics
-	ProposerSlashing     MessageMetrics
-	AttesterSlashing     MessageMetrics
-	// Variables related to the SubNets (only needed for when Shards will be implemented)
+	// Counters for the different topics, keyed by full topic name
+	Topics map[string]*MessageMetrics
 }
 
 // Connection event model
@@ -53,18 +59,44 @@ type ConnectionEvents struct {
 	TimeMili       int64
 }
 
-// Information regarding the messages received on the beacon_lock topic
+// Information regarding the messages received on a topic
 type MessageMetrics struct {
 	Cnt              int64
 	FirstMessageTime int64
 	LastMessageTime  int64
 }
 
+// Copy returns a snapshot of the peer metrics, safe to use while the metrics are being updated.
+func (pm *PeerMetrics) Copy() *PeerMetrics {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	out := &PeerMetrics{
+		PeerId:           pm.PeerId,
+		NodeId:           pm.NodeId,
+		ClientType:       pm.ClientType,
+		Pubkey:           pm.Pubkey,
+		Addrs:            append([]string(nil), pm.Addrs...),
+		Ip:               pm.Ip,
+		Country:          pm.Country,
+		City:             pm.City,
+		ASN:              pm.ASN,
+		ASOrg:            pm.ASOrg,
+		Latency:          pm.Latency,
+		ConnectionEvents: append([]ConnectionEvents(nil), pm.ConnectionEvents...),
+		Topics:           make(map[string]*MessageMetrics, len(pm.Topics)),
+	}
+	for topic, m := range pm.Topics {
+		mCopy := *m
+		out.Topics[topic] = &mCopy
+	}
+	return out
+}
+
 // Function that Wraps/Marshals the content of the sync.Map to be exported as a json
 func (c *GossipState) MarshalMetrics() ([]byte, error) {
-    tmpMap := make(map[string]PeerMetrics)
+	tmpMap := make(map[string]*PeerMetrics)
     c.GossipMetrics.Range(func(k, v interface{}) bool {
-        tmpMap[k.(string)] = v.(PeerMetrics)
+		tmpMap[k.(string)] = v.(*PeerMetrics).Copy()
         return true
     })
     return json.Marshal(tmpMap)
@@ -81,128 +113,115 @@ func (c *GossipState) MarshalPeerStore(ep track.ExtendedPeerstore) ([]byte, erro
     return json.Marshal(peerData)
 }
 
-
-// Function that Exports the entire Metrics to a .json file (lets see if in the future we can add websockets or other implementations)
+// Function that Exports the entire Metrics to a .json file, and the peerstore to another .json file.
+// Both files are replaced atomically.
 func (c *GossipState) ExportMetrics(filePath string, peerstorePath string, ep track.ExtendedPeerstore) error {
-    metrics, err := c.MarshalMetrics()
-	if err != nil {
-		fmt.Println("Error Marshalling the metrics")
+	e := &Exporter{State: c, Store: ep, Format: ExportJSON, FilePath: filePath, PeerstorePath: peerstorePath}
+	return e.Export(time.Now())
+}
+
+// Add new peer with all the information from the peerstore to the metrics peerstore.
+// The location of the peer is looked up in the background, if a locator is configured.
+func (c *GossipState) AddNewPeer(peerId peer.ID, ep track.ExtendedPeerstore) {
+	peerData := ep.GetAllData(peerId)
+	peerMetrics := c.loadOrCreatePeer(peerId)
+	peerMetrics.lock.Lock()
+	defer peerMetrics.lock.Unlock()
+	// Only fill in the peer information once
+	if peerMetrics.NodeId != "" {
+		return
 	}
-    fmt.Println("Loading Peerstore to Export them")
-    peerstore, err := c.MarshalPeerStore(ep)
-	if err != nil {
-		fmt.Println("Error Marshalling the peerstore")
+	peerMetrics.NodeId = peerData.NodeID.String()
+	peerMetrics.ClientType = peerData.UserAgent
+	peerMetrics.Pubkey = peerData.Pubkey
+	peerMetrics.Addrs = peerData.Addrs
+	peerMetrics.Latency = int64(peerData.Latency / time.Millisecond)
+	ip := getIpFromAddrs(peerData.Addrs)
+	if ip == nil {
+		return
 	}
+	peerMetrics.Ip = ip.String()
 
-    err = ioutil.WriteFile(filePath, metrics, 0644)
-	if err != nil {
-		fmt.Println("Error opening file: ", filePath)
-		return err
+	c.locatorLock.RLock()
+	locator := c.locator
+	c.locatorLock.RUnlock()
+	if locator == nil {
+		return
 	}
-	err = ioutil.WriteFile(peerstorePath, peerstore, 0644)
-	if err != nil {
-		fmt.Println("Error opening file: ", peerstorePath)
-		return err
+	queued := locator.LocateAsync(ip, func(loc *geoloc.Location, err error) {
+		if err != nil {
+			return
+		}
+		peerMetrics.lock.Lock()
+		defer peerMetrics.lock.Unlock()
+		peerMetrics.Country = loc.Country
+		peerMetrics.City = loc.City
+		peerMetrics.ASN = loc.ASN
+		peerMetrics.ASOrg = loc.ASOrg
+	})
+	if !queued {
+		fmt.Println("Location lookup queue is full, skipping location of peer", peerId.String())
 	}
-	return nil
 }
 
-// IP-API message structure
-type IpApiMessage struct {
-    Query       string
-    Status      string
-    Continent   string
-    ContinentCode string
-    Country     string
-    CountryCode string
-    Region      string
-    RegionName  string
-    City    string
-    District    string
-    Zip     string
-    Lat         string
-    Lon         string
-    Timezone    string
-    Offset      string
-    Currency    string
-    Isp     string
-    Org     string
-    As      string
-    Asname  string
-    Mobile  string
-    Proxy   string
-    Hosting string
+// SetLocator changes the geolocation service used to locate new peers, and returns the previous one.
+// The locator may be nil to disable geolocation.
+func (c *GossipState) SetLocator(locator *geoloc.AsyncLocator) (prev *geoloc.AsyncLocator) {
+	c.locatorLock.Lock()
+	defer c.locatorLock.Unlock()
+	prev = c.locator
+	c.locator = locator
+	return prev
 }
 
-// get IP, location country and City from the multiaddress of the peer on the peerstore
-func getIpAndLocationFromAddrs(multiAddrs string) (ip string, country string, city string) {
-    ip = strings.TrimPrefix(multiAddrs, "/ip4/")
-    ipSlices := strings.Split(ip, "/")
-    ip = ipSlices[0]
-    url := "http://ip-api.com/json/" + ip
-    resp, err := http.Get(url)
-    if err != nil {
-        fmt.Println("There was an error getting the Location of the peer from the IP-API, please, check that there 40requests/minute limit hasn't been exceed")
-    }
-    defer resp.Body.Close()
-    bodyBytes, _ := ioutil.ReadAll(resp.Body)
-
-    // Convert response body to Todo struct
-    var ipApiResp IpApiMessage
-    json.Unmarshal(bodyBytes, &ipApiResp)
-
-    country = ipApiResp.Country
-    city = ipApiResp.City
-    // return the received values from the received message
-    return ip, country, city
-
+// get the first public IP (or otherwise the first IP) from the multi-addresses of the peer
+func getIpFromAddrs(addrs []string) net.IP {
+	var out net.IP
+	for _, addr := range addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			continue
+		}
+		ip, err := manet.ToIP(maddr)
+		if err != nil {
+			continue
+		}
+		if manet.IsPublicAddr(maddr) {
+			return ip
+		}
+		if out == nil {
+			out = ip
+		}
+	}
+	return out
 }
 
-// Add new peer with all the information from the peerstore to the metrics peerstore
-func (c *GossipState) AddNewPeer(peerId peer.ID, ep track.ExtendedPeerstore) {	// Check if the peer already exists
-	_ , ok := c.GossipMetrics.Load(peerId.String())
-    if !ok {
-        peerData := ep.GetAllData(peerId)
-        ip, country, city := getIpAndLocationFromAddrs(peerData.Addrs[0])
-        peerMetrics := PeerMetrics {
-            PeerId: peerId.String(),
-            NodeId: peerData.NodeID.String(),
-            ClientType: peerData.UserAgent,
-            Pubkey: peerData.Pubkey,
-            Addrs: peerData.Addrs,
-            Ip: ip,
-            Country: country,
-            City: city,
-            Latency: int64(peerData.Latency / 1*time.Millisecond),
-        }
-
-        // Temp
-        fmt.Println(peerMetrics.Latency)
-		// Temp
-
-        // Include the new PeerMetrics struct on the syn.Map
-        c.GossipMetrics.Store(peerId.String(), peerMetrics)
-    }
+// loadOrCreatePeer gets the metrics of the given peer, and creates empty metrics if the peer is not known yet.
+func (c *GossipState) loadOrCreatePeer(peerId peer.ID) *PeerMetrics {
+	pMetrics, _ := c.GossipMetrics.LoadOrStore(peerId.String(), &PeerMetrics{
+		PeerId: peerId.String(),
+		Topics: make(map[string]*MessageMetrics),
+	})
+	return pMetrics.(*PeerMetrics)
 }
 
 // Add a connection Event to the given peer
-func (c *GossipState) AddConnectionEvent(peerId peer.ID, connectionType string) {
+func (c *GossipState) AddConnectionEvent(peerId peer.ID, connectionType string) error {
 	newConnection := ConnectionEvents{
 		ConnectionType: connectionType,
 		TimeMili:       GetTimeMiliseconds(),
 	}
-	pMetrics , ok := c.GossipMetrics.Load(peerId.String())
-    if ok {
-        peerMetrics := pMetrics.(PeerMetrics)
-        peerMetrics.ConnectionEvents = append(peerMetrics.ConnectionEvents, newConnection)
-        c.GossipMetrics.Store(peerId.String(), peerMetrics)
-    } else {
-        // Might be possible to add 
-        errors.New("Counld't add Event, Peer is not in the list")
+	pMetrics, ok := c.GossipMetrics.Load(peerId.String())
+	if !ok {
+		return errors.New("Couldn't add Event, Peer is not in the list")
 	}
+	peerMetrics := pMetrics.(*PeerMetrics)
+	peerMetrics.lock.Lock()
+	defer peerMetrics.lock.Unlock()
+	peerMetrics.ConnectionEvents = append(peerMetrics.ConnectionEvents, newConnection)
+	return nil
 }
 
-
 // Increments the counter of the topic
 func (c *MessageMetrics) IncrementCnt() int64 {
 	c.Cnt++
@@ -233,13 +252,11 @@ func GetTimeMiliseconds() int64 {
 }
 
 // Function that Manages the metrics updates for the incoming messages
-func (c *GossipState) IncomingMessageManager(peerId peer.ID, topicName string) error {
-    pMetrics , _ := c.GossipMetrics.Load(peerId.String())
-    peerMetrics := pMetrics.(PeerMetrics)
-	messageMetrics, err := GetMessageMetrics(&peerMetrics, topicName)
-	if err != nil {
-		return errors.New("Topic Name no supported")
-	}
+func (c *GossipState) IncomingMessageManager(peerId peer.ID, topicName string) {
+	peerMetrics := c.loadOrCreatePeer(peerId)
+	peerMetrics.lock.Lock()
+	defer peerMetrics.lock.Unlock()
+	messageMetrics := GetMessageMetrics(peerMetrics, topicName)
 
 	if messageMetrics.Cnt == 0 {
 		messageMetrics.StampTime("first")
@@ -247,26 +264,33 @@ func (c *GossipState) IncomingMessageManager(peerId peer.ID, topicName string) e
 
 	messageMetrics.IncrementCnt()
 	messageMetrics.StampTime("last")
-
-    // Store back the Loaded/Modified Variable
-    c.GossipMetrics.Store(peerId.String(), peerMetrics)
-	return nil
 }
 
-func GetMessageMetrics(c *PeerMetrics, topicName string) (mesMetr *MessageMetrics, err error) {
-	// All this could be inside a different function
-	switch topicName {
-	case pgossip.BeaconBlock:
-		return &c.BeaconBlock, nil
-	case pgossip.BeaconAggregateProof:
-		return &c.BeaconAggregateProof, nil
-	case pgossip.VoluntaryExit:
-		return &c.VoluntaryExit, nil
-	case pgossip.ProposerSlashing:
-		return &c.ProposerSlashing, nil
-	case pgossip.AttesterSlashing:
-		return &c.AttesterSlashing, nil
-	default:
-		return &c.BeaconBlock, err
+// GetMessageMetrics gets the message metrics of the given topic, creating empty metrics if the topic is new.
+// The caller is responsible for locking the peer metrics.
+func GetMessageMetrics(c *PeerMetrics, topicName string) *MessageMetrics {
+	if c.Topics == nil {
+		c.Topics = make(map[string]*MessageMetrics)
 	}
+	m, ok := c.Topics[topicName]
+	if !ok {
+		m = new(MessageMetrics)
+		c.Topics[topicName] = m
+	}
+	return m
+}
+
+// TopicMessageCounts sums the received message counts of all peers, per topic.
+func (c *GossipState) TopicMessageCounts() map[string]int64 {
+	out := make(map[string]int64)
+	c.GossipMetrics.Range(func(k, v interface{}) bool {
+		pm := v.(*PeerMetrics)
+		pm.lock.Lock()
+		for topic, m := range pm.Topics {
+			out[topic] += m.Cnt
+		}
+		pm.lock.Unlock()
+		return true
+	})
+	return out
 }
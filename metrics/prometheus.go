@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Content type of the Prometheus text exposition format
+const PromContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type PromLabel struct {
+	Name  string
+	Value string
+}
+
+type PromSample struct {
+	Labels []PromLabel
+	Value  float64
+}
+
+// PromWriter writes metric families in the Prometheus text exposition format,
+// which OpenMetrics scrapers accept as well.
+type PromWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewPromWriter(w io.Writer) *PromWriter {
+	return &PromWriter{w: bufio.NewWriter(w)}
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// Family writes a metric family of the given type ("gauge" or "counter"), with all its samples.
+func (pw *PromWriter) Family(name string, typ string, help string, samples ...PromSample) {
+	if pw.err != nil {
+		return
+	}
+	_, pw.err = fmt.Fprintf(pw.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, s := range samples {
+		if pw.err != nil {
+			return
+		}
+		var labels string
+		if len(s.Labels) > 0 {
+			parts := make([]string, 0, len(s.Labels))
+			for _, l := range s.Labels {
+				parts = append(parts, l.Name+`="`+promEscaper.Replace(l.Value)+`"`)
+			}
+			labels = "{" + strings.Join(parts, ",") + "}"
+		}
+		_, pw.err = fmt.Fprintf(pw.w, "%s%s %s\n", name, labels, strconv.FormatFloat(s.Value, 'g', -1, 64))
+	}
+}
+
+// Gauge writes a single unlabeled gauge
+func (pw *PromWriter) Gauge(name string, help string, v float64) {
+	pw.Family(name, "gauge", help, PromSample{Value: v})
+}
+
+// Flush writes the buffered output, and returns the first error that was encountered.
+func (pw *PromWriter) Flush() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
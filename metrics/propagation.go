@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Delivery of a gossip message by a peer
+type Delivery struct {
+	Peer peer.ID
+	Time time.Time
+}
+
+// MessagePropagation tracks how a single gossip message, identified by its message ID, reached us.
+type MessagePropagation struct {
+	ID    string
+	Topic string
+	// Slot the message was first seen in, based on the genesis time
+	Slot uint64
+	// Time since the start of the slot when the message was first seen
+	SlotDelay time.Duration
+	// All deliveries, in order of arrival. The first is the original, the others are duplicates.
+	Deliveries []Delivery
+}
+
+func (m *MessagePropagation) FirstSeen() time.Time {
+	return m.Deliveries[0].Time
+}
+
+func (m *MessagePropagation) Duplicates() int {
+	return len(m.Deliveries) - 1
+}
+
+// PropagationTracker is a pubsub event tracer that tracks the propagation of each received gossip message.
+type PropagationTracker struct {
+	GenesisTime    time.Time
+	SecondsPerSlot uint64
+	// Maximum number of messages to track, the oldest messages are forgotten first
+	MaxMessages int
+
+	lock     sync.Mutex
+	messages map[string]*MessagePropagation
+	// message IDs in order of first arrival, to forget the oldest messages
+	order []string
+}
+
+func NewPropagationTracker(genesisTime time.Time, secondsPerSlot uint64, maxMessages int) *PropagationTracker {
+	return &PropagationTracker{
+		GenesisTime:    genesisTime,
+		SecondsPerSlot: secondsPerSlot,
+		MaxMessages:    maxMessages,
+		messages:       make(map[string]*MessagePropagation),
+	}
+}
+
+func (t *PropagationTracker) Trace(evt *pubsub_pb.TraceEvent) {
+	if evt.GetType() != pubsub_pb.TraceEvent_RECV_RPC {
+		return
+	}
+	msgs := evt.GetRecvRPC().GetMeta().GetMessages()
+	if len(msgs) == 0 {
+		return
+	}
+	from := peer.ID(evt.GetRecvRPC().GetReceivedFrom())
+	at := time.Unix(0, evt.GetTimestamp())
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, m := range msgs {
+		id := string(m.GetMessageID())
+		if prop, ok := t.messages[id]; ok {
+			prop.Deliveries = append(prop.Deliveries, Delivery{Peer: from, Time: at})
+			continue
+		}
+		var topic string
+		if topics := m.GetTopics(); len(topics) > 0 {
+			topic = topics[0]
+		}
+		prop := &MessagePropagation{
+			ID:         id,
+			Topic:      topic,
+			Deliveries: []Delivery{{Peer: from, Time: at}},
+		}
+		if t.SecondsPerSlot > 0 && at.After(t.GenesisTime) {
+			slotDuration := time.Duration(t.SecondsPerSlot) * time.Second
+			sinceGenesis := at.Sub(t.GenesisTime)
+			prop.Slot = uint64(sinceGenesis / slotDuration)
+			prop.SlotDelay = sinceGenesis % slotDuration
+		}
+		t.messages[id] = prop
+		t.order = append(t.order, id)
+		if t.MaxMessages > 0 && len(t.order) > t.MaxMessages {
+			delete(t.messages, t.order[0])
+			t.order = t.order[1:]
+		}
+	}
+}
+
+var _ pubsub.EventTracer = (*PropagationTracker)(nil)
+
+// SetPropagationTracker changes the propagation tracker, and returns the previous one. May be nil.
+func (c *GossipState) SetPropagationTracker(t *PropagationTracker) (prev *PropagationTracker) {
+	c.propagationLock.Lock()
+	defer c.propagationLock.Unlock()
+	prev = c.propagation
+	c.propagation = t
+	return prev
+}
+
+// PropagationTracker returns the current propagation tracker, or nil if propagation is not tracked.
+func (c *GossipState) PropagationTracker() *PropagationTracker {
+	c.propagationLock.RLock()
+	defer c.propagationLock.RUnlock()
+	return c.propagation
+}
+
+// Percentiles of a distribution, durations in milliseconds
+type Percentiles struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+func percentiles(values []float64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Float64s(values)
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(values)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return values[i]
+	}
+	return Percentiles{
+		Count: len(values),
+		P50:   rank(0.50),
+		P90:   rank(0.90),
+		P99:   rank(0.99),
+		Max:   values[len(values)-1],
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// TopicPropagation summarizes the propagation of the messages of a topic
+type TopicPropagation struct {
+	Messages int `json:"messages"`
+	// Time since slot start when messages were first seen
+	SlotDelayMs Percentiles `json:"slot_delay_ms"`
+	// Time since the first delivery for every delivery, including duplicates
+	DeliveryDelayMs Percentiles `json:"delivery_delay_ms"`
+	// Number of duplicates per message (not milliseconds)
+	Duplicates Percentiles `json:"duplicates"`
+}
+
+// ClientPropagation summarizes the deliveries of the peers of a client type
+type ClientPropagation struct {
+	Deliveries int `json:"deliveries"`
+	// Number of messages that this client delivered first
+	FirstDeliveries int `json:"first_deliveries"`
+	// Time since slot start of each delivery
+	SlotDelayMs Percentiles `json:"slot_delay_ms"`
+	// Time since the first delivery of the message, for each delivery
+	DeliveryDelayMs Percentiles `json:"delivery_delay_ms"`
+}
+
+type PropagationReport struct {
+	Messages int                                      `json:"messages"`
+	Topics   map[string]*TopicPropagation             `json:"topics"`
+	Clients  map[string]map[string]*ClientPropagation `json:"clients"` // topic -> client type -> summary
+}
+
+// Report summarizes the tracked messages per topic, and per client type per topic.
+// The client type of a peer is determined by the given function, which is called without holding the tracker lock.
+func (t *PropagationTracker) Report(clientType func(p peer.ID) string) *PropagationReport {
+	// copy the messages, to not block tracing while resolving client types
+	t.lock.Lock()
+	messages := make([]MessagePropagation, 0, len(t.messages))
+	for _, prop := range t.messages {
+		cp := *prop
+		cp.Deliveries = append([]Delivery(nil), prop.Deliveries...)
+		messages = append(messages, cp)
+	}
+	t.lock.Unlock()
+
+	type topicValues struct {
+		slotDelay, deliveryDelay, duplicates []float64
+	}
+	type clientValues struct {
+		deliveries, first        int
+		slotDelay, deliveryDelay []float64
+	}
+	topics := make(map[string]*topicValues)
+	clients := make(map[string]map[string]*clientValues)
+	clientNames := make(map[peer.ID]string)
+
+	for i := range messages {
+		prop := &messages[i]
+		tv, ok := topics[prop.Topic]
+		if !ok {
+			tv = new(topicValues)
+			topics[prop.Topic] = tv
+		}
+		first := prop.FirstSeen()
+		tv.slotDelay = append(tv.slotDelay, durationMs(prop.SlotDelay))
+		tv.duplicates = append(tv.duplicates, float64(prop.Duplicates()))
+
+		cvs, ok := clients[prop.Topic]
+		if !ok {
+			cvs = make(map[string]*clientValues)
+			clients[prop.Topic] = cvs
+		}
+		for i, d := range prop.Deliveries {
+			delay := durationMs(d.Time.Sub(first))
+			tv.deliveryDelay = append(tv.deliveryDelay, delay)
+
+			name, ok := clientNames[d.Peer]
+			if !ok {
+				name = clientType(d.Peer)
+				clientNames[d.Peer] = name
+			}
+			cv, ok := cvs[name]
+			if !ok {
+				cv = new(clientValues)
+				cvs[name] = cv
+			}
+			cv.deliveries += 1
+			if i == 0 {
+				cv.first += 1
+			}
+			cv.slotDelay = append(cv.slotDelay, durationMs(prop.SlotDelay+d.Time.Sub(first)))
+			cv.deliveryDelay = append(cv.deliveryDelay, delay)
+		}
+	}
+
+	report := &PropagationReport{
+		Messages: len(messages),
+		Topics:   make(map[string]*TopicPropagation),
+		Clients:  make(map[string]map[string]*ClientPropagation),
+	}
+	for topic, tv := range topics {
+		report.Topics[topic] = &TopicPropagation{
+			Messages:        len(tv.slotDelay),
+			SlotDelayMs:     percentiles(tv.slotDelay),
+			DeliveryDelayMs: percentiles(tv.deliveryDelay),
+			Duplicates:      percentiles(tv.duplicates),
+		}
+	}
+	for topic, cvs := range clients {
+		out := make(map[string]*ClientPropagation)
+		for name, cv := range cvs {
+			out[name] = &ClientPropagation{
+				Deliveries:      cv.deliveries,
+				FirstDeliveries: cv.first,
+				SlotDelayMs:     percentiles(cv.slotDelay),
+				DeliveryDelayMs: percentiles(cv.deliveryDelay),
+			}
+		}
+		report.Clients[topic] = out
+	}
+	return report
+}
+
+// ClientName extracts the client name from a user agent, e.g. "Lighthouse/v0.2.0/x86_64-linux" becomes "lighthouse".
+func ClientName(userAgent string) string {
+	name := strings.ToLower(strings.TrimSpace(strings.SplitN(userAgent, "/", 2)[0]))
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
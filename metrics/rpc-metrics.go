@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/protolambda/rumor/p2p/custom"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// RPCRequestKey identifies a group of RPC requests to count
+type RPCRequestKey struct {
+	Method    string
+	Version   string
+	Direction string
+	// Result code of the first response chunk, or "none" if there was no response.
+	Result string
+}
+
+// RPCMetrics counts the RPC requests of a host, by intercepting the req-resp streams.
+type RPCMetrics struct {
+	lock     sync.Mutex
+	requests map[RPCRequestKey]uint64
+}
+
+func (m *RPCMetrics) observe(key RPCRequestKey) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[RPCRequestKey]uint64)
+	}
+	m.requests[key] += 1
+}
+
+// Requests returns a snapshot of the request counters, sorted by key
+func (m *RPCMetrics) Requests() (keys []RPCRequestKey, counts []uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		if a.Direction != b.Direction {
+			return a.Direction < b.Direction
+		}
+		return a.Result < b.Result
+	})
+	for _, k := range keys {
+		counts = append(counts, m.requests[k])
+	}
+	return
+}
+
+func (m *RPCMetrics) InterceptStream(s network.Stream, outbound bool) network.Stream {
+	method, version, _, ok := reqresp.ParseProtocolID(s.Protocol())
+	if !ok {
+		return s
+	}
+	direction := "inbound"
+	if outbound {
+		direction = "outbound"
+	}
+	return &rpcMetricsStream{
+		Stream:   s,
+		m:        m,
+		outbound: outbound,
+		key:      RPCRequestKey{Method: method, Version: version, Direction: direction},
+	}
+}
+
+var _ custom.StreamInterceptor = (*RPCMetrics)(nil)
+
+// rpcMetricsStream counts the request once the result code of the first response chunk is known,
+// or when the stream ends without response.
+type rpcMetricsStream struct {
+	network.Stream
+	m        *RPCMetrics
+	outbound bool
+	key      RPCRequestKey
+	once     sync.Once
+}
+
+func (s *rpcMetricsStream) done(result string) {
+	s.once.Do(func() {
+		key := s.key
+		key.Result = result
+		s.m.observe(key)
+	})
+}
+
+func (s *rpcMetricsStream) Read(b []byte) (int, error) {
+	n, err := s.Stream.Read(b)
+	if s.outbound {
+		if n > 0 {
+			s.done(strconv.FormatUint(uint64(b[0]), 10))
+		} else if err != nil {
+			s.done("none")
+		}
+	}
+	return n, err
+}
+
+func (s *rpcMetricsStream) Write(b []byte) (int, error) {
+	n, err := s.Stream.Write(b)
+	if !s.outbound && n > 0 {
+		s.done(strconv.FormatUint(uint64(b[0]), 10))
+	}
+	return n, err
+}
+
+func (s *rpcMetricsStream) Close() error {
+	// The outbound side closes for writing after sending the request, the response is still to come.
+	if !s.outbound {
+		s.done("none")
+	}
+	return s.Stream.Close()
+}
+
+func (s *rpcMetricsStream) Reset() error {
+	s.done("none")
+	return s.Stream.Reset()
+}
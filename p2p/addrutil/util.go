@@ -14,6 +14,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/peer"
 	ma "github.com/multiformats/go-multiaddr"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/protolambda/ztyp/codec"
 	"net"
@@ -77,6 +78,32 @@ func (aee AttnetsENREntry) String() string {
 	return hex.EncodeToString(aee)
 }
 
+type SyncnetsENREntry []byte
+
+func NewSyncnetsENREntry(dat *altair.SyncnetBits) SyncnetsENREntry {
+	var buf bytes.Buffer
+	if err := dat.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func (see SyncnetsENREntry) ENRKey() string {
+	return "syncnets"
+}
+
+func (see SyncnetsENREntry) SyncnetBits() (altair.SyncnetBits, error) {
+	var dat altair.SyncnetBits
+	if err := dat.Deserialize(codec.NewDecodingReader(bytes.NewReader(see), uint64(len(see)))); err != nil {
+		return altair.SyncnetBits{}, err
+	}
+	return dat, nil
+}
+
+func (see SyncnetsENREntry) String() string {
+	return hex.EncodeToString(see)
+}
+
 var EnrEntries = map[string]func() (enr.Entry, func() string){
 	"secp256k1": func() (enr.Entry, func() string) {
 		res := new(enode.Secp256k1)
@@ -141,6 +168,12 @@ var EnrEntries = map[string]func() (enr.Entry, func() string){
 			return res.String()
 		}
 	},
+	"syncnets": func() (enr.Entry, func() string) {
+		res := new(SyncnetsENREntry)
+		return res, func() string {
+			return res.String()
+		}
+	},
 }
 
 func ParseEnrBytes(v string) ([]byte, error) {
@@ -388,3 +421,15 @@ func ParseEnrAttnets(n *enode.Node) (attnetbits *beacon.AttnetBits, exists bool,
 	}
 	return &dat, true, nil
 }
+
+func ParseEnrSyncnets(n *enode.Node) (syncnetbits *altair.SyncnetBits, exists bool, err error) {
+	var syncnets SyncnetsENREntry
+	if err := n.Load(&syncnets); err != nil {
+		return nil, false, nil
+	}
+	dat, err := syncnets.SyncnetBits()
+	if err != nil {
+		return nil, true, fmt.Errorf("failed parsing syncnets bytes: %v", err)
+	}
+	return &dat, true, nil
+}
@@ -0,0 +1,167 @@
+package altair
+
+import (
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// SyncAggregate is the aggregate signature of the sync committee, included in Altair blocks
+type SyncAggregate struct {
+	// Bitvector of SyncCommitteeSize bits
+	SyncCommitteeBits      []byte              `json:"sync_committee_bits" yaml:"sync_committee_bits"`
+	SyncCommitteeSignature beacon.BLSSignature `json:"sync_committee_signature" yaml:"sync_committee_signature"`
+}
+
+func (a *SyncAggregate) bitLen(spec *beacon.Spec) uint64 {
+	return ConfigFor(spec).SyncCommitteeSize
+}
+
+func (a *SyncAggregate) Deserialize(spec *beacon.Spec, dr *codec.DecodingReader) error {
+	if err := dr.BitVector(&a.SyncCommitteeBits, a.bitLen(spec)); err != nil {
+		return err
+	}
+	return a.SyncCommitteeSignature.Deserialize(dr)
+}
+
+func (a *SyncAggregate) Serialize(spec *beacon.Spec, w *codec.EncodingWriter) error {
+	if uint64(len(a.SyncCommitteeBits)) != (a.bitLen(spec)+7)/8 {
+		return fmt.Errorf("sync committee bits have wrong length: %d", len(a.SyncCommitteeBits))
+	}
+	if err := w.BitVector(a.SyncCommitteeBits); err != nil {
+		return err
+	}
+	return a.SyncCommitteeSignature.Serialize(w)
+}
+
+func (a *SyncAggregate) ByteLength(spec *beacon.Spec) uint64 {
+	return a.FixedLength(spec)
+}
+
+func (a *SyncAggregate) FixedLength(spec *beacon.Spec) uint64 {
+	return (a.bitLen(spec)+7)/8 + 96
+}
+
+func (a *SyncAggregate) HashTreeRoot(spec *beacon.Spec, hFn tree.HashFn) beacon.Root {
+	return hFn.HashTreeRoot(hFn.BitVectorHTR(a.SyncCommitteeBits), a.SyncCommitteeSignature)
+}
+
+// BeaconBlockBody of Altair: the phase0 body, with a sync aggregate
+type BeaconBlockBody struct {
+	RandaoReveal beacon.BLSSignature `json:"randao_reveal" yaml:"randao_reveal"`
+	Eth1Data     beacon.Eth1Data     `json:"eth1_data" yaml:"eth1_data"`
+	Graffiti     beacon.Root         `json:"graffiti" yaml:"graffiti"`
+
+	ProposerSlashings beacon.ProposerSlashings `json:"proposer_slashings" yaml:"proposer_slashings"`
+	AttesterSlashings beacon.AttesterSlashings `json:"attester_slashings" yaml:"attester_slashings"`
+	Attestations      beacon.Attestations      `json:"attestations" yaml:"attestations"`
+	Deposits          beacon.Deposits          `json:"deposits" yaml:"deposits"`
+	VoluntaryExits    beacon.VoluntaryExits    `json:"voluntary_exits" yaml:"voluntary_exits"`
+
+	SyncAggregate SyncAggregate `json:"sync_aggregate" yaml:"sync_aggregate"`
+}
+
+func (b *BeaconBlockBody) Deserialize(spec *beacon.Spec, dr *codec.DecodingReader) error {
+	return dr.Container(
+		&b.RandaoReveal, &b.Eth1Data,
+		&b.Graffiti, spec.Wrap(&b.ProposerSlashings),
+		spec.Wrap(&b.AttesterSlashings), spec.Wrap(&b.Attestations),
+		spec.Wrap(&b.Deposits), spec.Wrap(&b.VoluntaryExits),
+		spec.Wrap(&b.SyncAggregate),
+	)
+}
+
+func (b *BeaconBlockBody) Serialize(spec *beacon.Spec, w *codec.EncodingWriter) error {
+	return w.Container(
+		&b.RandaoReveal, &b.Eth1Data,
+		&b.Graffiti, spec.Wrap(&b.ProposerSlashings),
+		spec.Wrap(&b.AttesterSlashings), spec.Wrap(&b.Attestations),
+		spec.Wrap(&b.Deposits), spec.Wrap(&b.VoluntaryExits),
+		spec.Wrap(&b.SyncAggregate),
+	)
+}
+
+func (b *BeaconBlockBody) ByteLength(spec *beacon.Spec) uint64 {
+	return codec.ContainerLength(
+		&b.RandaoReveal, &b.Eth1Data,
+		&b.Graffiti, spec.Wrap(&b.ProposerSlashings),
+		spec.Wrap(&b.AttesterSlashings), spec.Wrap(&b.Attestations),
+		spec.Wrap(&b.Deposits), spec.Wrap(&b.VoluntaryExits),
+		spec.Wrap(&b.SyncAggregate),
+	)
+}
+
+func (b *BeaconBlockBody) FixedLength(*beacon.Spec) uint64 {
+	return 0
+}
+
+func (b *BeaconBlockBody) HashTreeRoot(spec *beacon.Spec, hFn tree.HashFn) beacon.Root {
+	return hFn.HashTreeRoot(
+		b.RandaoReveal, &b.Eth1Data,
+		b.Graffiti, spec.Wrap(&b.ProposerSlashings),
+		spec.Wrap(&b.AttesterSlashings), spec.Wrap(&b.Attestations),
+		spec.Wrap(&b.Deposits), spec.Wrap(&b.VoluntaryExits),
+		spec.Wrap(&b.SyncAggregate),
+	)
+}
+
+type BeaconBlock struct {
+	Slot          beacon.Slot           `json:"slot" yaml:"slot"`
+	ProposerIndex beacon.ValidatorIndex `json:"proposer_index" yaml:"proposer_index"`
+	ParentRoot    beacon.Root           `json:"parent_root" yaml:"parent_root"`
+	StateRoot     beacon.Root           `json:"state_root" yaml:"state_root"`
+	Body          BeaconBlockBody       `json:"body" yaml:"body"`
+}
+
+func (b *BeaconBlock) Deserialize(spec *beacon.Spec, dr *codec.DecodingReader) error {
+	return dr.Container(&b.Slot, &b.ProposerIndex, &b.ParentRoot, &b.StateRoot, spec.Wrap(&b.Body))
+}
+
+func (b *BeaconBlock) Serialize(spec *beacon.Spec, w *codec.EncodingWriter) error {
+	return w.Container(&b.Slot, &b.ProposerIndex, &b.ParentRoot, &b.StateRoot, spec.Wrap(&b.Body))
+}
+
+func (b *BeaconBlock) ByteLength(spec *beacon.Spec) uint64 {
+	return codec.ContainerLength(&b.Slot, &b.ProposerIndex, &b.ParentRoot, &b.StateRoot, spec.Wrap(&b.Body))
+}
+
+func (b *BeaconBlock) FixedLength(*beacon.Spec) uint64 {
+	return 0
+}
+
+func (b *BeaconBlock) HashTreeRoot(spec *beacon.Spec, hFn tree.HashFn) beacon.Root {
+	return hFn.HashTreeRoot(b.Slot, b.ProposerIndex, b.ParentRoot, b.StateRoot, spec.Wrap(&b.Body))
+}
+
+type SignedBeaconBlock struct {
+	Message   BeaconBlock         `json:"message" yaml:"message"`
+	Signature beacon.BLSSignature `json:"signature" yaml:"signature"`
+}
+
+func (b *SignedBeaconBlock) Deserialize(spec *beacon.Spec, dr *codec.DecodingReader) error {
+	return dr.Container(spec.Wrap(&b.Message), &b.Signature)
+}
+
+func (b *SignedBeaconBlock) Serialize(spec *beacon.Spec, w *codec.EncodingWriter) error {
+	return w.Container(spec.Wrap(&b.Message), &b.Signature)
+}
+
+func (b *SignedBeaconBlock) ByteLength(spec *beacon.Spec) uint64 {
+	return codec.ContainerLength(spec.Wrap(&b.Message), &b.Signature)
+}
+
+func (b *SignedBeaconBlock) FixedLength(*beacon.Spec) uint64 {
+	return 0
+}
+
+func (b *SignedBeaconBlock) HashTreeRoot(spec *beacon.Spec, hFn tree.HashFn) beacon.Root {
+	return hFn.HashTreeRoot(spec.Wrap(&b.Message), b.Signature)
+}
+
+// SignedBeaconBlockMaxByteLen is the maximum size of an encoded Altair block:
+// the phase0 block, with the fixed-size sync aggregate.
+func SignedBeaconBlockMaxByteLen(spec *beacon.Spec) uint64 {
+	var agg SyncAggregate
+	return spec.SignedBeaconBlock().MaxByteLength() + agg.FixedLength(spec)
+}
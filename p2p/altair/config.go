@@ -0,0 +1,44 @@
+package altair
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon"
+)
+
+// Config of the Altair fork of a chain. The phase0 spec of zrnt does not include these values.
+type Config struct {
+	ForkVersion beacon.Version
+	ForkEpoch   beacon.Epoch
+	// Preset: the number of validators in a sync committee
+	SyncCommitteeSize uint64
+}
+
+const (
+	mainnetSyncCommitteeSize = 512
+	minimalSyncCommitteeSize = 32
+)
+
+// Altair fork epochs of known chains, by genesis fork version
+var knownForkEpochs = map[beacon.Version]beacon.Epoch{
+	{0x00, 0x00, 0x00, 0x00}: 74240, // mainnet
+	{0x00, 0x00, 0x20, 0x09}: 61650, // pyrmont
+	{0x00, 0x00, 0x10, 0x20}: 36660, // prater
+}
+
+// ConfigFor derives the Altair config of the chain with the given phase0 spec.
+// Like mainnet and the public testnets, the Altair fork version is the genesis fork version with a leading 0x01.
+// The fork epoch is only known for mainnet and the public testnets, and is unscheduled for other chains:
+// the fork digest of Altair is still recognized, but the fork does not activate by epoch.
+// The minimal preset is detected by its slots per epoch, since the spec does not include the preset name.
+func ConfigFor(spec *beacon.Spec) Config {
+	version := spec.GENESIS_FORK_VERSION
+	version[0] = 0x01
+	epoch, ok := knownForkEpochs[spec.GENESIS_FORK_VERSION]
+	if !ok {
+		epoch = ^beacon.Epoch(0)
+	}
+	size := uint64(mainnetSyncCommitteeSize)
+	if spec.SLOTS_PER_EPOCH == 8 {
+		size = minimalSyncCommitteeSize
+	}
+	return Config{ForkVersion: version, ForkEpoch: epoch, SyncCommitteeSize: size}
+}
@@ -0,0 +1,126 @@
+// Package altair has the networking types of the Altair fork, which the beacon package of zrnt does not have yet.
+package altair
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+const SYNC_COMMITTEE_SUBNET_COUNT = 4
+
+const syncnetByteLen = (SYNC_COMMITTEE_SUBNET_COUNT + 7) / 8
+
+// SyncnetBits is the bitfield of the sync committee subnets a node is subscribed to
+type SyncnetBits [syncnetByteLen]byte
+
+func (sb *SyncnetBits) BitLen() uint64 {
+	return SYNC_COMMITTEE_SUBNET_COUNT
+}
+
+func (p *SyncnetBits) Deserialize(dr *codec.DecodingReader) error {
+	if p == nil {
+		return errors.New("nil syncnet bits")
+	}
+	if _, err := dr.Read(p[:]); err != nil {
+		return err
+	}
+	// The unused bits of the last byte must be zero
+	if p[syncnetByteLen-1]>>(SYNC_COMMITTEE_SUBNET_COUNT%8) != 0 {
+		return fmt.Errorf("syncnet bits have bits set beyond the subnet count: %08b", p[syncnetByteLen-1])
+	}
+	return nil
+}
+
+func (p SyncnetBits) Serialize(w *codec.EncodingWriter) error {
+	return w.Write(p[:])
+}
+
+func (p SyncnetBits) ByteLength() uint64 {
+	return syncnetByteLen
+}
+
+func (SyncnetBits) FixedLength() uint64 {
+	return syncnetByteLen
+}
+
+func (p SyncnetBits) HashTreeRoot(_ tree.HashFn) (out beacon.Root) {
+	copy(out[:], p[:])
+	return
+}
+
+func (p SyncnetBits) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(p[:])), nil
+}
+
+func (p SyncnetBits) String() string {
+	return "0x" + hex.EncodeToString(p[:])
+}
+
+func (p *SyncnetBits) UnmarshalText(text []byte) error {
+	if p == nil {
+		return errors.New("cannot decode into nil SyncnetBits")
+	}
+	if len(text) >= 2 && text[0] == '0' && (text[1] == 'x' || text[1] == 'X') {
+		text = text[2:]
+	}
+	if len(text) != syncnetByteLen*2 {
+		return fmt.Errorf("unexpected length string '%s'", string(text))
+	}
+	_, err := hex.Decode(p[:], text)
+	return err
+}
+
+// MetaDataV2 is the metadata of a node since Altair, served with version 2 of the metadata RPC method.
+type MetaDataV2 struct {
+	SeqNumber beacon.SeqNr      `json:"seq_number" yaml:"seq_number"`
+	Attnets   beacon.AttnetBits `json:"attnets" yaml:"attnets"`
+	Syncnets  SyncnetBits       `json:"syncnets" yaml:"syncnets"`
+}
+
+// UpgradeMetaData converts phase0 metadata to the Altair version, without sync committee subnets.
+func UpgradeMetaData(md *beacon.MetaData) MetaDataV2 {
+	return MetaDataV2{SeqNumber: md.SeqNumber, Attnets: md.Attnets}
+}
+
+// V1 returns the phase0 version of the metadata, which does not have the sync committee subnets.
+func (m *MetaDataV2) V1() beacon.MetaData {
+	return beacon.MetaData{SeqNumber: m.SeqNumber, Attnets: m.Attnets}
+}
+
+func (m *MetaDataV2) Data() map[string]interface{} {
+	return map[string]interface{}{
+		"seq_number": m.SeqNumber,
+		"attnets":    hex.EncodeToString(m.Attnets[:]),
+		"syncnets":   hex.EncodeToString(m.Syncnets[:]),
+	}
+}
+
+func (d *MetaDataV2) Deserialize(dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&d.SeqNumber, &d.Attnets, &d.Syncnets)
+}
+
+func (d *MetaDataV2) Serialize(w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(&d.SeqNumber, &d.Attnets, &d.Syncnets)
+}
+
+const MetadataV2ByteLen = beacon.MetadataByteLen + syncnetByteLen
+
+func (d MetaDataV2) ByteLength() uint64 {
+	return MetadataV2ByteLen
+}
+
+func (*MetaDataV2) FixedLength() uint64 {
+	return MetadataV2ByteLen
+}
+
+func (d *MetaDataV2) HashTreeRoot(hFn tree.HashFn) beacon.Root {
+	return hFn.HashTreeRoot(&d.SeqNumber, &d.Attnets, &d.Syncnets)
+}
+
+func (m *MetaDataV2) String() string {
+	return fmt.Sprintf("MetaDataV2(seq: %d, attnets: %08b, syncnets: %08b)", m.SeqNumber, m.Attnets, m.Syncnets)
+}
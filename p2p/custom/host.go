@@ -129,6 +129,8 @@ type BasicHost struct {
 	caBook                  peerstore.CertifiedAddrBook
 
 	AutoNat autonat.AutoNAT
+
+	interceptors
 }
 
 var _ host.Host = (*BasicHost)(nil)
@@ -555,7 +557,7 @@ func (h *BasicHost) SetStreamHandler(pid protocol.ID, handler network.StreamHand
 	h.Mux().AddHandler(string(pid), func(p string, rwc io.ReadWriteCloser) error {
 		is := rwc.(network.Stream)
 		is.SetProtocol(protocol.ID(p))
-		handler(is)
+		handler(h.intercept(is, false))
 		return nil
 	})
 	h.emitters.evtLocalProtocolsUpdated.Emit(event.EvtLocalProtocolsUpdated{
@@ -569,7 +571,7 @@ func (h *BasicHost) SetStreamHandlerMatch(pid protocol.ID, m func(string) bool,
 	h.Mux().AddHandlerWithFunc(string(pid), m, func(p string, rwc io.ReadWriteCloser) error {
 		is := rwc.(network.Stream)
 		is.SetProtocol(protocol.ID(p))
-		handler(is)
+		handler(h.intercept(is, false))
 		return nil
 	})
 	h.emitters.evtLocalProtocolsUpdated.Emit(event.EvtLocalProtocolsUpdated{
@@ -619,10 +621,10 @@ func (h *BasicHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.I
 	if pref != "" {
 		s.SetProtocol(pref)
 		lzcon := msmux.NewMSSelect(s, string(pref))
-		return &streamWrapper{
+		return h.intercept(&streamWrapper{
 			Stream: s,
 			rw:     lzcon,
-		}, nil
+		}, true), nil
 	}
 
 	selected, err := msmux.SelectOneOf(pidStrings, s)
@@ -634,7 +636,7 @@ func (h *BasicHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.I
 	selpid := protocol.ID(selected)
 	s.SetProtocol(selpid)
 	h.Peerstore().AddProtocols(p, selected)
-	return s, nil
+	return h.intercept(s, true), nil
 }
 
 func (h *BasicHost) preferredProtocol(p peer.ID, pids []string) (protocol.ID, error) {
@@ -0,0 +1,60 @@
+package custom
+
+import (
+	"github.com/libp2p/go-libp2p-core/network"
+	"sync"
+)
+
+// StreamInterceptor can wrap the streams opened or accepted by the host, e.g. to track or record stream contents.
+// The stream protocol is set before the stream is intercepted.
+type StreamInterceptor interface {
+	InterceptStream(s network.Stream, outbound bool) network.Stream
+}
+
+// Interceptable is implemented by hosts that support stream interception
+type Interceptable interface {
+	// AddStreamInterceptor registers an interceptor, and returns true if it was already registered
+	AddStreamInterceptor(i StreamInterceptor) (exists bool)
+	// RmStreamInterceptor unregisters an interceptor, and returns true if it was registered before unregistering it
+	RmStreamInterceptor(i StreamInterceptor) (exists bool)
+}
+
+type interceptors struct {
+	lock sync.RWMutex
+	all  []StreamInterceptor
+}
+
+func (is *interceptors) AddStreamInterceptor(i StreamInterceptor) (exists bool) {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	for _, v := range is.all {
+		if v == i {
+			return true
+		}
+	}
+	is.all = append(is.all, i)
+	return false
+}
+
+func (is *interceptors) RmStreamInterceptor(i StreamInterceptor) (exists bool) {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	for j, v := range is.all {
+		if v == i {
+			is.all = append(is.all[:j:j], is.all[j+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (is *interceptors) intercept(s network.Stream, outbound bool) network.Stream {
+	is.lock.RLock()
+	defer is.lock.RUnlock()
+	for _, v := range is.all {
+		s = v.InterceptStream(s, outbound)
+	}
+	return s
+}
+
+var _ Interceptable = (*BasicHost)(nil)
@@ -1,7 +1,44 @@
 package gossip
 
-var BeaconBlock string = "/eth2/e7a75d5a/beacon_block/ssz_snappy"
-var BeaconAggregateProof string = "/eth2/e7a75d5a/beacon_aggregate_and_proof/ssz_snappy"
-var VoluntaryExit string = "/eth2/e7a75d5a/voluntary_exit/ssz_snappy"
-var ProposerSlashing string = "/eth2/e7a75d5a/proposer_slashing/ssz_snappy"
-var AttesterSlashing string = "/eth2/e7a75d5a/attester_slashing/ssz_snappy"
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"strings"
+)
+
+// Eth2 gossip topic names, without fork digest and encoding
+const (
+	BeaconBlock          string = "beacon_block"
+	BeaconAggregateProof string = "beacon_aggregate_and_proof"
+	VoluntaryExit        string = "voluntary_exit"
+	ProposerSlashing     string = "proposer_slashing"
+	AttesterSlashing     string = "attester_slashing"
+)
+
+// The default encoding of eth2 gossip messages
+const SSZSnappyEncoding = "ssz_snappy"
+
+// AttestationSubnet returns the topic name of the attestation subnet with the given index
+func AttestationSubnet(subnet uint64) string {
+	return fmt.Sprintf("beacon_attestation_%d", subnet)
+}
+
+// Eth2Topic builds a full eth2 topic: /eth2/<fork-digest>/<name>/<encoding>
+func Eth2Topic(digest beacon.ForkDigest, name string, encoding string) string {
+	return fmt.Sprintf("/eth2/%x/%s/%s", digest[:], name, encoding)
+}
+
+// ParseEth2Topic splits a full eth2 topic into its fork digest, name and encoding.
+func ParseEth2Topic(topic string) (digest beacon.ForkDigest, name string, encoding string, err error) {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 5 || parts[0] != "" || parts[1] != "eth2" {
+		return digest, "", "", fmt.Errorf("not an eth2 topic: %s", topic)
+	}
+	b, err := hex.DecodeString(parts[2])
+	if err != nil || len(b) != 4 {
+		return digest, "", "", fmt.Errorf("invalid fork digest in topic: %s", topic)
+	}
+	copy(digest[:], b)
+	return digest, parts[3], parts[4], nil
+}
@@ -0,0 +1,157 @@
+package gossip
+
+import (
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"sync"
+	"time"
+)
+
+// Gossipsub forgets fanout peers of a topic if nothing was published to the topic for this long.
+const FanoutTTL = 60 * time.Second
+
+// Number of graft/prune events to remember per topic
+const meshHistoryLen = 256
+
+type MeshEventType string
+
+const (
+	MeshGraft MeshEventType = "graft"
+	MeshPrune MeshEventType = "prune"
+)
+
+type MeshEvent struct {
+	Type MeshEventType `json:"type"`
+	Peer peer.ID       `json:"peer"`
+	Time time.Time     `json:"time"`
+}
+
+type topicMesh struct {
+	// true if the router joined the topic, i.e. there is a subscription
+	joined bool
+	mesh   map[peer.ID]time.Time
+	// fanout peers, with the last time a message was sent to them on this topic
+	fanout  map[peer.ID]time.Time
+	history []MeshEvent
+}
+
+// MeshTracker is a pubsub event tracer that tracks the mesh and fanout peers of each topic, and the graft/prune history.
+type MeshTracker struct {
+	lock   sync.Mutex
+	topics map[string]*topicMesh
+}
+
+func NewMeshTracker() *MeshTracker {
+	return &MeshTracker{topics: make(map[string]*topicMesh)}
+}
+
+func (t *MeshTracker) topic(name string) *topicMesh {
+	tm, ok := t.topics[name]
+	if !ok {
+		tm = &topicMesh{mesh: make(map[peer.ID]time.Time), fanout: make(map[peer.ID]time.Time)}
+		t.topics[name] = tm
+	}
+	return tm
+}
+
+func (tm *topicMesh) addHistory(ev MeshEvent) {
+	tm.history = append(tm.history, ev)
+	if len(tm.history) > meshHistoryLen {
+		tm.history = tm.history[len(tm.history)-meshHistoryLen:]
+	}
+}
+
+func (t *MeshTracker) Trace(evt *pubsub_pb.TraceEvent) {
+	at := time.Unix(0, evt.GetTimestamp())
+	switch evt.GetType() {
+	case pubsub_pb.TraceEvent_JOIN:
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		tm := t.topic(evt.GetJoin().GetTopic())
+		tm.joined = true
+		// fanout peers are moved into the mesh (and traced as grafts) when joining
+		tm.fanout = make(map[peer.ID]time.Time)
+	case pubsub_pb.TraceEvent_LEAVE:
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		tm := t.topic(evt.GetLeave().GetTopic())
+		tm.joined = false
+	case pubsub_pb.TraceEvent_GRAFT:
+		p := peer.ID(evt.GetGraft().GetPeerID())
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		tm := t.topic(evt.GetGraft().GetTopic())
+		tm.mesh[p] = at
+		tm.addHistory(MeshEvent{Type: MeshGraft, Peer: p, Time: at})
+	case pubsub_pb.TraceEvent_PRUNE:
+		p := peer.ID(evt.GetPrune().GetPeerID())
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		tm := t.topic(evt.GetPrune().GetTopic())
+		delete(tm.mesh, p)
+		tm.addHistory(MeshEvent{Type: MeshPrune, Peer: p, Time: at})
+	case pubsub_pb.TraceEvent_REMOVE_PEER:
+		// the router drops disconnected peers from the mesh and fanout without tracing prunes
+		p := peer.ID(evt.GetRemovePeer().GetPeerID())
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		for _, tm := range t.topics {
+			delete(tm.mesh, p)
+			delete(tm.fanout, p)
+		}
+	case pubsub_pb.TraceEvent_SEND_RPC:
+		msgs := evt.GetSendRPC().GetMeta().GetMessages()
+		if len(msgs) == 0 {
+			return
+		}
+		p := peer.ID(evt.GetSendRPC().GetSendTo())
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		for _, m := range msgs {
+			for _, name := range m.GetTopics() {
+				tm := t.topic(name)
+				if !tm.joined {
+					tm.fanout[p] = at
+				}
+			}
+		}
+	}
+}
+
+var _ pubsub.EventTracer = (*MeshTracker)(nil)
+
+// TopicMeshInfo is a snapshot of the mesh state of a topic
+type TopicMeshInfo struct {
+	Joined bool `json:"joined"`
+	// Mesh peers, with the time they were grafted
+	Mesh map[peer.ID]time.Time `json:"mesh"`
+	// Fanout peers, with the last time a message was published to them
+	Fanout  map[peer.ID]time.Time `json:"fanout"`
+	History []MeshEvent           `json:"history"`
+}
+
+// Topic returns a snapshot of the mesh state of the topic. Fanout peers that expired are omitted.
+func (t *MeshTracker) Topic(name string) *TopicMeshInfo {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	out := &TopicMeshInfo{
+		Mesh:   make(map[peer.ID]time.Time),
+		Fanout: make(map[peer.ID]time.Time),
+	}
+	tm, ok := t.topics[name]
+	if !ok {
+		return out
+	}
+	out.Joined = tm.joined
+	for p, at := range tm.mesh {
+		out.Mesh[p] = at
+	}
+	for p, at := range tm.fanout {
+		if time.Since(at) < FanoutTTL {
+			out.Fanout[p] = at
+		}
+	}
+	out.History = append([]MeshEvent(nil), tm.history...)
+	return out
+}
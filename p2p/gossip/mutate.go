@@ -0,0 +1,117 @@
+package gossip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/golang/snappy"
+	"strings"
+)
+
+// GossipMaxSize is the maximum size of an uncompressed gossip message, GOSSIP_MAX_SIZE in the eth2 spec.
+const GossipMaxSize = 1 << 20
+
+// SnappyLiteralEncode encodes data as a valid snappy block that only consists of literals,
+// with the first literal split at the given position.
+// Different split positions result in different encodings that decode to the same data,
+// and thus in different gossip message IDs for the same message.
+func SnappyLiteralEncode(data []byte, split int) []byte {
+	var out []byte
+	var tmp [binary.MaxVarintLen64]byte
+	out = append(out, tmp[:binary.PutUvarint(tmp[:], uint64(len(data)))]...)
+	if split <= 0 || split >= len(data) {
+		return appendLiterals(out, data)
+	}
+	out = appendLiterals(out, data[:split])
+	return appendLiterals(out, data[split:])
+}
+
+func appendLiterals(out []byte, data []byte) []byte {
+	for len(data) > 0 {
+		n := len(data)
+		if n > 1<<16 {
+			n = 1 << 16
+		}
+		switch {
+		case n <= 60:
+			out = append(out, byte(n-1)<<2)
+		case n <= 1<<8:
+			out = append(out, 60<<2, byte(n-1))
+		default:
+			out = append(out, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		out = append(out, data[:n]...)
+		data = data[n:]
+	}
+	return out
+}
+
+// CorruptSnappy snappy-encodes data, and then corrupts the length header of the block,
+// so that it does not decode.
+func CorruptSnappy(data []byte) []byte {
+	enc := snappy.Encode(nil, data)
+	_, n := binary.Uvarint(enc)
+	var tmp [binary.MaxVarintLen64]byte
+	out := append([]byte{}, tmp[:binary.PutUvarint(tmp[:], uint64(len(data))+1)]...)
+	return append(out, enc[n:]...)
+}
+
+// MalformSSZ corrupts SSZ data: the first 4 bytes, an offset in variable-size containers, are set to 0xffffffff,
+// and the last byte is dropped.
+func MalformSSZ(data []byte) []byte {
+	out := append([]byte{}, data...)
+	for i := 0; i < 4 && i < len(out); i++ {
+		out[i] = 0xff
+	}
+	if len(out) > 0 {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// PadSSZ appends zero bytes to the data, up to the given size.
+func PadSSZ(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+	out := make([]byte, size)
+	copy(out, data)
+	return out
+}
+
+// SlotOffset returns the position of the slot in an uncompressed message on the given eth2 topic
+func SlotOffset(topic string) (int, error) {
+	_, name, _, err := ParseEth2Topic(topic)
+	if err != nil {
+		name = topic
+	}
+	switch {
+	case name == BeaconBlock:
+		// SignedBeaconBlock: message offset (4), signature (96), then the block starts with the slot
+		return 100, nil
+	case name == BeaconAggregateProof:
+		// SignedAggregateAndProof: message offset (4), signature (96),
+		// AggregateAndProof: aggregator index (8), aggregate offset (4), selection proof (96),
+		// Attestation: aggregation bits offset (4), then the data starts with the slot
+		return 212, nil
+	case strings.HasPrefix(name, "beacon_attestation_"):
+		// Attestation: aggregation bits offset (4), then the data starts with the slot
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("topic %s has no slot", topic)
+	}
+}
+
+// ShiftSlot adds delta to the slot of an uncompressed message on the given eth2 topic.
+func ShiftSlot(topic string, data []byte, delta int64) ([]byte, error) {
+	offset, err := SlotOffset(topic)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < offset+8 {
+		return nil, fmt.Errorf("message too short to contain a slot: %d bytes", len(data))
+	}
+	out := append([]byte{}, data...)
+	slot := binary.LittleEndian.Uint64(out[offset : offset+8])
+	binary.LittleEndian.PutUint64(out[offset:offset+8], uint64(int64(slot)+delta))
+	return out, nil
+}
@@ -13,23 +13,39 @@ import (
 type GossipSub interface {
 	Join(topic string, opts ...pubsub.TopicOpt) (*pubsub.Topic, error)
 	BlacklistPeer(id peer.ID)
+	// AddTracer registers a pubsub event tracer, and returns true if it was already registered
+	AddTracer(t pubsub.EventTracer) (exists bool)
+	// RmTracer unregisters a pubsub event tracer, and returns true if it was registered before unregistering it
+	RmTracer(t pubsub.EventTracer) (exists bool)
+	// Mesh returns a snapshot of the mesh and fanout peers of a topic
+	Mesh(topic string) *TopicMeshInfo
 }
 
 type gossipImpl struct {
 	*pubsub.PubSub
+	*MultiTracer
+	mesh *MeshTracker
 }
 
 func NewGossipSub(ctx context.Context, h host.Host) (GossipSub, error) {
+	tracer := NewMultiTracer()
+	mesh := NewMeshTracker()
+	tracer.AddTracer(mesh)
 	psOptions := []pubsub.Option{
 		pubsub.WithMessageSigning(false),
 		pubsub.WithStrictSignatureVerification(false),
 		pubsub.WithMessageIdFn(MsgIDFunction),
+		pubsub.WithEventTracer(tracer),
 	}
 	ps, err := pubsub.NewGossipSub(ctx, h, psOptions...)
 	if err != nil {
 		return nil, err
 	}
-	return &gossipImpl{PubSub: ps}, nil
+	return &gossipImpl{PubSub: ps, MultiTracer: tracer, mesh: mesh}, nil
+}
+
+func (g *gossipImpl) Mesh(topic string) *TopicMeshInfo {
+	return g.mesh.Topic(topic)
 }
 
 func MsgIDFunction(pmsg *pubsub_pb.Message) string {
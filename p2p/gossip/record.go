@@ -0,0 +1,98 @@
+package gossip
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"io"
+	"time"
+)
+
+// RecordedMessage is a gossip message as it was received, persisted as a JSON line.
+// The data is the raw message data, i.e. still compressed if the topic encoding is compressed.
+type RecordedMessage struct {
+	Topic string
+	From  peer.ID
+	// Time the message was received
+	Time  time.Time
+	Data  []byte
+	SeqNo []byte
+}
+
+type recordedMessageJSON struct {
+	Topic string    `json:"topic"`
+	From  peer.ID   `json:"from"`
+	Time  time.Time `json:"time"`
+	Data  string    `json:"data"`
+	SeqNo string    `json:"seq_no"`
+}
+
+func (m *RecordedMessage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&recordedMessageJSON{
+		Topic: m.Topic,
+		From:  m.From,
+		Time:  m.Time,
+		Data:  hex.EncodeToString(m.Data),
+		SeqNo: hex.EncodeToString(m.SeqNo),
+	})
+}
+
+func (m *RecordedMessage) UnmarshalJSON(b []byte) error {
+	var v recordedMessageJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	data, err := hex.DecodeString(v.Data)
+	if err != nil {
+		return fmt.Errorf("invalid message data: %v", err)
+	}
+	seqNo, err := hex.DecodeString(v.SeqNo)
+	if err != nil {
+		return fmt.Errorf("invalid message seq no: %v", err)
+	}
+	*m = RecordedMessage{Topic: v.Topic, From: v.From, Time: v.Time, Data: data, SeqNo: seqNo}
+	return nil
+}
+
+// RecordWriter writes recorded messages as JSON lines
+type RecordWriter struct {
+	enc *json.Encoder
+}
+
+func NewRecordWriter(w io.Writer) *RecordWriter {
+	return &RecordWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *RecordWriter) Write(m *RecordedMessage) error {
+	return w.enc.Encode(m)
+}
+
+// RecordReader reads recorded messages from JSON lines
+type RecordReader struct {
+	dec *json.Decoder
+}
+
+func NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next reads the next recorded message, or returns io.EOF when there are no more messages.
+func (r *RecordReader) Next() (*RecordedMessage, error) {
+	var m RecordedMessage
+	if err := r.dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// RewriteTopicDigest replaces the fork digest of an eth2 topic. Other topics are returned as-is.
+func RewriteTopicDigest(topic string, digest beacon.ForkDigest) string {
+	_, name, encoding, err := ParseEth2Topic(topic)
+	if err != nil {
+		return topic
+	}
+	return Eth2Topic(digest, name, encoding)
+}
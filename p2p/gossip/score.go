@@ -0,0 +1,72 @@
+package gossip
+
+import (
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/protolambda/rumor/p2p/track"
+	"sync"
+)
+
+// ScoreTracer is a pubsub event tracer that scores peers by the validation results of the messages they send.
+// Rejected and ignored messages are attributed to the peer they were received from.
+// Delivered messages are attributed to the first peer that sent the message, since the delivery event does not include the sender.
+type ScoreTracer struct {
+	Book track.ScoreBook
+	// Maximum number of message senders to remember, the oldest are forgotten first
+	MaxMessages int
+
+	lock    sync.Mutex
+	senders map[string]peer.ID
+	// message IDs in order of first arrival, to forget the oldest senders
+	order []string
+}
+
+func NewScoreTracer(book track.ScoreBook, maxMessages int) *ScoreTracer {
+	return &ScoreTracer{Book: book, MaxMessages: maxMessages, senders: make(map[string]peer.ID)}
+}
+
+func (t *ScoreTracer) Trace(evt *pubsub_pb.TraceEvent) {
+	switch evt.GetType() {
+	case pubsub_pb.TraceEvent_RECV_RPC:
+		msgs := evt.GetRecvRPC().GetMeta().GetMessages()
+		if len(msgs) == 0 {
+			return
+		}
+		from := peer.ID(evt.GetRecvRPC().GetReceivedFrom())
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		for _, m := range msgs {
+			id := string(m.GetMessageID())
+			if _, ok := t.senders[id]; ok {
+				continue
+			}
+			t.senders[id] = from
+			t.order = append(t.order, id)
+			if t.MaxMessages > 0 && len(t.order) > t.MaxMessages {
+				delete(t.senders, t.order[0])
+				t.order = t.order[1:]
+			}
+		}
+	case pubsub_pb.TraceEvent_DELIVER_MESSAGE:
+		t.lock.Lock()
+		from, ok := t.senders[string(evt.GetDeliverMessage().GetMessageID())]
+		t.lock.Unlock()
+		if ok {
+			t.Book.RegisterScore(from, track.ScoreGossipAccept)
+		}
+	case pubsub_pb.TraceEvent_REJECT_MESSAGE:
+		rej := evt.GetRejectMessage()
+		from := peer.ID(rej.GetReceivedFrom())
+		// The reject reasons are not exported by pubsub
+		switch rej.GetReason() {
+		case "validation failed", "invalid signature", "missing signature", "unexpected signature", "unexpected auth info":
+			t.Book.RegisterScore(from, track.ScoreGossipReject)
+		case "validation ignored":
+			t.Book.RegisterScore(from, track.ScoreGossipIgnore)
+		}
+		// Throttled validation and full queues are local problems, and not scored.
+	}
+}
+
+var _ pubsub.EventTracer = (*ScoreTracer)(nil)
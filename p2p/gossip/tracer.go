@@ -0,0 +1,51 @@
+package gossip
+
+import (
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"sync"
+)
+
+// MultiTracer forwards pubsub trace events to a dynamic set of tracers.
+// The pubsub system only accepts a tracer during construction, so this is installed once,
+// and tracers can be added and removed while gossipsub is running.
+type MultiTracer struct {
+	lock    sync.RWMutex
+	tracers map[pubsub.EventTracer]struct{}
+}
+
+func NewMultiTracer() *MultiTracer {
+	return &MultiTracer{tracers: make(map[pubsub.EventTracer]struct{})}
+}
+
+func (m *MultiTracer) Trace(evt *pubsub_pb.TraceEvent) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for t := range m.tracers {
+		t.Trace(evt)
+	}
+}
+
+// AddTracer registers a tracer, and returns true if it was already registered
+func (m *MultiTracer) AddTracer(t pubsub.EventTracer) (exists bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, exists := m.tracers[t]; exists {
+		return true
+	}
+	m.tracers[t] = struct{}{}
+	return false
+}
+
+// RmTracer unregisters a tracer, and returns true if it was registered before unregistering it
+func (m *MultiTracer) RmTracer(t pubsub.EventTracer) (exists bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, exists := m.tracers[t]; !exists {
+		return false
+	}
+	delete(m.tracers, t)
+	return true
+}
+
+var _ pubsub.EventTracer = (*MultiTracer)(nil)
@@ -6,6 +6,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/sirupsen/logrus"
 	"net"
@@ -35,6 +36,7 @@ type Dv5Settings interface {
 	SetUDP(port uint16)
 	SetEth2Data(dat *beacon.Eth2Data)
 	SetAttnets(dat *beacon.AttnetBits)
+	SetSyncnets(dat *altair.SyncnetBits)
 }
 
 type Discv5Impl struct {
@@ -7,6 +7,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/protolambda/rumor/p2p/addrutil"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"net"
 	"sync"
@@ -118,3 +119,7 @@ func (s *EnrState) SetEth2Data(dat *beacon.Eth2Data) {
 func (s *EnrState) SetAttnets(dat *beacon.AttnetBits) {
 	s.localNode.Set(addrutil.NewAttnetsENREntry(dat))
 }
+
+func (s *EnrState) SetSyncnets(dat *altair.SyncnetBits) {
+	s.localNode.Set(addrutil.NewSyncnetsENREntry(dat))
+}
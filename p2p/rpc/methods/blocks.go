@@ -80,6 +80,19 @@ func BlocksByRangeRPCv1(spec *beacon.Spec) *reqresp.RPCMethod {
 	}
 }
 
+// BlocksByRangeRPCv2 is like v1, but every response chunk is prefixed with the fork digest of the block,
+// and decoded into the block type of that fork.
+func BlocksByRangeRPCv2(forks *ForkSchedule) *reqresp.RPCMethod {
+	return &reqresp.RPCMethod{
+		Protocol:                  "/eth2/beacon_chain/req/beacon_blocks_by_range/2/ssz",
+		RequestCodec:              reqresp.NewSSZCodec(func() reqresp.SerDes { return new(BlocksByRangeReqV1) }, blocksByRangeReqByteLen, blocksByRangeReqByteLen),
+		ResponseChunkCodec:        forks.MaxBlockCodec(),
+		DefaultResponseChunkCount: 20,
+		ContextBytesLen:           4,
+		ResponseChunkContextCodec: forks.BlockCodec,
+	}
+}
+
 const MAX_REQUEST_BLOCKS_BY_ROOT = 1024
 
 type BlocksByRootReq []Root
@@ -129,3 +142,16 @@ func BlocksByRootRPCv1(spec *beacon.Spec) *reqresp.RPCMethod {
 		DefaultResponseChunkCount: 20,
 	}
 }
+
+// BlocksByRootRPCv2 is like v1, but every response chunk is prefixed with the fork digest of the block,
+// and decoded into the block type of that fork.
+func BlocksByRootRPCv2(forks *ForkSchedule) *reqresp.RPCMethod {
+	return &reqresp.RPCMethod{
+		Protocol:                  "/eth2/beacon_chain/req/beacon_blocks_by_root/2/ssz",
+		RequestCodec:              reqresp.NewSSZCodec(func() reqresp.SerDes { return new(BlocksByRootReq) }, 0, 32*MAX_REQUEST_BLOCKS_BY_ROOT),
+		ResponseChunkCodec:        forks.MaxBlockCodec(),
+		DefaultResponseChunkCount: 20,
+		ContextBytesLen:           4,
+		ResponseChunkContextCodec: forks.BlockCodec,
+	}
+}
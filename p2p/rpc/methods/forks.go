@@ -0,0 +1,114 @@
+package methods
+
+import (
+	"context"
+	"fmt"
+	"github.com/protolambda/rumor/chain"
+	"github.com/protolambda/rumor/p2p/altair"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/zrnt/eth2/beacon"
+)
+
+// Fork of the chain, from which on the types of the fork are used
+type Fork struct {
+	Name    string
+	Version beacon.Version
+	Epoch   beacon.Epoch
+	// Codec of signed blocks of this fork
+	BlockCodec func(spec *beacon.Spec) reqresp.Codec
+}
+
+func phase0BlockCodec(spec *beacon.Spec) reqresp.Codec {
+	return reqresp.NewSSZCodec(func() reqresp.SerDes { return spec.Wrap(new(beacon.SignedBeaconBlock)) }, 0, spec.SignedBeaconBlock().MaxByteLength())
+}
+
+func altairBlockCodec(spec *beacon.Spec) reqresp.Codec {
+	return reqresp.NewSSZCodec(func() reqresp.SerDes { return spec.Wrap(new(altair.SignedBeaconBlock)) }, 0, altair.SignedBeaconBlockMaxByteLen(spec))
+}
+
+// ForkSchedule describes the forks of a chain, to map context bytes (fork digests) to the types of a fork.
+type ForkSchedule struct {
+	Spec                  *beacon.Spec
+	GenesisValidatorsRoot beacon.Root
+}
+
+// Forks lists the forks in order: phase0 and Altair. See altair.ConfigFor for the Altair version and epoch.
+func (f *ForkSchedule) Forks() []Fork {
+	alt := altair.ConfigFor(f.Spec)
+	return []Fork{
+		{Name: "phase0", Version: f.Spec.GENESIS_FORK_VERSION, Epoch: beacon.GENESIS_EPOCH, BlockCodec: phase0BlockCodec},
+		{Name: "altair", Version: alt.ForkVersion, Epoch: alt.ForkEpoch, BlockCodec: altairBlockCodec},
+	}
+}
+
+// Digest computes the fork digest of the given fork
+func (f *ForkSchedule) Digest(fork *Fork) beacon.ForkDigest {
+	return beacon.ComputeForkDigest(fork.Version, f.GenesisValidatorsRoot)
+}
+
+// AtSlot returns the fork that is active at the given slot
+func (f *ForkSchedule) AtSlot(slot beacon.Slot) Fork {
+	epoch := f.Spec.SlotToEpoch(slot)
+	forks := f.Forks()
+	out := forks[0]
+	for _, fork := range forks[1:] {
+		if fork.Epoch <= epoch {
+			out = fork
+		}
+	}
+	return out
+}
+
+// ByDigest finds the fork with the given digest
+func (f *ForkSchedule) ByDigest(digest []byte) (Fork, error) {
+	for _, fork := range f.Forks() {
+		d := f.Digest(&fork)
+		if string(d[:]) == string(digest) {
+			return fork, nil
+		}
+	}
+	return Fork{}, fmt.Errorf("unknown fork digest %x", digest)
+}
+
+// BlockCodec returns the codec of the signed blocks of the fork with the given digest
+func (f *ForkSchedule) BlockCodec(contextBytes []byte) (reqresp.Codec, error) {
+	fork, err := f.ByDigest(contextBytes)
+	if err != nil {
+		return nil, err
+	}
+	return fork.BlockCodec(f.Spec), nil
+}
+
+// MaxBlockCodec returns a codec that bounds the size of the blocks of all forks
+func (f *ForkSchedule) MaxBlockCodec() reqresp.Codec {
+	var out reqresp.Codec
+	for _, fork := range f.Forks() {
+		c := fork.BlockCodec(f.Spec)
+		if out == nil || c.MaxByteLen() > out.MaxByteLen() {
+			out = c
+		}
+	}
+	return out
+}
+
+// ChainForkSchedule builds the fork schedule of the chain that the given state is part of
+func ChainForkSchedule(spec *beacon.Spec, state *beacon.BeaconStateView) (*ForkSchedule, error) {
+	root, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return nil, err
+	}
+	return &ForkSchedule{Spec: spec, GenesisValidatorsRoot: root}, nil
+}
+
+// HeadForkSchedule builds the fork schedule of the given chain, based on its head state
+func HeadForkSchedule(ctx context.Context, spec *beacon.Spec, ch chain.FullChain) (*ForkSchedule, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain head: %v", err)
+	}
+	state, err := head.State(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get head state: %v", err)
+	}
+	return ChainForkSchedule(spec, state)
+}
@@ -1,8 +1,10 @@
 package methods
 
 import (
+	"fmt"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
 	"github.com/protolambda/zrnt/eth2/beacon"
+	"strconv"
 )
 
 var GoodbyeRPCv1 = reqresp.RPCMethod{
@@ -11,3 +13,50 @@ var GoodbyeRPCv1 = reqresp.RPCMethod{
 	ResponseChunkCodec:        reqresp.NewSSZCodec(func() reqresp.SerDes { return new(beacon.Goodbye) }, 8, 8),
 	DefaultResponseChunkCount: 0,
 }
+
+// Goodbye reasons defined in the spec. Reasons 128 and up are client specific.
+const (
+	GoodbyeClientShutdown    beacon.Goodbye = 1
+	GoodbyeIrrelevantNetwork beacon.Goodbye = 2
+	GoodbyeFaultError        beacon.Goodbye = 3
+)
+
+// Client specific goodbye reasons that are commonly used
+const (
+	GoodbyeUnableToVerifyNetwork beacon.Goodbye = 128
+	GoodbyeTooManyPeers          beacon.Goodbye = 129
+	GoodbyeBadScore              beacon.Goodbye = 250
+	GoodbyeBanned                beacon.Goodbye = 251
+)
+
+var goodbyeReasonNames = map[beacon.Goodbye]string{
+	GoodbyeClientShutdown:        "client-shutdown",
+	GoodbyeIrrelevantNetwork:     "irrelevant-network",
+	GoodbyeFaultError:            "fault-error",
+	GoodbyeUnableToVerifyNetwork: "unable-to-verify-network",
+	GoodbyeTooManyPeers:          "too-many-peers",
+	GoodbyeBadScore:              "bad-score",
+	GoodbyeBanned:                "banned",
+}
+
+// GoodbyeReasonName returns the name of a goodbye reason, or "unknown" if the reason has no name.
+func GoodbyeReasonName(reason beacon.Goodbye) string {
+	if name, ok := goodbyeReasonNames[reason]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseGoodbyeReason parses a goodbye reason, by name or number.
+func ParseGoodbyeReason(v string) (beacon.Goodbye, error) {
+	for reason, name := range goodbyeReasonNames {
+		if v == name {
+			return reason, nil
+		}
+	}
+	n, err := strconv.ParseUint(v, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognized goodbye reason: %s", v)
+	}
+	return beacon.Goodbye(n), nil
+}
@@ -1,6 +1,7 @@
 package methods
 
 import (
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/rumor/p2p/rpc/reqresp"
 	"github.com/protolambda/zrnt/eth2/beacon"
 )
@@ -11,3 +12,10 @@ var MetaDataRPCv1 = reqresp.RPCMethod{
 	ResponseChunkCodec:        reqresp.NewSSZCodec(func() reqresp.SerDes { return new(beacon.MetaData) }, beacon.MetadataByteLen, beacon.MetadataByteLen),
 	DefaultResponseChunkCount: 1,
 }
+
+var MetaDataRPCv2 = reqresp.RPCMethod{
+	Protocol:                  "/eth2/beacon_chain/req/metadata/2/ssz",
+	RequestCodec:              (*reqresp.SSZCodec)(nil), // no request data, just empty bytes.
+	ResponseChunkCodec:        reqresp.NewSSZCodec(func() reqresp.SerDes { return new(altair.MetaDataV2) }, altair.MetadataV2ByteLen, altair.MetadataV2ByteLen),
+	DefaultResponseChunkCount: 1,
+}
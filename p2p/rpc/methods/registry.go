@@ -0,0 +1,148 @@
+package methods
+
+import (
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"sync"
+)
+
+// MethodEntry declares an RPC method version, and how to build it.
+type MethodEntry struct {
+	// Name of the method in commands, e.g. "blocks-by-range"
+	Name string
+	// Name of the method in the protocol ID, e.g. "beacon_blocks_by_range"
+	ProtocolName string
+	// Version of the method in the protocol ID, e.g. "1"
+	Version string
+	// If response chunks are prefixed with context bytes: the fork digest of the chunk contents
+	ContextBytes bool
+	// Method builds the RPC method, with its request and response codecs, for the given fork schedule
+	Method func(forks *ForkSchedule) *reqresp.RPCMethod
+}
+
+// Route is the command name of the method version: the name for version 1, the name with a version suffix otherwise.
+func (e *MethodEntry) Route() string {
+	if e.Version == "1" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s-v%s", e.Name, e.Version)
+}
+
+// Protocol is the protocol ID of the method, without compression suffix
+func (e *MethodEntry) Protocol() protocol.ID {
+	return protocol.ID(fmt.Sprintf("%s%s/%s/ssz", reqresp.ProtocolPrefix, e.ProtocolName, e.Version))
+}
+
+// Registry keeps track of the known RPC methods, in order of registration.
+type Registry struct {
+	lock    sync.RWMutex
+	entries []*MethodEntry
+}
+
+func NewRegistry(entries ...MethodEntry) *Registry {
+	r := new(Registry)
+	for _, e := range entries {
+		if err := r.Register(e); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a method version. It errors if the method version is already registered.
+func (r *Registry) Register(e MethodEntry) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, x := range r.entries {
+		if x.Route() == e.Route() || x.Protocol() == e.Protocol() {
+			return fmt.Errorf("method %s is already registered", e.Route())
+		}
+	}
+	r.entries = append(r.entries, &e)
+	return nil
+}
+
+// Get finds a method version by its route, see MethodEntry.Route
+func (r *Registry) Get(route string) (*MethodEntry, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, e := range r.entries {
+		if e.Route() == route {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Find finds a method by name and version
+func (r *Registry) Find(name string, version string) (*MethodEntry, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, e := range r.entries {
+		if e.Name == name && e.Version == version {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown RPC method %s version %s", name, version)
+}
+
+// ByProtocol finds a method version by its protocol ID. The protocol ID may have a compression suffix.
+func (r *Registry) ByProtocol(p protocol.ID) (*MethodEntry, bool) {
+	name, version, _, ok := reqresp.ParseProtocolID(p)
+	if !ok {
+		return nil, false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, e := range r.entries {
+		if e.ProtocolName == name && e.Version == version {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Entries returns all registered method versions
+func (r *Registry) Entries() []*MethodEntry {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]*MethodEntry(nil), r.entries...)
+}
+
+// Routes returns the routes of all registered method versions
+func (r *Registry) Routes() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	out := make([]string, len(r.entries))
+	for i, e := range r.entries {
+		out[i] = e.Route()
+	}
+	return out
+}
+
+func staticMethod(m *reqresp.RPCMethod) func(forks *ForkSchedule) *reqresp.RPCMethod {
+	return func(forks *ForkSchedule) *reqresp.RPCMethod {
+		return m
+	}
+}
+
+func specMethod(fn func(spec *beacon.Spec) *reqresp.RPCMethod) func(forks *ForkSchedule) *reqresp.RPCMethod {
+	return func(forks *ForkSchedule) *reqresp.RPCMethod {
+		return fn(forks.Spec)
+	}
+}
+
+// DefaultRegistry has all the RPC methods supported by rumor
+var DefaultRegistry = NewRegistry(
+	MethodEntry{Name: "goodbye", ProtocolName: "goodbye", Version: "1", Method: staticMethod(&GoodbyeRPCv1)},
+	MethodEntry{Name: "status", ProtocolName: "status", Version: "1", Method: staticMethod(&StatusRPCv1)},
+	MethodEntry{Name: "ping", ProtocolName: "ping", Version: "1", Method: staticMethod(&PingRPCv1)},
+	MethodEntry{Name: "metadata", ProtocolName: "metadata", Version: "1", Method: staticMethod(&MetaDataRPCv1)},
+	MethodEntry{Name: "metadata", ProtocolName: "metadata", Version: "2", Method: staticMethod(&MetaDataRPCv2)},
+	MethodEntry{Name: "blocks-by-range", ProtocolName: "beacon_blocks_by_range", Version: "1", Method: specMethod(BlocksByRangeRPCv1)},
+	MethodEntry{Name: "blocks-by-root", ProtocolName: "beacon_blocks_by_root", Version: "1", Method: specMethod(BlocksByRootRPCv1)},
+	MethodEntry{Name: "blocks-by-range", ProtocolName: "beacon_blocks_by_range", Version: "2", ContextBytes: true, Method: BlocksByRangeRPCv2},
+	MethodEntry{Name: "blocks-by-root", ProtocolName: "beacon_blocks_by_root", Version: "2", ContextBytes: true, Method: BlocksByRootRPCv2},
+)
@@ -0,0 +1,206 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"io"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	// The stream was opened or accepted
+	StreamOpen EventType = "open"
+	// The raw request, as sent on the wire: length prefix and (compressed) payload
+	StreamRequest EventType = "request"
+	// A response chunk, with the decompressed chunk contents
+	StreamChunk EventType = "chunk"
+	// The response could not be parsed, see the reason
+	StreamInvalid EventType = "invalid"
+	// The stream ended normally
+	StreamClose EventType = "close"
+	// The stream was reset, or failed
+	StreamReset EventType = "reset"
+)
+
+// Event is a recorded event of an RPC stream. Every event includes the stream ID, protocol, peer and direction,
+// so events can be processed without tracking the stream.
+type Event struct {
+	// ID of the stream, unique within the recording
+	Stream   uint64
+	Time     time.Time
+	Type     EventType
+	Protocol protocol.ID
+	Peer     peer.ID
+	Outbound bool
+	// Raw request bytes, or the decompressed contents of a response chunk
+	Data []byte
+	// Result code and index of a response chunk
+	Result     reqresp.ResponseCode
+	ChunkIndex uint64
+	// Context bytes of a response chunk, if any
+	ContextBytes []byte
+	// Why the stream was closed or reset, or why the response is invalid
+	Reason string
+}
+
+func (ev *Event) Direction() string {
+	if ev.Outbound {
+		return "outbound"
+	}
+	return "inbound"
+}
+
+type eventJSON struct {
+	Stream       uint64               `json:"stream"`
+	Time         time.Time            `json:"time"`
+	Type         EventType            `json:"type"`
+	Protocol     protocol.ID          `json:"protocol"`
+	Peer         peer.ID              `json:"peer"`
+	Direction    string               `json:"direction"`
+	Data         string               `json:"data,omitempty"`
+	Result       reqresp.ResponseCode `json:"result"`
+	ChunkIndex   uint64               `json:"chunk_index"`
+	ContextBytes string               `json:"context_bytes,omitempty"`
+	Reason       string               `json:"reason,omitempty"`
+}
+
+func (ev *Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&eventJSON{
+		Stream:       ev.Stream,
+		Time:         ev.Time,
+		Type:         ev.Type,
+		Protocol:     ev.Protocol,
+		Peer:         ev.Peer,
+		Direction:    ev.Direction(),
+		Data:         hex.EncodeToString(ev.Data),
+		Result:       ev.Result,
+		ChunkIndex:   ev.ChunkIndex,
+		ContextBytes: hex.EncodeToString(ev.ContextBytes),
+		Reason:       ev.Reason,
+	})
+}
+
+func (ev *Event) UnmarshalJSON(b []byte) error {
+	var v eventJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	data, err := hex.DecodeString(v.Data)
+	if err != nil {
+		return fmt.Errorf("invalid event data: %v", err)
+	}
+	contextBytes, err := hex.DecodeString(v.ContextBytes)
+	if err != nil {
+		return fmt.Errorf("invalid event context bytes: %v", err)
+	}
+	*ev = Event{
+		Stream:       v.Stream,
+		Time:         v.Time,
+		Type:         v.Type,
+		Protocol:     v.Protocol,
+		Peer:         v.Peer,
+		Outbound:     v.Direction == "outbound",
+		Data:         data,
+		Result:       v.Result,
+		ChunkIndex:   v.ChunkIndex,
+		ContextBytes: contextBytes,
+		Reason:       v.Reason,
+	}
+	return nil
+}
+
+// Recording formats
+const (
+	// JSON lines, with hex-encoded data
+	FormatJSONL = "jsonl"
+	// Gob encoded events
+	FormatBinary = "binary"
+)
+
+// FormatOf guesses the format of a recording file by its extension: binary for .bin and .gob files, JSON lines otherwise.
+func FormatOf(path string) string {
+	if strings.HasSuffix(path, ".bin") || strings.HasSuffix(path, ".gob") {
+		return FormatBinary
+	}
+	return FormatJSONL
+}
+
+type EventWriter interface {
+	Write(ev *Event) error
+}
+
+type EventReader interface {
+	// Next reads the next event, and returns io.EOF when there are no events left.
+	Next() (*Event, error)
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonWriter) Write(ev *Event) error {
+	return w.enc.Encode(ev)
+}
+
+type jsonReader struct {
+	dec *json.Decoder
+}
+
+func (r *jsonReader) Next() (*Event, error) {
+	var ev Event
+	if err := r.dec.Decode(&ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+type binaryWriter struct {
+	enc *gob.Encoder
+}
+
+func (w *binaryWriter) Write(ev *Event) error {
+	return w.enc.Encode(ev)
+}
+
+type binaryReader struct {
+	dec *gob.Decoder
+}
+
+func (r *binaryReader) Next() (*Event, error) {
+	var ev Event
+	if err := r.dec.Decode(&ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+func NewEventWriter(w io.Writer, format string) (EventWriter, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatBinary:
+		return &binaryWriter{enc: gob.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized recording format: %s", format)
+	}
+}
+
+func NewEventReader(r io.Reader, format string) (EventReader, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonReader{dec: json.NewDecoder(bufio.NewReader(r))}, nil
+	case FormatBinary:
+		return &binaryReader{dec: gob.NewDecoder(bufio.NewReader(r))}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized recording format: %s", format)
+	}
+}
@@ -0,0 +1,237 @@
+package recording
+
+import (
+	"context"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/protolambda/rumor/p2p/custom"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// Requests larger than this are truncated in the recording
+const maxRecordedRequestSize = 1 << 20
+
+// Max number of response chunks that are parsed, enough for the largest blocks-by-range response
+const maxRecordedChunks = 1024
+
+// Recorder records the RPC streams of a host, by intercepting the streams of the methods in the registry.
+type Recorder struct {
+	Registry *methods.Registry
+	// The fork schedule to build the methods with, to know the response chunk size limits
+	Forks *methods.ForkSchedule
+
+	lock   sync.Mutex
+	w      EventWriter
+	nextID uint64
+	err    error
+}
+
+func NewRecorder(w EventWriter, registry *methods.Registry, forks *methods.ForkSchedule) *Recorder {
+	return &Recorder{Registry: registry, Forks: forks, w: w}
+}
+
+func (r *Recorder) write(ev *Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.w.Write(ev)
+}
+
+// Err returns the first error that was encountered when writing events, if any. Events are not written after an error.
+func (r *Recorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *Recorder) InterceptStream(s network.Stream, outbound bool) network.Stream {
+	entry, ok := r.Registry.ByProtocol(s.Protocol())
+	if !ok {
+		return s
+	}
+	_, _, encoding, _ := reqresp.ParseProtocolID(s.Protocol())
+	comp, err := reqresp.DefaultCompressions.ByEncoding(encoding)
+	r.lock.Lock()
+	id := r.nextID
+	r.nextID += 1
+	r.lock.Unlock()
+
+	rs := &recordStream{
+		Stream:   s,
+		r:        r,
+		outbound: outbound,
+		template: Event{Stream: id, Protocol: s.Protocol(), Peer: s.Conn().RemotePeer(), Outbound: outbound},
+	}
+	rs.emit(Event{Type: StreamOpen})
+	if err != nil {
+		// the recording still has the raw request, but the response cannot be parsed
+		rs.emit(Event{Type: StreamInvalid, Reason: err.Error()})
+		return rs
+	}
+	m := entry.Method(r.Forks)
+	pr, pw := io.Pipe()
+	rs.resp = pw
+	rs.parsed = make(chan struct{})
+	go rs.parseResponse(pr, m, comp)
+	return rs
+}
+
+var _ custom.StreamInterceptor = (*Recorder)(nil)
+
+// recordStream records the request and response data as it passes through the stream.
+type recordStream struct {
+	network.Stream
+	r        *Recorder
+	outbound bool
+	template Event
+
+	lock      sync.Mutex
+	request   []byte
+	requested bool
+	// response data is fed to the response parser, which emits the chunk events
+	resp   *io.PipeWriter
+	parsed chan struct{}
+	ended  bool
+}
+
+func (s *recordStream) emit(ev Event) {
+	out := s.template
+	out.Time = time.Now()
+	out.Type = ev.Type
+	out.Data = ev.Data
+	out.Result = ev.Result
+	out.ChunkIndex = ev.ChunkIndex
+	out.ContextBytes = ev.ContextBytes
+	out.Reason = ev.Reason
+	s.r.write(&out)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (s *recordStream) parseResponse(r *io.PipeReader, m *reqresp.RPCMethod, comp reqresp.Compression) {
+	defer close(s.parsed)
+	handle := reqresp.ContextResponseChunkHandler(func(ctx context.Context, chunkIndex uint64, chunkSize uint64,
+		result reqresp.ResponseCode, contextBytes []byte, r io.Reader, w io.Writer) error {
+		data, err := ioutil.ReadAll(io.LimitReader(r, int64(chunkSize)))
+		if err != nil {
+			return err
+		}
+		s.emit(Event{Type: StreamChunk, Data: data, Result: result, ChunkIndex: chunkIndex, ContextBytes: contextBytes})
+		return nil
+	}).MakeResponseHandler(maxRecordedChunks, m.ResponseChunkCodec.MaxByteLen(), m.ContextBytesLen, comp)
+	if err := handle(context.Background(), r, nopWriteCloser{ioutil.Discard}); err != nil && err != io.ErrClosedPipe {
+		s.emit(Event{Type: StreamInvalid, Reason: err.Error()})
+	}
+	// unblock the stream if the response is invalid, the remaining response data is ignored
+	_ = r.CloseWithError(io.ErrClosedPipe)
+}
+
+func (s *recordStream) onRequestData(b []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.requested {
+		return
+	}
+	space := maxRecordedRequestSize - len(s.request)
+	if space > len(b) {
+		space = len(b)
+	}
+	s.request = append(s.request, b[:space]...)
+}
+
+// flushRequest emits the request event, if it was not emitted yet. Must hold the lock.
+func (s *recordStream) flushRequest() {
+	if s.requested {
+		return
+	}
+	s.requested = true
+	s.emit(Event{Type: StreamRequest, Data: s.request})
+}
+
+func (s *recordStream) onResponseData(b []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.flushRequest()
+	if s.resp != nil && !s.ended {
+		_, _ = s.resp.Write(b)
+	}
+}
+
+// end emits the final event of the stream, after the response parser processed all the response data.
+func (s *recordStream) end(typ EventType, reason string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.flushRequest()
+	if s.resp != nil {
+		_ = s.resp.Close()
+		<-s.parsed
+	}
+	s.emit(Event{Type: typ, Reason: reason})
+}
+
+func (s *recordStream) Read(b []byte) (int, error) {
+	n, err := s.Stream.Read(b)
+	if n > 0 {
+		if s.outbound {
+			s.onResponseData(b[:n])
+		} else {
+			s.onRequestData(b[:n])
+		}
+	}
+	if err != nil && s.outbound {
+		if err == io.EOF {
+			s.end(StreamClose, "eof")
+		} else {
+			s.end(StreamReset, err.Error())
+		}
+	}
+	return n, err
+}
+
+func (s *recordStream) Write(b []byte) (int, error) {
+	n, err := s.Stream.Write(b)
+	if n > 0 {
+		if s.outbound {
+			s.onRequestData(b[:n])
+		} else {
+			s.onResponseData(b[:n])
+		}
+	}
+	if err != nil {
+		s.end(StreamReset, err.Error())
+	}
+	return n, err
+}
+
+func (s *recordStream) Close() error {
+	if s.outbound {
+		// The outbound side closes for writing after sending the request, the response is still to come.
+		s.lock.Lock()
+		s.flushRequest()
+		s.lock.Unlock()
+	} else {
+		s.end(StreamClose, "closed")
+	}
+	return s.Stream.Close()
+}
+
+func (s *recordStream) Reset() error {
+	s.end(StreamReset, "reset")
+	return s.Stream.Reset()
+}
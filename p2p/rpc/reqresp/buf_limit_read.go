@@ -26,6 +26,14 @@ func NewBufLimitReader(rd io.Reader, size int, limit int) *BufLimitReader {
 	return r
 }
 
+// Reset discards any buffered data, resets the limit, and switches the buffered reader to read from rd.
+func (b *BufLimitReader) Reset(rd io.Reader) {
+	b.rd = rd
+	b.r, b.w = 0, 0
+	b.N = 0
+	b.PerRead = false
+}
+
 var errNegativeRead = errors.New("reader returned negative count from Read")
 
 // Read reads data into p.
@@ -1,9 +1,13 @@
 package reqresp
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/golang/snappy"
 	"io"
+	"math"
+	"strings"
+	"sync"
 )
 
 type Compression interface {
@@ -41,3 +45,224 @@ func (c SnappyCompression) MaxEncodedLen(msgLen uint64) (uint64, error) {
 func (c SnappyCompression) Name() string {
 	return "snappy"
 }
+
+// EncodedLimit is the maximum number of bytes to read for a payload of the given size, with optional compression.
+func EncodedLimit(comp Compression, size uint64) (int, error) {
+	if comp != nil {
+		v, err := comp.MaxEncodedLen(size)
+		if err != nil {
+			return 0, err
+		}
+		size = v
+	}
+	if size > math.MaxInt32 {
+		return 0, fmt.Errorf("encoded size %d is too large to read", size)
+	}
+	return int(size), nil
+}
+
+// Frame size of the length-framed compression, like the maximum snappy chunk size.
+const lengthFrameSize = 1 << 16
+
+// Size of the uvarint frame header, enough for a length of up to lengthFrameSize.
+const lengthFrameHeaderSize = 3
+
+// LengthFramedCompression does not compress, but splits the data in frames that are each prefixed with a uvarint length.
+// It is an experimental encoding, for testing how peers deal with encodings other than snappy.
+type LengthFramedCompression struct{}
+
+func (c LengthFramedCompression) Decompress(reader io.Reader) io.Reader {
+	return &lengthFrameReader{r: reader}
+}
+
+func (c LengthFramedCompression) Compress(w io.WriteCloser) io.WriteCloser {
+	return &lengthFrameWriter{w: w}
+}
+
+func (c LengthFramedCompression) MaxEncodedLen(msgLen uint64) (uint64, error) {
+	frames := msgLen / lengthFrameSize
+	if msgLen%lengthFrameSize != 0 {
+		frames += 1
+	}
+	overhead := frames * lengthFrameHeaderSize
+	if msgLen > math.MaxUint64-overhead {
+		return 0, fmt.Errorf("message length %d is too large to frame", msgLen)
+	}
+	return msgLen + overhead, nil
+}
+
+func (c LengthFramedCompression) Name() string {
+	return "length"
+}
+
+type lengthFrameReader struct {
+	r         io.Reader
+	remaining uint64
+}
+
+func (f *lengthFrameReader) ReadByte() (byte, error) {
+	var out [1]byte
+	if _, err := io.ReadFull(f.r, out[:]); err != nil {
+		return 0, err
+	}
+	return out[0], nil
+}
+
+func (f *lengthFrameReader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if f.remaining == 0 {
+		size, err := binary.ReadUvarint(f)
+		if err != nil {
+			return 0, err
+		}
+		if size == 0 || size > lengthFrameSize {
+			return 0, fmt.Errorf("invalid frame length %d", size)
+		}
+		f.remaining = size
+	}
+	if uint64(len(p)) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, err = f.r.Read(p)
+	f.remaining -= uint64(n)
+	if err == io.EOF && f.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+type lengthFrameWriter struct {
+	w   io.WriteCloser
+	buf []byte
+}
+
+func (f *lengthFrameWriter) flush() error {
+	if len(f.buf) == 0 {
+		return nil
+	}
+	var header [binary.MaxVarintLen64]byte
+	hn := binary.PutUvarint(header[:], uint64(len(f.buf)))
+	if _, err := f.w.Write(header[:hn]); err != nil {
+		return err
+	}
+	if _, err := f.w.Write(f.buf); err != nil {
+		return err
+	}
+	f.buf = f.buf[:0]
+	return nil
+}
+
+func (f *lengthFrameWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		space := lengthFrameSize - len(f.buf)
+		if space > len(p) {
+			space = len(p)
+		}
+		f.buf = append(f.buf, p[:space]...)
+		p = p[space:]
+		n += space
+		if len(f.buf) == lengthFrameSize {
+			if err := f.flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close flushes the last frame. Like the snappy writer, it does not close the underlying writer.
+func (f *lengthFrameWriter) Close() error {
+	return f.flush()
+}
+
+// PassthroughCompression does not transform the data at all, but is named like a compression.
+// It is used to offer peers encodings that they do not know, e.g. "ssz_foobar".
+type PassthroughCompression struct {
+	Suffix string
+}
+
+func (c PassthroughCompression) Decompress(reader io.Reader) io.Reader {
+	return reader
+}
+
+func (c PassthroughCompression) Compress(w io.WriteCloser) io.WriteCloser {
+	return &noCloseWriter{w: w}
+}
+
+func (c PassthroughCompression) MaxEncodedLen(msgLen uint64) (uint64, error) {
+	return msgLen, nil
+}
+
+func (c PassthroughCompression) Name() string {
+	return c.Suffix
+}
+
+// CompressionRegistry keeps track of the known compressions, by name.
+type CompressionRegistry struct {
+	lock         sync.RWMutex
+	compressions map[string]Compression
+	names        []string
+}
+
+func NewCompressionRegistry(compressions ...Compression) *CompressionRegistry {
+	r := &CompressionRegistry{compressions: make(map[string]Compression)}
+	for _, c := range compressions {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a compression. It errors if a compression with the same name is already registered.
+func (r *CompressionRegistry) Register(c Compression) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	name := c.Name()
+	if name == "" || name == "none" || strings.ContainsAny(name, "/_") {
+		return fmt.Errorf("invalid compression name %q", name)
+	}
+	if _, ok := r.compressions[name]; ok {
+		return fmt.Errorf("compression %s is already registered", name)
+	}
+	r.compressions[name] = c
+	r.names = append(r.names, name)
+	return nil
+}
+
+// Get finds a compression by name
+func (r *CompressionRegistry) Get(name string) (Compression, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	c, ok := r.compressions[name]
+	return c, ok
+}
+
+// Names returns the names of all registered compressions, in order of registration
+func (r *CompressionRegistry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// ByEncoding finds the compression of the encoding part of a protocol ID, e.g. "ssz_snappy".
+// The plain "ssz" encoding is uncompressed, and results in a nil compression.
+func (r *CompressionRegistry) ByEncoding(encoding string) (Compression, error) {
+	if encoding == "ssz" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(encoding, "ssz_") {
+		return nil, fmt.Errorf("unrecognized encoding: %s", encoding)
+	}
+	name := encoding[len("ssz_"):]
+	c, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unrecognized compression of encoding %s", encoding)
+	}
+	return c, nil
+}
+
+// DefaultCompressions has all the compressions supported by rumor
+var DefaultCompressions = NewCompressionRegistry(SnappyCompression{}, LengthFramedCompression{})
@@ -0,0 +1,201 @@
+package reqresp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+var testCompressions = []Compression{
+	nil,
+	SnappyCompression{},
+	LengthFramedCompression{},
+	PassthroughCompression{Suffix: "foobar"},
+}
+
+func compName(comp Compression) string {
+	if comp == nil {
+		return "none"
+	}
+	return comp.Name()
+}
+
+// testPayload generates a payload of up to 3 snappy chunks / length frames, random or compressible.
+func testPayload(seed int64, size uint32, compressible bool) []byte {
+	data := make([]byte, size%(3*lengthFrameSize+100))
+	if !compressible {
+		rand.New(rand.NewSource(seed)).Read(data)
+	}
+	return data
+}
+
+func encodeWith(t *testing.T, comp Compression, data []byte) []byte {
+	var buf bytes.Buffer
+	if comp == nil {
+		buf.Write(data)
+		return buf.Bytes()
+	}
+	w := comp.Compress(&noCloseWriter{w: &buf})
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeWith(comp Compression, r io.Reader, size int) ([]byte, error) {
+	if comp != nil {
+		r = comp.Decompress(r)
+	}
+	out := make([]byte, size)
+	_, err := io.ReadFull(r, out)
+	return out, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestMaxEncodedLen(t *testing.T) {
+	for _, comp := range testCompressions {
+		comp := comp
+		t.Run(compName(comp), func(t *testing.T) {
+			prop := func(seed int64, size uint32, compressible bool) bool {
+				data := testPayload(seed, size, compressible)
+				enc := encodeWith(t, comp, data)
+				limit, err := EncodedLimit(comp, uint64(len(data)))
+				if err != nil {
+					t.Log(err)
+					return false
+				}
+				if len(enc) > limit {
+					t.Logf("encoded %d bytes to %d bytes, but max encoded length is %d", len(data), len(enc), limit)
+					return false
+				}
+				dec, err := decodeWith(comp, bytes.NewReader(enc), len(data))
+				if err != nil {
+					t.Log(err)
+					return false
+				}
+				return bytes.Equal(dec, data)
+			}
+			if err := quick.Check(prop, &quick.Config{MaxCount: 50}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMaxEncodedLenOverflow(t *testing.T) {
+	for _, comp := range []Compression{SnappyCompression{}, LengthFramedCompression{}} {
+		if _, err := comp.MaxEncodedLen(math.MaxUint64); err == nil {
+			t.Errorf("%s: expected error for max uint64 length", comp.Name())
+		}
+	}
+	if _, err := EncodedLimit(nil, math.MaxUint64); err == nil {
+		t.Error("expected error for limit that does not fit in an int")
+	}
+}
+
+func TestBufLimitReaderEnforcement(t *testing.T) {
+	for _, comp := range testCompressions {
+		comp := comp
+		t.Run(compName(comp), func(t *testing.T) {
+			prop := func(seed int64, size uint32, compressible bool, bufSize uint16) bool {
+				data := testPayload(seed, size, compressible)
+				enc := encodeWith(t, comp, data)
+				// trailing data of the stream, e.g. a next chunk, must never be consumed
+				stream := append(append([]byte{}, enc...), bytes.Repeat([]byte{0xff}, 1000)...)
+
+				limit, err := EncodedLimit(comp, uint64(len(data)))
+				if err != nil {
+					t.Log(err)
+					return false
+				}
+				src := &countingReader{r: bytes.NewReader(stream)}
+				blr := NewBufLimitReader(src, 1+int(bufSize), limit)
+				dec, err := decodeWith(comp, blr, len(data))
+				if err != nil {
+					t.Logf("failed to decode within limit %d: %v", limit, err)
+					return false
+				}
+				if !bytes.Equal(dec, data) {
+					t.Log("decoded data does not match")
+					return false
+				}
+				if src.n > limit {
+					t.Logf("consumed %d bytes, over the limit %d", src.n, limit)
+					return false
+				}
+
+				// a limit smaller than the encoding must never allow reading the full payload
+				if len(enc) == 0 {
+					return true
+				}
+				tight := len(enc) - 1
+				src = &countingReader{r: bytes.NewReader(stream)}
+				blr = NewBufLimitReader(src, 1+int(bufSize), tight)
+				if _, err := decodeWith(comp, blr, len(data)); err == nil {
+					t.Logf("decoded %d bytes from %d encoded bytes with limit %d", len(data), len(enc), tight)
+					return false
+				}
+				if src.n > tight {
+					t.Logf("consumed %d bytes, over the limit %d", src.n, tight)
+					return false
+				}
+				return true
+			}
+			if err := quick.Check(prop, &quick.Config{MaxCount: 50}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLengthFramedInvalidFrames(t *testing.T) {
+	comp := LengthFramedCompression{}
+	// zero-length frame
+	if _, err := ioutil.ReadAll(comp.Decompress(bytes.NewReader([]byte{0}))); err == nil {
+		t.Error("expected error on zero-length frame")
+	}
+	// frame larger than the frame size
+	if _, err := ioutil.ReadAll(comp.Decompress(bytes.NewReader([]byte{0x81, 0x80, 0x04}))); err == nil {
+		t.Error("expected error on oversized frame")
+	}
+	// truncated frame
+	if _, err := ioutil.ReadAll(comp.Decompress(bytes.NewReader([]byte{4, 1, 2}))); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected unexpected EOF on truncated frame, got %v", err)
+	}
+}
+
+func TestCompressionRegistry(t *testing.T) {
+	r := NewCompressionRegistry(SnappyCompression{})
+	if err := r.Register(SnappyCompression{}); err == nil {
+		t.Error("expected error on duplicate registration")
+	}
+	if err := r.Register(PassthroughCompression{Suffix: "a_b"}); err == nil {
+		t.Error("expected error on invalid name")
+	}
+	if c, err := r.ByEncoding("ssz"); err != nil || c != nil {
+		t.Errorf("expected no compression for ssz, got %v, %v", c, err)
+	}
+	if c, err := r.ByEncoding("ssz_snappy"); err != nil || c == nil || c.Name() != "snappy" {
+		t.Errorf("expected snappy compression, got %v, %v", c, err)
+	}
+	if _, err := r.ByEncoding("ssz_zstd"); err == nil {
+		t.Error("expected error on unknown compression")
+	}
+}
@@ -115,3 +115,14 @@ func StreamChunk(result ResponseCode, size uint64, r io.Reader, w io.Writer, com
 	}
 	return StreamHeaderAndPayload(size, r, w, comp)
 }
+
+// StreamChunkWithContext is like StreamChunk, but writes the given context bytes between the result code and the header.
+func StreamChunkWithContext(result ResponseCode, contextBytes []byte, size uint64, r io.Reader, w io.Writer, comp Compression) error {
+	if err := EncodeResult(result, w); err != nil {
+		return err
+	}
+	if _, err := w.Write(contextBytes); err != nil {
+		return fmt.Errorf("failed to write context bytes: %v", err)
+	}
+	return StreamHeaderAndPayload(size, r, w, comp)
+}
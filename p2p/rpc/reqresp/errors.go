@@ -0,0 +1,143 @@
+package reqresp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// ErrorKind classifies what went wrong in a request or response, to tell apart the different kinds of client bugs.
+type ErrorKind string
+
+const (
+	// The request or response did not complete in time.
+	TimeoutErrKind ErrorKind = "timeout"
+	// The length prefix of a request or response chunk is malformed, too small, or too large.
+	LengthPrefixErrKind ErrorKind = "length_prefix"
+	// The payload could not be decompressed, e.g. a bad snappy frame.
+	CompressionErrKind ErrorKind = "compression"
+	// The decompressed payload could not be decoded as SSZ.
+	DecodeErrKind ErrorKind = "ssz_decode"
+	// The result code of a response chunk is not known.
+	ResultCodeErrKind ErrorKind = "unknown_result_code"
+	// The response has more chunks than allowed.
+	ChunkCountErrKind ErrorKind = "too_many_chunks"
+)
+
+// RPCError is an error of a known kind.
+type RPCError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+func (e *RPCError) Unwrap() error {
+	return e.Err
+}
+
+func rpcErr(kind ErrorKind, format string, args ...interface{}) error {
+	return &RPCError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// ErrKind returns the kind of the error, and false if the error is not of a known kind.
+// Timeouts are recognized even if the error is not an RPCError.
+func ErrKind(err error) (ErrorKind, bool) {
+	if err == nil {
+		return "", false
+	}
+	var rErr *RPCError
+	if errors.As(err, &rErr) {
+		return rErr.Kind, true
+	}
+	if isTimeout(err) {
+		return TimeoutErrKind, true
+	}
+	return "", false
+}
+
+// errTrackReader remembers the first error, other than io.EOF, of the reader it wraps.
+type errTrackReader struct {
+	r   io.Reader
+	err error
+}
+
+func (t *errTrackReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && err != io.EOF && t.err == nil {
+		t.err = err
+	}
+	return n, err
+}
+
+// payloadReader reads a (decompressed) payload, and tracks the errors of the stream and the decompression,
+// to tell them apart from decoding errors of the payload itself.
+type payloadReader struct {
+	stream  *errTrackReader
+	decomp  *errTrackReader
+	payload io.Reader
+}
+
+func newPayloadReader(r io.Reader, comp Compression) *payloadReader {
+	out := &payloadReader{stream: &errTrackReader{r: r}}
+	out.payload = out.stream
+	if comp != nil {
+		out.decomp = &errTrackReader{r: comp.Decompress(out.stream)}
+		out.payload = out.decomp
+	}
+	return out
+}
+
+func (p *payloadReader) Read(b []byte) (int, error) {
+	return p.payload.Read(b)
+}
+
+// classify wraps an error that happened while processing the payload into an RPCError.
+// Stream errors are only classified if they are timeouts. Decompression errors are classified as such,
+// and all other errors are classified with the given fallback kind, if any.
+func (p *payloadReader) classify(err error, fallback ErrorKind) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := ErrKind(err); ok {
+		return err
+	}
+	if p.stream.err != nil {
+		if isTimeout(p.stream.err) {
+			return &RPCError{Kind: TimeoutErrKind, Err: err}
+		}
+		return err
+	}
+	if p.decomp != nil && p.decomp.err != nil {
+		return &RPCError{Kind: CompressionErrKind, Err: err}
+	}
+	if fallback == "" {
+		return err
+	}
+	return &RPCError{Kind: fallback, Err: err}
+}
+
+// classifyPayloadErr classifies the error like payloadReader.classify if the reader is a payload reader,
+// and only with the fallback kind otherwise.
+func classifyPayloadErr(r io.Reader, err error, fallback ErrorKind) error {
+	if p, ok := r.(*payloadReader); ok {
+		return p.classify(err, fallback)
+	}
+	if _, ok := ErrKind(err); !ok && err != nil && fallback != "" {
+		return &RPCError{Kind: fallback, Err: err}
+	}
+	return err
+}
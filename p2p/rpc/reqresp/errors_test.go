@@ -0,0 +1,168 @@
+package reqresp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+var testStatusMethod = RPCMethod{
+	Protocol:                  "/eth2/beacon_chain/req/status/1/ssz",
+	RequestCodec:              NewSSZCodec(func() SerDes { return new(beacon.Status) }, beacon.StatusByteLen, beacon.StatusByteLen),
+	ResponseChunkCodec:        NewSSZCodec(func() SerDes { return new(beacon.Status) }, beacon.StatusByteLen, beacon.StatusByteLen),
+	DefaultResponseChunkCount: 1,
+}
+
+// readTestResponse reads a status response, like a status request would.
+func readTestResponse(ctx context.Context, r io.Reader, comp Compression, maxChunks uint64) error {
+	m := &testStatusMethod
+	handle := ContextResponseChunkHandler(func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, contextBytes []byte, r io.Reader, w io.Writer) error {
+		chunk := &chRespHandler{m: m, r: r, result: result, chunkSize: chunkSize, chunkIndex: chunkIndex}
+		if result == SuccessCode {
+			var status beacon.Status
+			return chunk.ReadObj(&status)
+		}
+		_, err := chunk.ReadErrMsg()
+		return err
+	}).MakeResponseHandler(maxChunks, m.ResponseChunkCodec.MaxByteLen(), 0, comp)
+	return handle(ctx, r, &noCloseWriter{w: ioutil.Discard})
+}
+
+func testChunk(t testing.TB, code ResponseCode, payload []byte, comp Compression) []byte {
+	var buf bytes.Buffer
+	if err := EncodeChunk(code, bytes.NewReader(payload), &buf, comp); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+type timeoutReader struct {
+	r io.Reader
+}
+
+func (t *timeoutReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err == io.EOF {
+		return n, timeoutErr{}
+	}
+	return n, err
+}
+
+func TestResponseErrKinds(t *testing.T) {
+	status := make([]byte, beacon.StatusByteLen)
+	valid := testChunk(t, SuccessCode, status, SnappyCompression{})
+	corrupt := append([]byte{}, valid...)
+	corrupt[len(corrupt)-1] ^= 0xff // break the checksum of the snappy frame
+
+	cases := []struct {
+		name      string
+		data      []byte
+		comp      Compression
+		maxChunks uint64
+		kind      ErrorKind
+	}{
+		{"valid", valid, SnappyCompression{}, 1, ""},
+		{"valid error chunk", testChunk(t, ServerErrCode, []byte("oops"), SnappyCompression{}), SnappyCompression{}, 1, ""},
+		{"application error code", testChunk(t, 200, []byte("oops"), nil), nil, 1, ""},
+		{"reserved result code", testChunk(t, 3, status, nil), nil, 1, ResultCodeErrKind},
+		{"length prefix overflow", append([]byte{0}, bytes.Repeat([]byte{0xff}, 10)...), nil, 1, LengthPrefixErrKind},
+		{"chunk too large", testChunk(t, SuccessCode, make([]byte, beacon.StatusByteLen+1), nil), nil, 1, LengthPrefixErrKind},
+		{"error message too large", testChunk(t, ServerErrCode, make([]byte, MAX_ERR_SIZE+1), nil), nil, 1, LengthPrefixErrKind},
+		{"bad snappy frame", corrupt, SnappyCompression{}, 1, CompressionErrKind},
+		{"bad ssz", testChunk(t, SuccessCode, status[:10], SnappyCompression{}), SnappyCompression{}, 1, DecodeErrKind},
+		{"truncated ssz", []byte{0, beacon.StatusByteLen, 1, 2, 3}, nil, 1, DecodeErrKind},
+		{"too many chunks", append(append([]byte{}, valid...), valid...), SnappyCompression{}, 1, ChunkCountErrKind},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := readTestResponse(context.Background(), bytes.NewReader(c.data), c.comp, c.maxChunks)
+			kind, _ := ErrKind(err)
+			if kind != c.kind {
+				t.Errorf("expected error kind %q, got %q (err: %v)", c.kind, kind, err)
+			}
+			if c.kind == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestResponseTimeout(t *testing.T) {
+	valid := testChunk(t, SuccessCode, make([]byte, beacon.StatusByteLen), SnappyCompression{})
+	// the stream times out halfway the chunk
+	r := &timeoutReader{r: bytes.NewReader(valid[:len(valid)/2])}
+	err := readTestResponse(context.Background(), r, SnappyCompression{}, 1)
+	if kind, _ := ErrKind(err); kind != TimeoutErrKind {
+		t.Errorf("expected timeout, got %q (err: %v)", kind, err)
+	}
+
+	// the context deadline passed, and the stream was closed because of it
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err = readTestResponse(ctx, &timeoutReader{r: bytes.NewReader(nil)}, nil, 1)
+	if kind, _ := ErrKind(err); kind != TimeoutErrKind {
+		t.Errorf("expected timeout, got %q (err: %v)", kind, err)
+	}
+}
+
+func TestRequestErrKinds(t *testing.T) {
+	status := make([]byte, beacon.StatusByteLen)
+	var valid bytes.Buffer
+	if err := EncodeHeaderAndPayload(bytes.NewReader(status), &valid, SnappyCompression{}); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := append([]byte{}, valid.Bytes()...)
+	corrupt[len(corrupt)-1] ^= 0xff
+
+	cases := []struct {
+		name string
+		data []byte
+		kind ErrorKind
+	}{
+		{"valid", valid.Bytes(), ""},
+		{"length prefix overflow", bytes.Repeat([]byte{0xff}, 11), LengthPrefixErrKind},
+		{"missing length prefix", nil, LengthPrefixErrKind},
+		{"too small", []byte{10}, LengthPrefixErrKind},
+		{"too large", []byte{beacon.StatusByteLen + 1}, LengthPrefixErrKind},
+		{"bad snappy frame", corrupt, CompressionErrKind},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &testStatusMethod
+			reqLen, r, invalidInputErr := ReadRequestHeader(bytes.NewReader(c.data), SnappyCompression{},
+				m.RequestCodec.MinByteLen(), m.RequestCodec.MaxByteLen())
+			h := &chReqHandler{m: m, comp: SnappyCompression{}, reqLen: reqLen, r: r, invalidInputErr: invalidInputErr}
+			var status beacon.Status
+			err := h.ReadRequest(&status)
+			kind, _ := ErrKind(err)
+			if kind != c.kind {
+				t.Errorf("expected error kind %q, got %q (err: %v)", c.kind, kind, err)
+			}
+			if c.kind == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRPCErrorUnwrap(t *testing.T) {
+	inner := errors.New("inner")
+	err := &RPCError{Kind: DecodeErrKind, Err: inner}
+	if !errors.Is(err, inner) {
+		t.Error("expected RPCError to unwrap to the inner error")
+	}
+	if kind, ok := ErrKind(errors.Unwrap(err)); ok {
+		t.Errorf("expected no kind for plain error, got %q", kind)
+	}
+}
@@ -0,0 +1,88 @@
+//go:build go1.18
+// +build go1.18
+
+package reqresp
+
+import (
+	"bytes"
+	"context"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"testing"
+)
+
+func fuzzCompression(snappy bool) Compression {
+	if snappy {
+		return SnappyCompression{}
+	}
+	return nil
+}
+
+// FuzzResponseReader checks that any response is read without panics, within limits,
+// and that the classification of invalid responses is reproducible.
+func FuzzResponseReader(f *testing.F) {
+	status := make([]byte, beacon.StatusByteLen)
+	valid := testChunk(f, SuccessCode, status, SnappyCompression{})
+	f.Add(valid, true, uint8(1))
+	f.Add(append(append([]byte{}, valid...), valid...), true, uint8(2))
+	f.Add(testChunk(f, ServerErrCode, []byte("oops"), nil), false, uint8(1))
+	f.Add(testChunk(f, 3, status, nil), false, uint8(1))
+	f.Add(append([]byte{0}, bytes.Repeat([]byte{0xff}, 10)...), false, uint8(1))
+	f.Fuzz(func(t *testing.T, data []byte, snappy bool, maxChunks uint8) {
+		comp := fuzzCompression(snappy)
+		src := &countingReader{r: bytes.NewReader(data)}
+		err := readTestResponse(context.Background(), src, comp, uint64(maxChunks))
+		// every chunk: result byte, varint, and the encoded contents. And a final byte to check the end.
+		limit, lErr := EncodedLimit(comp, beacon.StatusByteLen)
+		if lErr != nil {
+			t.Fatal(lErr)
+		}
+		if max := int(maxChunks)*(1+10+limit) + 1; src.n > max {
+			t.Fatalf("read %d bytes, more than the max of %d", src.n, max)
+		}
+		again := readTestResponse(context.Background(), bytes.NewReader(data), comp, uint64(maxChunks))
+		kind, _ := ErrKind(err)
+		kindAgain, _ := ErrKind(again)
+		if kind != kindAgain {
+			t.Fatalf("error kind %q is not reproducible, got %q", kind, kindAgain)
+		}
+	})
+}
+
+// FuzzRequestReader checks that any request is read without panics, within limits,
+// and that the classification of invalid requests is reproducible.
+func FuzzRequestReader(f *testing.F) {
+	var valid bytes.Buffer
+	if err := EncodeHeaderAndPayload(bytes.NewReader(make([]byte, beacon.StatusByteLen)), &valid, SnappyCompression{}); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(valid.Bytes(), true)
+	f.Add([]byte{beacon.StatusByteLen + 1}, false)
+	f.Add(bytes.Repeat([]byte{0xff}, 11), true)
+	read := func(data []byte, comp Compression) (int, error) {
+		m := &testStatusMethod
+		src := &countingReader{r: bytes.NewReader(data)}
+		reqLen, r, invalidInputErr := ReadRequestHeader(src, comp, m.RequestCodec.MinByteLen(), m.RequestCodec.MaxByteLen())
+		h := &chReqHandler{m: m, comp: comp, reqLen: reqLen, r: r, invalidInputErr: invalidInputErr}
+		var status beacon.Status
+		err := h.ReadRequest(&status)
+		return src.n, err
+	}
+	f.Fuzz(func(t *testing.T, data []byte, snappy bool) {
+		comp := fuzzCompression(snappy)
+		n, err := read(data, comp)
+		limit, lErr := EncodedLimit(comp, beacon.StatusByteLen)
+		if lErr != nil {
+			t.Fatal(lErr)
+		}
+		// the varint is read byte by byte, and is at most 10 bytes
+		if max := 10 + limit; n > max {
+			t.Fatalf("read %d bytes, more than the max of %d", n, max)
+		}
+		_, again := read(data, comp)
+		kind, _ := ErrKind(err)
+		kindAgain, _ := ErrKind(again)
+		if kind != kindAgain {
+			t.Fatalf("error kind %q is not reproducible, got %q", kind, kindAgain)
+		}
+	})
+}
@@ -3,10 +3,11 @@ package reqresp
 import (
 	"context"
 	"encoding/binary"
-	"fmt"
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"io"
+	"sync/atomic"
+	"time"
 )
 
 const requestBufferSize = 2048
@@ -20,64 +21,96 @@ type StreamCtxFn func() context.Context
 
 // startReqRPC registers a request handler for the given protocol. Compression is optional and may be nil.
 func (handle RequestPayloadHandler) MakeStreamHandler(newCtx StreamCtxFn, comp Compression, minRequestContentSize, maxRequestContentSize uint64) network.StreamHandler {
+	return handle.MakeLimitedStreamHandler(newCtx, comp, minRequestContentSize, maxRequestContentSize, nil)
+}
+
+// MakeLimitedStreamHandler is like MakeStreamHandler, but only handles the streams that the limiter allows,
+// and counts the handled streams with the limiter. The limiter is optional and may be nil.
+func (handle RequestPayloadHandler) MakeLimitedStreamHandler(newCtx StreamCtxFn, comp Compression, minRequestContentSize, maxRequestContentSize uint64, limiter *StreamLimiter) network.StreamHandler {
 	return func(stream network.Stream) {
 		peerId := stream.Conn().RemotePeer()
+		if !limiter.acquire(peerId) {
+			_ = stream.Reset()
+			limiter.observe(&StreamEvent{Protocol: stream.Protocol(), Peer: peerId, Rejected: true})
+			return
+		}
+		defer limiter.release(peerId)
+
 		ctx, cancel := context.WithCancel(newCtx())
 		defer cancel()
 
 		go func() {
 			<-ctx.Done()
-			// TODO: should this be a stream reset?
-			_ = stream.Close() // Close stream after ctx closes.
+			// If the request was not handled in time, reset the stream to signal the failure to the peer.
+			// Otherwise close the stream after ctx closes.
+			if ctx.Err() == context.DeadlineExceeded {
+				_ = stream.Reset()
+			} else {
+				_ = stream.Close()
+			}
+		}()
+
+		counted := &countingStream{Stream: stream}
+		start := time.Now()
+		defer func() {
+			limiter.observe(&StreamEvent{
+				Protocol:     stream.Protocol(),
+				Peer:         peerId,
+				TimedOut:     ctx.Err() == context.DeadlineExceeded,
+				BytesRead:    atomic.LoadUint64(&counted.read),
+				BytesWritten: atomic.LoadUint64(&counted.written),
+				Duration:     time.Since(start),
+			})
 		}()
 
-		w := io.WriteCloser(stream)
+		w := io.WriteCloser(counted)
 		// If no request data, then do not even read a length from the stream.
 		if maxRequestContentSize == 0 {
 			handle(ctx, peerId, 0, nil, w, comp, nil)
 			return
 		}
 
-		var invalidInputErr error
+		blr := getRequestReader(counted)
+		defer putRequestReader(blr)
+		reqLen, r, invalidInputErr := readRequestHeader(blr, comp, minRequestContentSize, maxRequestContentSize)
+		handle(ctx, peerId, reqLen, r, w, comp, invalidInputErr)
+	}
+}
+
+// ReadRequestHeader reads and validates the length prefix of a request, and returns a reader of the request payload,
+// limited to the max encoded size of the payload. If the request is invalid, the RPCError is returned as invalidInputErr,
+// and the returned reader does not read anything.
+func ReadRequestHeader(r io.Reader, comp Compression, minRequestContentSize, maxRequestContentSize uint64) (reqLen uint64, payload io.Reader, invalidInputErr error) {
+	return readRequestHeader(NewBufLimitReader(r, requestBufferSize, 0), comp, minRequestContentSize, maxRequestContentSize)
+}
 
-		// TODO: pool this
-		blr := NewBufLimitReader(stream, requestBufferSize, 0)
-		blr.N = 1 // var ints need to be read byte by byte
-		blr.PerRead = true
-		reqLen, err := binary.ReadUvarint(blr)
-		blr.PerRead = false
-		if err != nil {
-			invalidInputErr = err
-		} else if reqLen < minRequestContentSize {
-			// Check against raw content size minimum (without compression applied)
-			invalidInputErr = fmt.Errorf("request length %d is unexpectedly small, request size minimum is %d", reqLen, minRequestContentSize)
-		} else if reqLen > maxRequestContentSize {
-			// Check against raw content size limit (without compression applied)
-			invalidInputErr = fmt.Errorf("request length %d exceeds request size limit %d", reqLen, maxRequestContentSize)
-		} else if comp != nil {
-			// Now apply compression adjustment for size limit, and use that as the limit for the buffered-limited-reader.
-			s, err := comp.MaxEncodedLen(maxRequestContentSize)
-			if err != nil {
-				invalidInputErr = err
-			} else {
-				maxRequestContentSize = s
-			}
-		}
-		// If the input is invalid, never read it.
-		if invalidInputErr != nil {
-			maxRequestContentSize = 0
-		}
-		if comp == nil {
-			blr.N = int(maxRequestContentSize)
+func readRequestHeader(blr *BufLimitReader, comp Compression, minRequestContentSize, maxRequestContentSize uint64) (reqLen uint64, payload io.Reader, invalidInputErr error) {
+	blr.N = 1 // var ints need to be read byte by byte
+	blr.PerRead = true
+	reqLen, err := binary.ReadUvarint(blr)
+	blr.PerRead = false
+	if err != nil {
+		if isTimeout(err) {
+			invalidInputErr = &RPCError{Kind: TimeoutErrKind, Err: err}
 		} else {
-			v, err := comp.MaxEncodedLen(maxRequestContentSize)
-			if err != nil {
-				blr.N = int(maxRequestContentSize)
-			} else {
-				blr.N = int(v)
-			}
+			invalidInputErr = rpcErr(LengthPrefixErrKind, "failed to read request length prefix: %v", err)
 		}
-		r := io.Reader(blr)
-		handle(ctx, peerId, reqLen, r, w, comp, invalidInputErr)
+	} else if reqLen < minRequestContentSize {
+		// Check against raw content size minimum (without compression applied)
+		invalidInputErr = rpcErr(LengthPrefixErrKind, "request length %d is unexpectedly small, request size minimum is %d", reqLen, minRequestContentSize)
+	} else if reqLen > maxRequestContentSize {
+		// Check against raw content size limit (without compression applied)
+		invalidInputErr = rpcErr(LengthPrefixErrKind, "request length %d exceeds request size limit %d", reqLen, maxRequestContentSize)
+	}
+	// If the input is invalid, never read it. Otherwise apply the compression adjustment
+	// to the size of the request, and use that as the limit for the buffered-limited-reader.
+	if invalidInputErr != nil {
+		blr.N = 0
+	} else if limit, err := EncodedLimit(comp, reqLen); err != nil {
+		invalidInputErr = rpcErr(LengthPrefixErrKind, "%v", err)
+		blr.N = 0
+	} else {
+		blr.N = limit
 	}
+	return reqLen, blr, invalidInputErr
 }
@@ -15,20 +15,41 @@ type ResponseChunkHandler func(ctx context.Context, chunkIndex uint64, chunkSize
 // ResponseHandler processes a response by internally processing chunks, any error is propagated up.
 type ResponseHandler func(ctx context.Context, r io.Reader, w io.WriteCloser) error
 
+// ContextResponseChunkHandler is like a ResponseChunkHandler, for methods that prefix successful response chunks with context bytes.
+// The context bytes are nil for chunks without context, i.e. error chunks.
+type ContextResponseChunkHandler func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, contextBytes []byte, r io.Reader, w io.Writer) error
+
 type OnRequested func()
 
 // MakeResponseHandler builds a ResponseHandler, which won't take more than maxChunkCount chunks, or chunk contents larger than maxChunkContentSize.
 // Compression is optional and may be nil. Chunks are processed by the given ResponseChunkHandler.
 func (handleChunk ResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64, maxChunkContentSize uint64, comp Compression) ResponseHandler {
+	return ContextResponseChunkHandler(func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, contextBytes []byte, r io.Reader, w io.Writer) error {
+		return handleChunk(ctx, chunkIndex, chunkSize, result, r, w)
+	}).MakeResponseHandler(maxChunkCount, maxChunkContentSize, 0, comp)
+}
+
+// MakeResponseHandler builds a ResponseHandler, like ResponseChunkHandler.MakeResponseHandler,
+// but reads contextBytesLen context bytes before the header of every successful response chunk.
+// Invalid responses result in an RPCError, to classify what the responding peer did wrong.
+func (handleChunk ContextResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64, maxChunkContentSize uint64, contextBytesLen uint64, comp Compression) ResponseHandler {
 	//		response  ::= <response_chunk>*
-	//		response_chunk  ::= <result> | <encoding-dependent-header> | <encoded-payload>
+	//		response_chunk  ::= <result> | <context-bytes> | <encoding-dependent-header> | <encoded-payload>
 	//		result    ::= “0” | “1” | “2” | [“128” ... ”255”]
+	//		context-bytes ::= empty, or the fork digest of the payload type for methods with context bytes, only for successful chunks
 	return func(ctx context.Context, r io.Reader, w io.WriteCloser) error {
 		defer w.Close()
 		if maxChunkCount == 0 {
 			return nil
 		}
 		blr := NewBufLimitReader(r, 1024, 0)
+		// stream errors are classified as timeouts when the context is done
+		streamErr := func(err error) error {
+			if ctx.Err() == context.DeadlineExceeded && !isTimeout(err) {
+				return &RPCError{Kind: TimeoutErrKind, Err: err}
+			}
+			return err
+		}
 		for chunkIndex := uint64(0); chunkIndex < maxChunkCount; chunkIndex++ {
 			blr.N = 1
 			resByte, err := blr.ReadByte()
@@ -36,37 +57,52 @@ func (handleChunk ResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64
 				return nil
 			}
 			if err != nil {
-				return fmt.Errorf("failed to read chunk %d result byte: %v", chunkIndex, err)
+				return streamErr(fmt.Errorf("failed to read chunk %d result byte: %w", chunkIndex, err))
+			}
+			isErrCode := resByte == InvalidReqCode || resByte == ServerErrCode || resByte >= 128
+			if resByte != uint8(SuccessCode) && !isErrCode {
+				return rpcErr(ResultCodeErrKind, "chunk %d has reserved result code %d", chunkIndex, resByte)
+			}
+			var contextBytes []byte
+			if contextBytesLen > 0 && resByte == uint8(SuccessCode) {
+				contextBytes = make([]byte, contextBytesLen)
+				blr.N = int(contextBytesLen)
+				if _, err := io.ReadFull(blr, contextBytes); err != nil {
+					return streamErr(fmt.Errorf("failed to read chunk %d context bytes: %w", chunkIndex, err))
+				}
 			}
 			// varints need to be read byte by byte.
 			blr.N = 1
 			blr.PerRead = true
 			chunkSize, err := binary.ReadUvarint(blr)
 			blr.PerRead = false
-			// TODO when input is incorrect, return a different type of error.
 			if err != nil {
-				return err
+				if isTimeout(err) || ctx.Err() == context.DeadlineExceeded {
+					return &RPCError{Kind: TimeoutErrKind, Err: err}
+				}
+				return rpcErr(LengthPrefixErrKind, "failed to read chunk %d length prefix: %v", chunkIndex, err)
 			}
-			if resByte == InvalidReqCode || resByte == ServerErrCode {
+			if isErrCode {
 				if chunkSize > MAX_ERR_SIZE {
-					return fmt.Errorf("chunk size %d of chunk %d exceeds error size limit %d", chunkSize, chunkIndex, MAX_ERR_SIZE)
-				}
-				blr.N = MAX_ERR_SIZE
-			} else {
-				if chunkSize > maxChunkContentSize {
-					return fmt.Errorf("chunk size %d of chunk %d exceeds chunk limit %d", chunkSize, chunkIndex, maxChunkContentSize)
+					return rpcErr(LengthPrefixErrKind, "chunk size %d of chunk %d exceeds error size limit %d", chunkSize, chunkIndex, MAX_ERR_SIZE)
 				}
-				blr.N = int(maxChunkContentSize)
+			} else if chunkSize > maxChunkContentSize {
+				return rpcErr(LengthPrefixErrKind, "chunk size %d of chunk %d exceeds chunk limit %d", chunkSize, chunkIndex, maxChunkContentSize)
+			}
+			// The encoded chunk contents may be larger than the chunk size, but not larger than the compression allows.
+			limit, err := EncodedLimit(comp, chunkSize)
+			if err != nil {
+				return rpcErr(LengthPrefixErrKind, "chunk %d: %v", chunkIndex, err)
 			}
-			cr := io.Reader(blr)
+			blr.N = limit
+			cr := newPayloadReader(blr, comp)
 			cw := w
 			if comp != nil {
-				cr = comp.Decompress(cr)
 				cw = comp.Compress(cw)
 			}
-			if err := handleChunk(ctx, chunkIndex, chunkSize, ResponseCode(resByte), cr, cw); err != nil {
+			if err := handleChunk(ctx, chunkIndex, chunkSize, ResponseCode(resByte), contextBytes, cr, cw); err != nil {
 				_ = cw.Close()
-				return err
+				return cr.classify(err, "")
 			}
 			if comp != nil {
 				if err := cw.Close(); err != nil {
@@ -74,6 +110,14 @@ func (handleChunk ResponseChunkHandler) MakeResponseHandler(maxChunkCount uint64
 				}
 			}
 		}
+		// The stream must end after the max amount of chunks.
+		blr.N = 1
+		if _, err := blr.ReadByte(); err != io.EOF {
+			if err != nil {
+				return streamErr(fmt.Errorf("failed to check for end of response: %w", err))
+			}
+			return rpcErr(ChunkCountErrKind, "response has more than the max of %d chunks", maxChunkCount)
+		}
 		return nil
 	}
 }
@@ -0,0 +1,167 @@
+package reqresp
+
+import (
+	"bytes"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StreamEvent describes an inbound stream after it was handled, or after it was rejected.
+type StreamEvent struct {
+	Protocol protocol.ID
+	Peer     peer.ID
+	// If the stream was reset right away, because of the concurrency limits
+	Rejected bool
+	// If the request was not handled before the deadline of the stream context
+	TimedOut bool
+	// Bytes read from and written to the stream
+	BytesRead    uint64
+	BytesWritten uint64
+	Duration     time.Duration
+}
+
+// StreamStats counts the inbound streams seen by a StreamLimiter
+type StreamStats struct {
+	Accepted uint64
+	Rejected uint64
+	TimedOut uint64
+	// Bytes read from and written to the accepted streams
+	BytesRead    uint64
+	BytesWritten uint64
+}
+
+// StreamLimiter caps the number of inbound streams that are handled concurrently, in total and per peer,
+// and counts the handled streams. Streams over the limit are reset right away.
+type StreamLimiter struct {
+	// Max concurrently handled streams. 0 for no limit.
+	MaxStreams int
+	// Max concurrently handled streams per peer. 0 for no limit.
+	MaxPeerStreams int
+	// Optional hook, called after each stream is handled or rejected, e.g. to log or to export metrics.
+	OnStream func(ev *StreamEvent)
+
+	lock   sync.Mutex
+	active int
+	peers  map[peer.ID]int
+
+	stats StreamStats
+}
+
+// Stats returns a snapshot of the stream counters
+func (l *StreamLimiter) Stats() StreamStats {
+	return StreamStats{
+		Accepted:     atomic.LoadUint64(&l.stats.Accepted),
+		Rejected:     atomic.LoadUint64(&l.stats.Rejected),
+		TimedOut:     atomic.LoadUint64(&l.stats.TimedOut),
+		BytesRead:    atomic.LoadUint64(&l.stats.BytesRead),
+		BytesWritten: atomic.LoadUint64(&l.stats.BytesWritten),
+	}
+}
+
+// Active returns the number of streams that are currently being handled
+func (l *StreamLimiter) Active() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.active
+}
+
+// acquire reserves a slot for a stream of the peer, if the limits allow it. A nil limiter allows any stream.
+func (l *StreamLimiter) acquire(id peer.ID) bool {
+	if l == nil {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.MaxStreams > 0 && l.active >= l.MaxStreams {
+		return false
+	}
+	if l.MaxPeerStreams > 0 && l.peers[id] >= l.MaxPeerStreams {
+		return false
+	}
+	if l.peers == nil {
+		l.peers = make(map[peer.ID]int)
+	}
+	l.active += 1
+	l.peers[id] += 1
+	return true
+}
+
+func (l *StreamLimiter) release(id peer.ID) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.active -= 1
+	if n := l.peers[id]; n <= 1 {
+		delete(l.peers, id)
+	} else {
+		l.peers[id] = n - 1
+	}
+}
+
+func (l *StreamLimiter) observe(ev *StreamEvent) {
+	if l == nil {
+		return
+	}
+	if ev.Rejected {
+		atomic.AddUint64(&l.stats.Rejected, 1)
+	} else {
+		atomic.AddUint64(&l.stats.Accepted, 1)
+		if ev.TimedOut {
+			atomic.AddUint64(&l.stats.TimedOut, 1)
+		}
+		atomic.AddUint64(&l.stats.BytesRead, ev.BytesRead)
+		atomic.AddUint64(&l.stats.BytesWritten, ev.BytesWritten)
+	}
+	if l.OnStream != nil {
+		l.OnStream(ev)
+	}
+}
+
+// countingStream counts the bytes read from and written to the stream
+type countingStream struct {
+	network.Stream
+	read    uint64
+	written uint64
+}
+
+func (s *countingStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	atomic.AddUint64(&s.read, uint64(n))
+	return n, err
+}
+
+func (s *countingStream) Write(p []byte) (int, error) {
+	n, err := s.Stream.Write(p)
+	atomic.AddUint64(&s.written, uint64(n))
+	return n, err
+}
+
+var requestReaderPool = sync.Pool{
+	New: func() interface{} {
+		return NewBufLimitReader(nil, requestBufferSize, 0)
+	},
+}
+
+func getRequestReader(r io.Reader) *BufLimitReader {
+	blr := requestReaderPool.Get().(*BufLimitReader)
+	blr.Reset(r)
+	return blr
+}
+
+func putRequestReader(blr *BufLimitReader) {
+	blr.Reset(nil)
+	requestReaderPool.Put(blr)
+}
+
+var responseBufPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
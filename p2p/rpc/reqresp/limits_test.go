@@ -0,0 +1,75 @@
+package reqresp
+
+import (
+	"bytes"
+	"context"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	mn, err := mocknet.FullMeshConnected(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := mn.Hosts()[0], mn.Hosts()[1]
+
+	limiter := &StreamLimiter{MaxPeerStreams: 1}
+	events := make(chan *StreamEvent, 10)
+	limiter.OnStream = func(ev *StreamEvent) {
+		events <- ev
+	}
+	release := make(chan struct{})
+	m := &testStatusMethod
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	newCtx := func() context.Context {
+		return ctx
+	}
+	a.SetStreamHandler(m.Protocol, m.MakeLimitedStreamHandler(newCtx, nil, limiter, func(ctx context.Context, peerId peer.ID, handler ChunkedRequestHandler) {
+		if _, err := handler.RawRequest(); err != nil {
+			t.Error(err)
+		}
+		<-release
+	}))
+
+	// the write error is ignored, the stream may already be reset when it is rejected
+	request := func() network.Stream {
+		s, err := b.NewStream(context.Background(), a.ID(), m.Protocol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = EncodeHeaderAndPayload(bytes.NewReader(make([]byte, 84)), s, nil)
+		return s
+	}
+	first := request()
+	// wait for the first stream to be handled
+	for limiter.Active() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := request()
+	if ev := <-events; !ev.Rejected {
+		t.Fatalf("expected second stream to be rejected, got %v", ev)
+	}
+	if _, err := ioutil.ReadAll(second); err == nil {
+		t.Error("expected rejected stream to be reset")
+	}
+	close(release)
+	ev := <-events
+	if ev.Rejected || ev.TimedOut || ev.BytesRead != 85 {
+		t.Errorf("unexpected event of handled stream: %v", ev)
+	}
+	_ = first.Close()
+
+	stats := limiter.Stats()
+	if stats.Accepted != 1 || stats.Rejected != 1 || stats.BytesRead != 85 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+	if limiter.Active() != 0 {
+		t.Errorf("expected no active streams, got %d", limiter.Active())
+	}
+}
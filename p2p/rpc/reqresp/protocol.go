@@ -0,0 +1,23 @@
+package reqresp
+
+import (
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"strings"
+)
+
+// The common prefix of all eth2 req-resp protocol IDs
+const ProtocolPrefix = "/eth2/beacon_chain/req/"
+
+// ParseProtocolID splits an eth2 req-resp protocol ID, formatted as
+// /eth2/beacon_chain/req/<method>/<version>/<encoding>, into its parts.
+func ParseProtocolID(p protocol.ID) (method string, version string, encoding string, ok bool) {
+	s := string(p)
+	if !strings.HasPrefix(s, ProtocolPrefix) {
+		return "", "", "", false
+	}
+	parts := strings.Split(s[len(ProtocolPrefix):], "/")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
@@ -85,10 +85,26 @@ func (c *SSZCodec) Alloc() SerDes {
 }
 
 type RPCMethod struct {
-	Protocol                  protocol.ID
-	RequestCodec              Codec
+	Protocol     protocol.ID
+	RequestCodec Codec
+	// The codec of response chunks. For methods with context bytes, this is the codec that bounds the chunk size of all contexts.
 	ResponseChunkCodec        Codec
 	DefaultResponseChunkCount uint64
+	// Number of context bytes that prefix each successful response chunk. 0 if the method has no context bytes.
+	ContextBytesLen uint64
+	// The codec of a response chunk with the given context bytes. Required if ContextBytesLen is not 0.
+	ResponseChunkContextCodec func(contextBytes []byte) (Codec, error)
+}
+
+// ChunkCodec returns the codec of a response chunk with the given context bytes.
+func (m *RPCMethod) ChunkCodec(contextBytes []byte) (Codec, error) {
+	if m.ContextBytesLen == 0 {
+		return m.ResponseChunkCodec, nil
+	}
+	if uint64(len(contextBytes)) != m.ContextBytesLen {
+		return nil, fmt.Errorf("expected %d context bytes, got %d", m.ContextBytesLen, len(contextBytes))
+	}
+	return m.ResponseChunkContextCodec(contextBytes)
 }
 
 type ResponseCode uint8
@@ -130,17 +146,22 @@ type ChunkedResponseHandler interface {
 	ChunkSize() uint64
 	ChunkIndex() uint64
 	ResultCode() ResponseCode
+	// ContextBytes of the chunk, nil if the method has no context bytes or if the chunk is not successful.
+	ContextBytes() []byte
 	ReadRaw() ([]byte, error)
 	ReadErrMsg() (string, error)
+	// AllocObj allocates an object of the type of the chunk contents, based on the context bytes if any.
+	AllocObj() (SerDes, error)
 	ReadObj(dest codec.Deserializable) error
 }
 
 type chRespHandler struct {
-	m          *RPCMethod
-	r          io.Reader
-	result     ResponseCode
-	chunkSize  uint64
-	chunkIndex uint64
+	m            *RPCMethod
+	r            io.Reader
+	result       ResponseCode
+	chunkSize    uint64
+	chunkIndex   uint64
+	contextBytes []byte
 }
 
 func (c *chRespHandler) ChunkSize() uint64 {
@@ -155,33 +176,50 @@ func (c *chRespHandler) ResultCode() ResponseCode {
 	return c.result
 }
 
+func (c *chRespHandler) ContextBytes() []byte {
+	return c.contextBytes
+}
+
 func (c *chRespHandler) ReadRaw() ([]byte, error) {
 	var buf bytes.Buffer
 	_, err := buf.ReadFrom(io.LimitReader(c.r, int64(c.chunkSize)))
-	return buf.Bytes(), err
+	return buf.Bytes(), classifyPayloadErr(c.r, err, "")
 }
 
 func (c *chRespHandler) ReadErrMsg() (string, error) {
 	var buf bytes.Buffer
 	_, err := buf.ReadFrom(io.LimitReader(c.r, int64(c.chunkSize)))
-	return string(buf.Bytes()), err
+	return string(buf.Bytes()), classifyPayloadErr(c.r, err, "")
+}
+
+func (c *chRespHandler) AllocObj() (SerDes, error) {
+	cod, err := c.m.ChunkCodec(c.contextBytes)
+	if err != nil {
+		return nil, err
+	}
+	return cod.Alloc(), nil
 }
 
 func (c *chRespHandler) ReadObj(dest codec.Deserializable) error {
-	return c.m.ResponseChunkCodec.Decode(c.r, c.chunkSize, dest)
+	cod, err := c.m.ChunkCodec(c.contextBytes)
+	if err != nil {
+		return err
+	}
+	return classifyPayloadErr(c.r, cod.Decode(c.r, c.chunkSize, dest), DecodeErrKind)
 }
 
 func (m *RPCMethod) RunRequest(ctx context.Context, newStreamFn NewStreamFn,
 	peerId peer.ID, comp Compression, req RequestInput, maxRespChunks uint64, madeRequest func() error,
 	onResponse OnResponseListener) error {
 
-	handleChunks := ResponseChunkHandler(func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, r io.Reader, w io.Writer) error {
+	handleChunks := ContextResponseChunkHandler(func(ctx context.Context, chunkIndex uint64, chunkSize uint64, result ResponseCode, contextBytes []byte, r io.Reader, w io.Writer) error {
 		return onResponse(&chRespHandler{
-			m:          m,
-			r:          r,
-			result:     result,
-			chunkSize:  chunkSize,
-			chunkIndex: chunkIndex,
+			m:            m,
+			r:            r,
+			result:       result,
+			chunkSize:    chunkSize,
+			chunkIndex:   chunkIndex,
+			contextBytes: contextBytes,
 		})
 	})
 
@@ -191,17 +229,13 @@ func (m *RPCMethod) RunRequest(ctx context.Context, newStreamFn NewStreamFn,
 	}
 
 	protocolId := m.Protocol
-	maxChunkContentSize := m.ResponseChunkCodec.MaxByteLen()
 	if comp != nil {
 		protocolId += protocol.ID("_" + comp.Name())
-		if s, err := comp.MaxEncodedLen(maxChunkContentSize); err != nil {
-			return err
-		} else {
-			maxChunkContentSize = s
-		}
 	}
+	// The chunk size limit applies to the uncompressed contents, the response handler adjusts for compression.
+	maxChunkContentSize := m.ResponseChunkCodec.MaxByteLen()
 
-	respHandler := handleChunks.MakeResponseHandler(maxRespChunks, maxChunkContentSize, comp)
+	respHandler := handleChunks.MakeResponseHandler(maxRespChunks, maxChunkContentSize, m.ContextBytesLen, comp)
 
 	handler := ResponseHandler(func(ctx context.Context, r io.Reader, w io.WriteCloser) error {
 		if err := madeRequest(); err != nil {
@@ -212,7 +246,13 @@ func (m *RPCMethod) RunRequest(ctx context.Context, newStreamFn NewStreamFn,
 
 	// Runs the request in sync, which processes responses,
 	// and then finally closes the channel through the earlier deferred close.
-	return newStreamFn.Request(ctx, peerId, protocolId, reqR, comp, handler)
+	if err := newStreamFn.Request(ctx, peerId, protocolId, reqR, comp, handler); err != nil {
+		if _, ok := ErrKind(err); !ok && ctx.Err() == context.DeadlineExceeded {
+			return &RPCError{Kind: TimeoutErrKind, Err: err}
+		}
+		return err
+	}
+	return nil
 }
 
 type ReadRequestFn func(dest interface{}) error
@@ -231,6 +271,14 @@ type RequestResponder interface {
 	WriteRawResponseChunk(code ResponseCode, chunk []byte) error
 	StreamResponseChunk(code ResponseCode, size uint64, r io.Reader) error
 	WriteErrorChunk(code ResponseCode, msg string) error
+	// Like WriteResponseChunk, for methods with context bytes. The data is encoded with the codec of the context.
+	WriteResponseChunkWithContext(code ResponseCode, contextBytes []byte, data codec.Serializable) error
+	// Like StreamResponseChunk, for methods with context bytes.
+	StreamResponseChunkWithContext(code ResponseCode, contextBytes []byte, size uint64, r io.Reader) error
+	// WriteRaw writes the bytes to the stream as-is, e.g. to respond with a malformed chunk.
+	WriteRaw(b []byte) error
+	// Reset resets the stream, instead of closing it after the response.
+	Reset() error
 }
 
 type ChunkedRequestHandler interface {
@@ -241,11 +289,13 @@ type ChunkedRequestHandler interface {
 type chReqHandler struct {
 	m               *RPCMethod
 	comp            Compression
-	respBuf         bytes.Buffer
+	respBuf         *bytes.Buffer
 	reqLen          uint64
 	r               io.Reader
 	w               io.Writer
 	invalidInputErr error
+	// The stream of the request, to reset
+	stream network.Stream
 }
 
 func (h *chReqHandler) InvalidInput() error {
@@ -256,11 +306,8 @@ func (h *chReqHandler) ReadRequest(dest codec.Deserializable) error {
 	if h.invalidInputErr != nil {
 		return h.invalidInputErr
 	}
-	r := h.r
-	if h.comp != nil {
-		r = h.comp.Decompress(r)
-	}
-	return h.m.RequestCodec.Decode(r, h.reqLen, dest)
+	r := newPayloadReader(h.r, h.comp)
+	return r.classify(h.m.RequestCodec.Decode(r, h.reqLen, dest), DecodeErrKind)
 }
 
 func (h *chReqHandler) RawRequest() ([]byte, error) {
@@ -268,33 +315,85 @@ func (h *chReqHandler) RawRequest() ([]byte, error) {
 		return nil, h.invalidInputErr
 	}
 	var buf bytes.Buffer
-	r := h.r
-	if h.comp != nil {
-		r = h.comp.Decompress(r)
-	}
+	r := newPayloadReader(h.r, h.comp)
 	if _, err := buf.ReadFrom(io.LimitReader(r, int64(h.reqLen))); err != nil {
-		return nil, err
+		return nil, r.classify(err, "")
 	}
 	return buf.Bytes(), nil
 }
 
+// responseBuffer returns the buffer to encode response chunks with, taken from the pool on first use.
+// The same buffer is re-used for each response chunk.
+func (h *chReqHandler) responseBuffer() *bytes.Buffer {
+	if h.respBuf == nil {
+		h.respBuf = responseBufPool.Get().(*bytes.Buffer)
+	}
+	h.respBuf.Reset()
+	return h.respBuf
+}
+
+// release returns the response buffer to the pool, after the request was handled.
+func (h *chReqHandler) release() {
+	if h.respBuf != nil {
+		responseBufPool.Put(h.respBuf)
+		h.respBuf = nil
+	}
+}
+
+// checkNoContext errors if a successful chunk is written without context bytes, for methods that require them.
+func (h *chReqHandler) checkNoContext(code ResponseCode) error {
+	if code == SuccessCode && h.m.ContextBytesLen != 0 {
+		return fmt.Errorf("method %s requires context bytes for successful response chunks", h.m.Protocol)
+	}
+	return nil
+}
+
 func (h *chReqHandler) WriteResponseChunk(code ResponseCode, data codec.Serializable) error {
-	h.respBuf.Reset() // re-use buffer for each response chunk
-	if err := h.m.ResponseChunkCodec.Encode(&h.respBuf, data); err != nil {
+	if err := h.checkNoContext(code); err != nil {
 		return err
 	}
-	b := h.respBuf.Bytes()
+	buf := h.responseBuffer()
+	if err := h.m.ResponseChunkCodec.Encode(buf, data); err != nil {
+		return err
+	}
+	b := buf.Bytes()
 	return StreamChunk(code, uint64(len(b)), bytes.NewReader(b), h.w, h.comp)
 }
 
 func (h *chReqHandler) WriteRawResponseChunk(code ResponseCode, chunk []byte) error {
+	if err := h.checkNoContext(code); err != nil {
+		return err
+	}
 	return StreamChunk(code, uint64(len(chunk)), bytes.NewReader(chunk), h.w, h.comp)
 }
 
 func (h *chReqHandler) StreamResponseChunk(code ResponseCode, size uint64, r io.Reader) error {
+	if err := h.checkNoContext(code); err != nil {
+		return err
+	}
 	return StreamChunk(code, size, r, h.w, h.comp)
 }
 
+func (h *chReqHandler) WriteResponseChunkWithContext(code ResponseCode, contextBytes []byte, data codec.Serializable) error {
+	cod, err := h.m.ChunkCodec(contextBytes)
+	if err != nil {
+		return err
+	}
+	buf := h.responseBuffer()
+	if err := cod.Encode(buf, data); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	return StreamChunkWithContext(code, contextBytes, uint64(len(b)), bytes.NewReader(b), h.w, h.comp)
+}
+
+func (h *chReqHandler) StreamResponseChunkWithContext(code ResponseCode, contextBytes []byte, size uint64, r io.Reader) error {
+	if uint64(len(contextBytes)) != h.m.ContextBytesLen {
+		return fmt.Errorf("expected %d context bytes, got %d", h.m.ContextBytesLen, len(contextBytes))
+	}
+	return StreamChunkWithContext(code, contextBytes, size, r, h.w, h.comp)
+}
+
 func (h *chReqHandler) WriteErrorChunk(code ResponseCode, msg string) error {
 	if len(msg) > MAX_ERR_SIZE {
 		msg = msg[:MAX_ERR_SIZE-3]
@@ -304,12 +403,34 @@ func (h *chReqHandler) WriteErrorChunk(code ResponseCode, msg string) error {
 	return StreamChunk(code, uint64(len(b)), bytes.NewReader(b), h.w, h.comp)
 }
 
+func (h *chReqHandler) WriteRaw(b []byte) error {
+	_, err := h.w.Write(b)
+	return err
+}
+
+func (h *chReqHandler) Reset() error {
+	if h.stream == nil {
+		return errors.New("no stream to reset")
+	}
+	return h.stream.Reset()
+}
+
 type OnRequestListener func(ctx context.Context, peerId peer.ID, handler ChunkedRequestHandler)
 
 func (m *RPCMethod) MakeStreamHandler(newCtx StreamCtxFn, comp Compression, listener OnRequestListener) network.StreamHandler {
-	return RequestPayloadHandler(func(ctx context.Context, peerId peer.ID, requestLen uint64, r io.Reader, w io.Writer, comp Compression, invalidInputErr error) {
-		listener(ctx, peerId, &chReqHandler{
-			m: m, comp: comp, reqLen: requestLen, r: r, w: w, invalidInputErr: invalidInputErr,
-		})
-	}).MakeStreamHandler(newCtx, comp, m.RequestCodec.MinByteLen(), m.RequestCodec.MaxByteLen())
+	return m.MakeLimitedStreamHandler(newCtx, comp, nil, listener)
+}
+
+// MakeLimitedStreamHandler is like MakeStreamHandler, but limits and counts the handled streams with the limiter.
+// The limiter is optional and may be nil.
+func (m *RPCMethod) MakeLimitedStreamHandler(newCtx StreamCtxFn, comp Compression, limiter *StreamLimiter, listener OnRequestListener) network.StreamHandler {
+	return func(stream network.Stream) {
+		RequestPayloadHandler(func(ctx context.Context, peerId peer.ID, requestLen uint64, r io.Reader, w io.Writer, comp Compression, invalidInputErr error) {
+			h := &chReqHandler{
+				m: m, comp: comp, reqLen: requestLen, r: r, w: w, invalidInputErr: invalidInputErr, stream: stream,
+			}
+			defer h.release()
+			listener(ctx, peerId, h)
+		}).MakeLimitedStreamHandler(newCtx, comp, m.RequestCodec.MinByteLen(), m.RequestCodec.MaxByteLen(), limiter)(stream)
+	}
 }
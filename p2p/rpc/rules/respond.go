@@ -0,0 +1,133 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/ztyp/codec"
+	"time"
+)
+
+var errNoRequestObj = errors.New("request could not be decoded")
+
+// Request is a request that was read to match the rules against.
+// It can still be read again, to pass it on to a regular request handler if no rule matches.
+type Request struct {
+	reqresp.ChunkedRequestHandler
+	Method *reqresp.RPCMethod
+	// Uncompressed request data, nil if the input is invalid
+	Data []byte
+	// Error of invalid input
+	Err error
+
+	decoded interface{}
+	decErr  error
+}
+
+// ReadRequest reads the request of the handler.
+func ReadRequest(m *reqresp.RPCMethod, handler reqresp.ChunkedRequestHandler) *Request {
+	data, err := handler.RawRequest()
+	return &Request{ChunkedRequestHandler: handler, Method: m, Data: data, Err: err}
+}
+
+func (r *Request) RawRequest() ([]byte, error) {
+	return r.Data, r.Err
+}
+
+func (r *Request) ReadRequest(dest codec.Deserializable) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if err := r.Method.RequestCodec.Decode(bytes.NewReader(r.Data), uint64(len(r.Data)), dest); err != nil {
+		return &reqresp.RPCError{Kind: reqresp.DecodeErrKind, Err: err}
+	}
+	return nil
+}
+
+// fields decodes the request, and converts it to a generic JSON value to match fields against.
+func (r *Request) fields() (interface{}, error) {
+	if r.decoded != nil || r.decErr != nil {
+		return r.decoded, r.decErr
+	}
+	r.decErr = errNoRequestObj
+	obj := r.Method.RequestCodec.Alloc()
+	if obj == nil || r.ReadRequest(obj) != nil {
+		return nil, r.decErr
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, r.decErr
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&r.decoded); err != nil {
+		return nil, r.decErr
+	}
+	r.decErr = nil
+	return r.decoded, nil
+}
+
+// Apply runs the response steps of the rule. The response ends when it returns,
+// unless the stream was reset by the rule.
+func (r *Rule) Apply(ctx context.Context, req *Request, comp reqresp.Compression) error {
+	for i := range r.Respond {
+		st := &r.Respond[i]
+		switch {
+		case st.Delay != 0:
+			select {
+			case <-time.After(time.Duration(st.Delay)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case st.Chunk != nil:
+			code := reqresp.SuccessCode
+			if st.Result != nil {
+				code = *st.Result
+			}
+			var buf bytes.Buffer
+			var err error
+			if len(st.Context) > 0 {
+				err = reqresp.StreamChunkWithContext(code, st.Context, uint64(len(st.Chunk)), bytes.NewReader(st.Chunk), &buf, comp)
+			} else {
+				err = reqresp.StreamChunk(code, uint64(len(st.Chunk)), bytes.NewReader(st.Chunk), &buf, comp)
+			}
+			if err != nil {
+				return fmt.Errorf("step %d: failed to encode chunk: %v", i, err)
+			}
+			if done, err := st.write(req, buf.Bytes()); err != nil || done {
+				return err
+			}
+		case st.Error != "":
+			code := reqresp.ResponseCode(reqresp.ServerErrCode)
+			if st.Result != nil {
+				code = *st.Result
+			}
+			var buf bytes.Buffer
+			msg := []byte(st.Error)
+			if err := reqresp.StreamChunk(code, uint64(len(msg)), bytes.NewReader(msg), &buf, comp); err != nil {
+				return fmt.Errorf("step %d: failed to encode error: %v", i, err)
+			}
+			if done, err := st.write(req, buf.Bytes()); err != nil || done {
+				return err
+			}
+		case st.Raw != nil:
+			if err := req.WriteRaw(st.Raw); err != nil {
+				return err
+			}
+		case st.Reset:
+			return req.Reset()
+		}
+	}
+	return nil
+}
+
+// write writes the encoded chunk, truncated if the step says so. If truncated, the response is done.
+func (st *Step) write(req *Request, b []byte) (done bool, err error) {
+	if st.Truncate != 0 && st.Truncate < len(b) {
+		return true, req.WriteRaw(b[:st.Truncate])
+	}
+	return false, req.WriteRaw(b)
+}
@@ -0,0 +1,264 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RuleSet is an ordered list of rules. The first rule that matches a request is applied to it.
+type RuleSet struct {
+	Rules []*Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule describes which requests to match, and the steps to respond with.
+type Rule struct {
+	// Name of the rule, for logging
+	Name    string `json:"name" yaml:"name"`
+	Match   Match  `json:"match" yaml:"match"`
+	Respond []Step `json:"respond" yaml:"respond"`
+}
+
+// Match describes the requests a rule applies to. Empty fields match anything.
+type Match struct {
+	// Peer IDs of the requesting peer
+	Peers []string `json:"peers" yaml:"peers"`
+	// Method routes (e.g. "blocks-by-range-v2"), names (e.g. "blocks-by-range") or protocol IDs
+	Methods []string `json:"methods" yaml:"methods"`
+	// Request fields, by dot-separated path into the JSON representation of the request, e.g. "head_slot".
+	// List elements are selected by index, and the empty path selects the request itself.
+	// Values are compared by their string representation. Invalid requests never match request fields.
+	Request map[string]interface{} `json:"request" yaml:"request"`
+
+	peers map[peer.ID]struct{}
+}
+
+// Step is a single step of a response, exactly one of Delay, Chunk, Error, Raw or Reset is set.
+type Step struct {
+	// Wait before the next step
+	Delay Duration `json:"delay,omitempty" yaml:"delay,omitempty"`
+	// Write a chunk with the given (uncompressed) contents
+	Chunk HexBytes `json:"chunk,omitempty" yaml:"chunk,omitempty"`
+	// Write an error chunk with the given message
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Write the bytes to the stream as-is
+	Raw HexBytes `json:"raw,omitempty" yaml:"raw,omitempty"`
+	// Reset the stream, no steps can follow
+	Reset bool `json:"reset,omitempty" yaml:"reset,omitempty"`
+
+	// Result code of the chunk or error. Defaults to success for chunks, and server-error for errors.
+	Result *reqresp.ResponseCode `json:"result,omitempty" yaml:"result,omitempty"`
+	// Context bytes of the chunk, for methods with context bytes
+	Context HexBytes `json:"context,omitempty" yaml:"context,omitempty"`
+	// If not 0, only write the first n bytes of the encoded chunk or error, and end the response.
+	Truncate int `json:"truncate,omitempty" yaml:"truncate,omitempty"`
+}
+
+// Duration is a time.Duration that is formatted like "1.5s" in JSON and YAML
+type Duration time.Duration
+
+func (d *Duration) set(v string) error {
+	x, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = Duration(x)
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v string
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+// HexBytes are bytes that are hex-encoded in JSON and YAML, with optional 0x prefix
+type HexBytes []byte
+
+func (h *HexBytes) set(v string) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+	if err != nil {
+		return err
+	}
+	*h = b
+	return nil
+}
+
+func (h *HexBytes) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return h.set(v)
+}
+
+func (h *HexBytes) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v string
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return h.set(v)
+}
+
+// Load reads a rule set from a YAML (.yaml or .yml) or JSON file.
+func Load(path string) (*RuleSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	return Parse(data, ext == ".yaml" || ext == ".yml")
+}
+
+// Parse parses a rule set from YAML or JSON, and checks it.
+func Parse(data []byte, isYAML bool) (*RuleSet, error) {
+	var rs RuleSet
+	if isYAML {
+		if err := yaml.UnmarshalStrict(data, &rs); err != nil {
+			return nil, err
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		dec.UseNumber()
+		if err := dec.Decode(&rs); err != nil {
+			return nil, err
+		}
+	}
+	if err := rs.Check(); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// Check validates the rules, and prepares them for matching.
+func (rs *RuleSet) Check() error {
+	for i, r := range rs.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule_%d", i)
+		}
+		if err := r.check(); err != nil {
+			return fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) check() error {
+	r.Match.peers = make(map[peer.ID]struct{}, len(r.Match.Peers))
+	for _, p := range r.Match.Peers {
+		id, err := peer.Decode(p)
+		if err != nil {
+			return fmt.Errorf("invalid peer ID %q: %v", p, err)
+		}
+		r.Match.peers[id] = struct{}{}
+	}
+	for i := range r.Respond {
+		st := &r.Respond[i]
+		actions := 0
+		for _, set := range []bool{st.Delay != 0, st.Chunk != nil, st.Error != "", st.Raw != nil, st.Reset} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			return fmt.Errorf("step %d: expected exactly one of delay, chunk, error, raw or reset, got %d", i, actions)
+		}
+		if st.Reset && i != len(r.Respond)-1 {
+			return fmt.Errorf("step %d: no steps can follow a reset", i)
+		}
+		if st.Truncate < 0 {
+			return fmt.Errorf("step %d: negative truncate", i)
+		}
+	}
+	return nil
+}
+
+// Find returns the first rule that matches the request, or nil if no rule matches.
+func (rs *RuleSet) Find(entry *methods.MethodEntry, peerId peer.ID, req *Request) *Rule {
+	for _, r := range rs.Rules {
+		if r.Match.matches(entry, peerId, req) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (m *Match) matches(entry *methods.MethodEntry, peerId peer.ID, req *Request) bool {
+	if len(m.peers) > 0 {
+		if _, ok := m.peers[peerId]; !ok {
+			return false
+		}
+	}
+	if len(m.Methods) > 0 {
+		found := false
+		for _, name := range m.Methods {
+			if name == entry.Route() || name == entry.Name || name == string(entry.Protocol()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(m.Request) == 0 {
+		return true
+	}
+	fields, err := req.fields()
+	if err != nil {
+		return false
+	}
+	for path, expected := range m.Request {
+		v, ok := lookup(fields, path)
+		if !ok || fmt.Sprint(v) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup gets the value at the dot-separated path in a decoded JSON value
+func lookup(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			next, ok := x[key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			var i int
+			if _, err := fmt.Sscanf(key, "%d", &i); err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
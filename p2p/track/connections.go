@@ -0,0 +1,17 @@
+package track
+
+import (
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+// ConnectionNotifiee builds a network notifiee that records the connections of the network in the connection book.
+func ConnectionNotifiee(book ConnectionBook) network.Notifiee {
+	return &network.NotifyBundle{
+		ConnectedF: func(net network.Network, conn network.Conn) {
+			book.RegisterConnected(conn.RemotePeer(), conn.Stat().Direction)
+		},
+		DisconnectedF: func(net network.Network, conn network.Conn) {
+			book.RegisterDisconnected(conn.RemotePeer())
+		},
+	}
+}
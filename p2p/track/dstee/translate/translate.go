@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"encoding/gob"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -16,11 +17,13 @@ import (
 	pstore_pb "github.com/libp2p/go-libp2p-peerstore/pb"
 	"github.com/multiformats/go-base32"
 	"github.com/protolambda/rumor/p2p/addrutil"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/protolambda/ztyp/codec"
 	"net"
 	"sort"
 	"strconv"
+	"time"
 )
 
 /*
@@ -30,13 +33,16 @@ Peerstore layout, partially due to libp2p legacy:
 		- /eth2
 		 	- /<peer-id>
 				- /metadata           <- ssz encoded
+				- /metadata_v2        <- ssz encoded, Altair metadata with syncnets
 				- /metadata_claim     <- ssz encoded
 				- /status             <- ssz encoded
+				- /connections        <- json encoded, connection history
 				- /enr                <- stored in raw base64 enr presentation. Then expanded into subfields when reading:
 				  - /raw              <- base64 enr representation
                   - /other            <- map of unrecognized key/value pairs. Values encoded as hex bytes by us.
                   - /eth2_data        <- Eth2Data
                   - /attnets          <- bitfield
+                  - /syncnets         <- bitfield
                   - /seq              <- ENR seq num
                   - /ip               <- IP (v4 or v6)
                   - /udp              <- UDP port
@@ -58,14 +64,15 @@ Peerstore layout, partially due to libp2p legacy:
 */
 
 type ENRData struct {
-	Raw      string             `json:"raw,omitempty"`
-	Other    map[string]string  `json:"other,omitempty"`
-	Eth2Data *beacon.Eth2Data   `json:"eth2_data,omitempty"`
-	Attnets  *beacon.AttnetBits `json:"attnets,omitempty"`
-	Seq      uint64             `json:"seq,omitempty"`
-	IP       net.IP             `json:"ip,omitempty"`
-	TCP      uint16             `json:"tcp,omitempty"`
-	UDP      uint16             `json:"udp,omitempty"`
+	Raw      string              `json:"raw,omitempty"`
+	Other    map[string]string   `json:"other,omitempty"`
+	Eth2Data *beacon.Eth2Data    `json:"eth2_data,omitempty"`
+	Attnets  *beacon.AttnetBits  `json:"attnets,omitempty"`
+	Syncnets *altair.SyncnetBits `json:"syncnets,omitempty"`
+	Seq      uint64              `json:"seq,omitempty"`
+	IP       net.IP              `json:"ip,omitempty"`
+	TCP      uint16              `json:"tcp,omitempty"`
+	UDP      uint16              `json:"udp,omitempty"`
 }
 
 type AddrBookRecord struct {
@@ -91,11 +98,24 @@ type AddrBookRecord_CertifiedRecord struct {
 	Raw string `json:"raw,omitempty"`
 }
 
+// ConnectionData is the connection history of a peer, see track.ConnectionRecord
+type ConnectionData struct {
+	FirstSeen         time.Time     `json:"first_seen"`
+	LastConnected     *time.Time    `json:"last_connected,omitempty"`
+	LastDisconnected  *time.Time    `json:"last_disconnected,omitempty"`
+	ConnectedDuration time.Duration `json:"connected_duration"`
+	Inbound           uint64        `json:"inbound"`
+	Outbound          uint64        `json:"outbound"`
+	DialFailures      uint64        `json:"dial_failures"`
+}
+
 type Eth2Data struct {
-	Metadata      *beacon.MetaData `json:"metadata,omitempty"`
-	MetadataClaim beacon.SeqNr     `json:"metadata_claim,omitempty"`
-	Status        *beacon.Status   `json:"status,omitempty"`
-	ENR           *ENRData         `json:"enr,omitempty"`
+	Metadata      *beacon.MetaData   `json:"metadata,omitempty"`
+	MetadataV2    *altair.MetaDataV2 `json:"metadata_v2,omitempty"`
+	MetadataClaim beacon.SeqNr       `json:"metadata_claim,omitempty"`
+	Status        *beacon.Status     `json:"status,omitempty"`
+	Connections   *ConnectionData    `json:"connections,omitempty"`
+	ENR           *ENRData           `json:"enr,omitempty"`
 }
 
 type PartialPeerstoreEntry struct {
@@ -122,6 +142,9 @@ func (p *PartialPeerstoreEntry) Merge(other *PartialPeerstoreEntry) {
 			if other.Eth2.Status != nil {
 				p.Eth2.Status = other.Eth2.Status
 			}
+			if other.Eth2.Connections != nil {
+				p.Eth2.Connections = other.Eth2.Connections
+			}
 			if other.Eth2.MetadataClaim > p.Eth2.MetadataClaim {
 				p.Eth2.MetadataClaim = other.Eth2.MetadataClaim
 			}
@@ -131,6 +154,11 @@ func (p *PartialPeerstoreEntry) Merge(other *PartialPeerstoreEntry) {
 					p.Eth2.Metadata = other.Eth2.Metadata
 				}
 			}
+			if other.Eth2.MetadataV2 != nil {
+				if p.Eth2.MetadataV2 == nil || other.Eth2.MetadataV2.SeqNumber > p.Eth2.MetadataV2.SeqNumber {
+					p.Eth2.MetadataV2 = other.Eth2.MetadataV2
+				}
+			}
 		}
 	}
 	if other.AddrRecords != nil {
@@ -173,6 +201,9 @@ func (p *PartialPeerstoreEntry) ToCSV(prefixFields ...string) (out [][]string) {
 			if p.Eth2.ENR.Attnets != nil {
 				entry("eth2/enr/attnets", p.Eth2.ENR.Attnets.String())
 			}
+			if p.Eth2.ENR.Syncnets != nil {
+				entry("eth2/enr/syncnets", p.Eth2.ENR.Syncnets.String())
+			}
 			for k, v := range p.Eth2.ENR.Other {
 				entry("eth2/enr/"+k, v)
 			}
@@ -187,6 +218,19 @@ func (p *PartialPeerstoreEntry) ToCSV(prefixFields ...string) (out [][]string) {
 			entry("eth2/status/head_slot", strconv.FormatUint(uint64(p.Eth2.Status.HeadSlot), 10))
 			entry("eth2/status/fork_digest", p.Eth2.Status.ForkDigest.String())
 		}
+		if p.Eth2.Connections != nil {
+			entry("eth2/connections/first_seen", strconv.FormatInt(p.Eth2.Connections.FirstSeen.Unix(), 10))
+			if p.Eth2.Connections.LastConnected != nil {
+				entry("eth2/connections/last_connected", strconv.FormatInt(p.Eth2.Connections.LastConnected.Unix(), 10))
+			}
+			if p.Eth2.Connections.LastDisconnected != nil {
+				entry("eth2/connections/last_disconnected", strconv.FormatInt(p.Eth2.Connections.LastDisconnected.Unix(), 10))
+			}
+			entry("eth2/connections/connected_duration", p.Eth2.Connections.ConnectedDuration.String())
+			entry("eth2/connections/inbound", strconv.FormatUint(p.Eth2.Connections.Inbound, 10))
+			entry("eth2/connections/outbound", strconv.FormatUint(p.Eth2.Connections.Outbound, 10))
+			entry("eth2/connections/dial_failures", strconv.FormatUint(p.Eth2.Connections.DialFailures, 10))
+		}
 		if p.Eth2.MetadataClaim > p.Eth2.MetadataClaim {
 			entry("eth2/metadata_claim", strconv.FormatUint(uint64(p.Eth2.MetadataClaim), 10))
 		}
@@ -194,6 +238,11 @@ func (p *PartialPeerstoreEntry) ToCSV(prefixFields ...string) (out [][]string) {
 			entry("eth2/metadata/seq_number", strconv.FormatUint(uint64(p.Eth2.Metadata.SeqNumber), 10))
 			entry("eth2/metadata/attnets", p.Eth2.Metadata.Attnets.String())
 		}
+		if p.Eth2.MetadataV2 != nil {
+			entry("eth2/metadata_v2/seq_number", strconv.FormatUint(uint64(p.Eth2.MetadataV2.SeqNumber), 10))
+			entry("eth2/metadata_v2/attnets", p.Eth2.MetadataV2.Attnets.String())
+			entry("eth2/metadata_v2/syncnets", p.Eth2.MetadataV2.Syncnets.String())
+		}
 	}
 	if p.AddrRecords != nil {
 		if p.AddrRecords.CertifiedRecord != nil {
@@ -267,10 +316,14 @@ func KeyToPath(k ds.Key) (id peer.ID, p string, err error) {
 			switch parts[3] {
 			case "metadata":
 				p = "eth2/metadata"
+			case "metadata_v2":
+				p = "eth2/metadata_v2"
 			case "metadata_claim":
 				p = "eth2/metadata_claim"
 			case "status":
 				p = "eth2/status"
+			case "connections":
+				p = "eth2/connections"
 			case "enr":
 				p = "eth2/enr"
 			default:
@@ -329,6 +382,14 @@ func ItemToEntry(k ds.Key, v []byte) (id peer.ID, out PartialPeerstoreEntry, err
 					err = fmt.Errorf("bad metadata in peerstore: %v", e)
 					return
 				}
+			case "metadata_v2":
+				var md altair.MetaDataV2
+				if e := md.Deserialize(codec.NewDecodingReader(bytes.NewReader(v), uint64(len(v)))); e == nil {
+					out.Eth2.MetadataV2 = &md
+				} else {
+					err = fmt.Errorf("bad metadata_v2 in peerstore: %v", e)
+					return
+				}
 			case "metadata_claim":
 				if len(v) == 8 {
 					out.Eth2.MetadataClaim = beacon.SeqNr(binary.LittleEndian.Uint64(v))
@@ -344,6 +405,14 @@ func ItemToEntry(k ds.Key, v []byte) (id peer.ID, out PartialPeerstoreEntry, err
 					err = fmt.Errorf("bad status in peerstore: %v", e)
 					return
 				}
+			case "connections":
+				var cd ConnectionData
+				if e := json.Unmarshal(v, &cd); e == nil {
+					out.Eth2.Connections = &cd
+				} else {
+					err = fmt.Errorf("bad connections in peerstore: %v", e)
+					return
+				}
 			case "enr":
 				out.Eth2.ENR = &ENRData{}
 				out.Eth2.ENR.Raw = string(v)
@@ -351,12 +420,15 @@ func ItemToEntry(k ds.Key, v []byte) (id peer.ID, out PartialPeerstoreEntry, err
 				if err == nil {
 					n, err := enode.New(enode.ValidSchemes, rec)
 					if err == nil {
-						if eth2Data, ok, err := addrutil.ParseEnrEth2Data(n); err != nil && ok {
+						if eth2Data, ok, err := addrutil.ParseEnrEth2Data(n); err == nil && ok {
 							out.Eth2.ENR.Eth2Data = eth2Data
 						}
-						if attnets, ok, err := addrutil.ParseEnrAttnets(n); err != nil && ok {
+						if attnets, ok, err := addrutil.ParseEnrAttnets(n); err == nil && ok {
 							out.Eth2.ENR.Attnets = attnets
 						}
+						if syncnets, ok, err := addrutil.ParseEnrSyncnets(n); err == nil && ok {
+							out.Eth2.ENR.Syncnets = syncnets
+						}
 						out.Eth2.ENR.Seq = n.Seq()
 						out.Eth2.ENR.IP = n.IP()
 						out.Eth2.ENR.TCP = uint16(n.TCP())
@@ -378,7 +450,7 @@ func ItemToEntry(k ds.Key, v []byte) (id peer.ID, out PartialPeerstoreEntry, err
 							}
 							// if these cannot be parsed, then fine, add the raw form on failure (see above)
 							// Otherwise, don't duplicat the data.
-							if key == "eth2" || key == "attnets" || key == "ip" ||
+							if key == "eth2" || key == "attnets" || key == "syncnets" || key == "ip" ||
 								key == "ip6" || key == "udp" || key == "tcp" {
 								continue
 							}
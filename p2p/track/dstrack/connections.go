@@ -0,0 +1,149 @@
+package dstrack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/p2p/track"
+	"sync"
+	"time"
+)
+
+var connectionsSuffix = ds.NewKey("/connections")
+
+type dsConnectionBook struct {
+	ds ds.Datastore
+	// cache the records of connected peers, and make updates of the connection history atomic
+	sync.Mutex
+	records map[peer.ID]*track.ConnectionRecord
+	// Number of currently open connections per peer. Not persisted, connections do not outlive the process.
+	open map[peer.ID]uint64
+}
+
+var _ track.ConnectionBook = (*dsConnectionBook)(nil)
+
+func NewConnectionBook(store ds.Datastore) (*dsConnectionBook, error) {
+	return &dsConnectionBook{
+		ds:      store,
+		records: make(map[peer.ID]*track.ConnectionRecord),
+		open:    make(map[peer.ID]uint64),
+	}, nil
+}
+
+func (cb *dsConnectionBook) loadConnections(p peer.ID) (*track.ConnectionRecord, error) {
+	key := peerIdToKey(eth2Base, p).Child(connectionsSuffix)
+	value, err := cb.ds.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching connections from datastore for peer %s: %w", p.Pretty(), err)
+	}
+	var rec track.ConnectionRecord
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return nil, fmt.Errorf("failed parse connections from datastore: %v", err)
+	}
+	return &rec, nil
+}
+
+func (cb *dsConnectionBook) storeConnections(p peer.ID, rec *track.ConnectionRecord) error {
+	key := peerIdToKey(eth2Base, p).Child(connectionsSuffix)
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed encode connections for datastore: %v", err)
+	}
+	if err := cb.ds.Put(key, value); err != nil {
+		return fmt.Errorf("failed to store connections: %v", err)
+	}
+	return nil
+}
+
+// get the cached record, or lazy-load it from the datastore,
+// or create a new record if the peer is not known yet. Must hold the lock.
+func (cb *dsConnectionBook) get(id peer.ID, now time.Time) (*track.ConnectionRecord, error) {
+	if rec, ok := cb.records[id]; ok {
+		return rec, nil
+	}
+	rec, err := cb.loadConnections(id)
+	if errors.Is(err, ds.ErrNotFound) {
+		return &track.ConnectionRecord{FirstSeen: now}, nil
+	}
+	return rec, err
+}
+
+// update the record of the peer, and persist it.
+// Returns nil, and leaves the history untouched, if the existing record could not be loaded.
+func (cb *dsConnectionBook) update(id peer.ID, fn func(rec *track.ConnectionRecord, now time.Time)) *track.ConnectionRecord {
+	cb.Lock()
+	defer cb.Unlock()
+	now := time.Now()
+	rec, err := cb.get(id, now)
+	if err != nil {
+		return nil
+	}
+	fn(rec, now)
+	// Only keep the records of connected peers in memory, others are loaded again when needed.
+	if cb.open[id] > 0 {
+		cb.records[id] = rec
+	} else {
+		delete(cb.records, id)
+	}
+	// Try persist it to the store
+	_ = cb.storeConnections(id, rec)
+	out := *rec
+	return &out
+}
+
+func (cb *dsConnectionBook) Connections(id peer.ID) *track.ConnectionRecord {
+	cb.Lock()
+	defer cb.Unlock()
+	rec, ok := cb.records[id]
+	if !ok {
+		var err error
+		if rec, err = cb.loadConnections(id); err != nil {
+			return nil
+		}
+	}
+	out := *rec
+	return &out
+}
+
+func (cb *dsConnectionBook) RegisterConnected(id peer.ID, dir network.Direction) *track.ConnectionRecord {
+	return cb.update(id, func(rec *track.ConnectionRecord, now time.Time) {
+		switch dir {
+		case network.DirInbound:
+			rec.Inbound += 1
+		case network.DirOutbound:
+			rec.Outbound += 1
+		}
+		// A peer may have multiple connections, only the first starts the connected time
+		if cb.open[id] == 0 {
+			rec.LastConnected = &now
+		}
+		cb.open[id] += 1
+	})
+}
+
+func (cb *dsConnectionBook) RegisterDisconnected(id peer.ID) *track.ConnectionRecord {
+	return cb.update(id, func(rec *track.ConnectionRecord, now time.Time) {
+		if cb.open[id] == 0 {
+			// The connection was opened before tracking started
+			rec.LastDisconnected = &now
+			return
+		}
+		cb.open[id] -= 1
+		if cb.open[id] == 0 {
+			delete(cb.open, id)
+			rec.LastDisconnected = &now
+			if rec.LastConnected != nil {
+				rec.ConnectedDuration += now.Sub(*rec.LastConnected)
+			}
+		}
+	})
+}
+
+func (cb *dsConnectionBook) RegisterDialFailure(id peer.ID) *track.ConnectionRecord {
+	return cb.update(id, func(rec *track.ConnectionRecord, now time.Time) {
+		rec.DialFailures += 1
+	})
+}
@@ -14,11 +14,13 @@ import (
 	"github.com/libp2p/go-libp2p-peerstore/pstoreds"
 	"github.com/multiformats/go-base32"
 	"github.com/protolambda/rumor/p2p/addrutil"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/rumor/p2p/track"
 	"github.com/protolambda/rumor/p2p/track/dstee"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"io"
 	"sync"
+	"time"
 )
 
 var eth2Base = ds.NewKey("/peers/eth2")
@@ -33,8 +35,11 @@ type dsExtendedPeerstore struct {
 	store        ds.Batching
 	peerstore.Peerstore
 	*dsStatusBook
+	*dsGoodbyeBook
 	*dsMetadataBook
 	*dsENRBook
+	*dsScoreBook
+	*dsConnectionBook
 }
 
 func NewExtendedPeerstore(ctx context.Context, store ds.Batching, opts pstoreds.Options) (track.ExtendedPeerstore, error) {
@@ -51,6 +56,10 @@ func NewExtendedPeerstore(ctx context.Context, store ds.Batching, opts pstoreds.
 	if err != nil {
 		return nil, err
 	}
+	gb, err := NewGoodbyeBook(store)
+	if err != nil {
+		return nil, err
+	}
 	mb, err := NewMetadataBook(store)
 	if err != nil {
 		return nil, err
@@ -59,14 +68,25 @@ func NewExtendedPeerstore(ctx context.Context, store ds.Batching, opts pstoreds.
 	if err != nil {
 		return nil, err
 	}
+	scb, err := NewScoreBook(store, ps.LatencyEWMA)
+	if err != nil {
+		return nil, err
+	}
+	cb, err := NewConnectionBook(store)
+	if err != nil {
+		return nil, err
+	}
 
 	return &dsExtendedPeerstore{
-		multiTee:       mul,
-		store:          store,
-		Peerstore:      ps,
-		dsStatusBook:   sb,
-		dsMetadataBook: mb,
-		dsENRBook:      eb,
+		multiTee:         mul,
+		store:            store,
+		Peerstore:        ps,
+		dsStatusBook:     sb,
+		dsGoodbyeBook:    gb,
+		dsMetadataBook:   mb,
+		dsENRBook:        eb,
+		dsScoreBook:      scb,
+		dsConnectionBook: cb,
 	}, nil
 }
 
@@ -105,6 +125,13 @@ func (ep *dsExtendedPeerstore) ListTees() (out []dstee.Tee) {
 	return
 }
 
+// RegisterGoodbye records the goodbye, and updates the score of the peer with the reason of the goodbye
+func (ep *dsExtendedPeerstore) RegisterGoodbye(id peer.ID, reason beacon.Goodbye, backoff time.Duration) *track.GoodbyeRecord {
+	rec := ep.dsGoodbyeBook.RegisterGoodbye(id, reason, backoff)
+	ep.dsScoreBook.RegisterScore(id, track.GoodbyeScoreEvent(reason))
+	return rec
+}
+
 func (ep *dsExtendedPeerstore) ProtocolVersion(id peer.ID) (string, error) {
 	dat, err := ep.Get(id, "ProtocolVersion")
 	if err != nil {
@@ -146,6 +173,7 @@ func (ep *dsExtendedPeerstore) flush() error {
 	weakFlush("statusbook", ep.dsStatusBook)
 	weakFlush("metadatabook", ep.dsMetadataBook)
 	weakFlush("enrbook", ep.dsENRBook)
+	weakFlush("scorebook", ep.dsScoreBook)
 
 	if len(errs) > 0 {
 		return fmt.Errorf("failed while flushing peerstore data; err(s): %q", errs)
@@ -166,6 +194,7 @@ func (ep *dsExtendedPeerstore) Close() error {
 	weakClose("statusbook", ep.dsStatusBook)
 	weakClose("metadatabook", ep.dsMetadataBook)
 	weakClose("enrbook", ep.dsENRBook)
+	weakClose("scorebook", ep.dsScoreBook)
 
 	if len(errs) > 0 {
 		return fmt.Errorf("failed while closing peerstore; err(s): %q", errs)
@@ -195,6 +224,7 @@ func (ep *dsExtendedPeerstore) GetAllData(id peer.ID) *track.PeerAllData {
 		multiAddrs = append(multiAddrs, addr.String())
 	}
 	var enrAttnets *beacon.AttnetBits
+	var enrSyncnets *altair.SyncnetBits
 
 	var forkDigest *beacon.ForkDigest
 	var nextForkVersion *beacon.Version
@@ -210,6 +240,9 @@ func (ep *dsExtendedPeerstore) GetAllData(id peer.ID) *track.PeerAllData {
 		if dat, exists, err := addrutil.ParseEnrAttnets(en); err == nil && exists {
 			enrAttnets = dat
 		}
+		if dat, exists, err := addrutil.ParseEnrSyncnets(en); err == nil && exists {
+			enrSyncnets = dat
+		}
 	}
 	return &track.PeerAllData{
 		PeerID:          id,
@@ -224,9 +257,14 @@ func (ep *dsExtendedPeerstore) GetAllData(id peer.ID) *track.PeerAllData {
 		NextForkVersion: nextForkVersion,
 		NextForkEpoch:   nextForkEpoch,
 		Attnets:         enrAttnets,
+		Syncnets:        enrSyncnets,
 		MetaData:        ep.Metadata(id),
+		MetaDataV2:      ep.MetadataV2(id),
 		ClaimedSeq:      seq,
 		Status:          ep.Status(id),
+		Goodbye:         ep.Goodbye(id),
+		Score:           ep.Score(id),
+		Connections:     ep.Connections(id),
 		ENR:             en,
 	}
 }
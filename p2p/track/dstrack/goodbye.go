@@ -0,0 +1,98 @@
+package dstrack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/p2p/track"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"sync"
+	"time"
+)
+
+var goodbyeSuffix = ds.NewKey("/goodbye")
+
+type dsGoodbyeBook struct {
+	ds ds.Datastore
+	// cache goodbye records, and make updates of the goodbye count atomic.
+	// Peers without a goodbye are cached as nil, to not hit the datastore again on every lookup.
+	sync.Mutex
+	records map[peer.ID]*track.GoodbyeRecord
+}
+
+var _ track.GoodbyeBook = (*dsGoodbyeBook)(nil)
+
+func NewGoodbyeBook(store ds.Datastore) (*dsGoodbyeBook, error) {
+	return &dsGoodbyeBook{ds: store, records: make(map[peer.ID]*track.GoodbyeRecord)}, nil
+}
+
+func (gb *dsGoodbyeBook) loadGoodbye(p peer.ID) (*track.GoodbyeRecord, error) {
+	key := peerIdToKey(eth2Base, p).Child(goodbyeSuffix)
+	value, err := gb.ds.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching goodbye from datastore for peer %s: %w", p.Pretty(), err)
+	}
+	var rec track.GoodbyeRecord
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return nil, fmt.Errorf("failed parse goodbye from datastore: %v", err)
+	}
+	return &rec, nil
+}
+
+func (gb *dsGoodbyeBook) storeGoodbye(p peer.ID, rec *track.GoodbyeRecord) error {
+	key := peerIdToKey(eth2Base, p).Child(goodbyeSuffix)
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed encode goodbye for datastore: %v", err)
+	}
+	if err := gb.ds.Put(key, value); err != nil {
+		return fmt.Errorf("failed to store goodbye: %v", err)
+	}
+	return nil
+}
+
+// get the cached record, or lazy-load it from the datastore. Must hold the lock.
+func (gb *dsGoodbyeBook) get(id peer.ID) *track.GoodbyeRecord {
+	if rec, ok := gb.records[id]; ok {
+		return rec
+	}
+	rec, err := gb.loadGoodbye(id)
+	if errors.Is(err, ds.ErrNotFound) {
+		gb.records[id] = nil
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	gb.records[id] = rec
+	return rec
+}
+
+func (gb *dsGoodbyeBook) Goodbye(id peer.ID) *track.GoodbyeRecord {
+	gb.Lock()
+	defer gb.Unlock()
+	rec := gb.get(id)
+	if rec == nil {
+		return nil
+	}
+	out := *rec
+	return &out
+}
+
+// RegisterGoodbye records the goodbye of the peer, and counts it
+func (gb *dsGoodbyeBook) RegisterGoodbye(id peer.ID, reason beacon.Goodbye, backoff time.Duration) *track.GoodbyeRecord {
+	gb.Lock()
+	defer gb.Unlock()
+	now := time.Now()
+	rec := &track.GoodbyeRecord{Reason: reason, Time: now, Count: 1, BackoffUntil: now.Add(backoff)}
+	if prev := gb.get(id); prev != nil {
+		rec.Count = prev.Count + 1
+	}
+	gb.records[id] = rec
+	// Try persist it to the store
+	_ = gb.storeGoodbye(id, rec)
+	out := *rec
+	return &out
+}
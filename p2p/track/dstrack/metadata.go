@@ -6,6 +6,7 @@ import (
 	"fmt"
 	ds "github.com/ipfs/go-datastore"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/rumor/p2p/track"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"github.com/protolambda/ztyp/codec"
@@ -13,8 +14,9 @@ import (
 )
 
 var (
-	metadataSuffix = ds.NewKey("/metadata")
-	claimSuffix    = ds.NewKey("/metadata_claim")
+	metadataSuffix   = ds.NewKey("/metadata")
+	metadataV2Suffix = ds.NewKey("/metadata_v2")
+	claimSuffix      = ds.NewKey("/metadata_claim")
 )
 
 type dsMetadataBook struct {
@@ -23,6 +25,8 @@ type dsMetadataBook struct {
 	sync.RWMutex
 	// Track metadata with highest sequence number
 	metadatas map[peer.ID]beacon.MetaData
+	// Track Altair metadata with highest sequence number, separately, since not every peer serves it
+	metadatasV2 map[peer.ID]altair.MetaDataV2
 	// highest claimed seq nr, we may not have the actual corresponding metadata yet.
 	claims map[peer.ID]beacon.SeqNr
 	// Track how many times we have tried to ask them for metadata without getting an answer
@@ -33,10 +37,11 @@ var _ track.MetadataBook = (*dsMetadataBook)(nil)
 
 func NewMetadataBook(store ds.Datastore) (*dsMetadataBook, error) {
 	return &dsMetadataBook{
-		ds:        store,
-		metadatas: make(map[peer.ID]beacon.MetaData),
-		claims:    make(map[peer.ID]beacon.SeqNr),
-		fetches:   make(map[peer.ID]uint64),
+		ds:          store,
+		metadatas:   make(map[peer.ID]beacon.MetaData),
+		metadatasV2: make(map[peer.ID]altair.MetaDataV2),
+		claims:      make(map[peer.ID]beacon.SeqNr),
+		fetches:     make(map[peer.ID]uint64),
 	}, nil
 }
 
@@ -66,6 +71,32 @@ func (mb *dsMetadataBook) storeMetadata(p peer.ID, md *beacon.MetaData) error {
 	return nil
 }
 
+func (mb *dsMetadataBook) loadMetadataV2(p peer.ID) (*altair.MetaDataV2, error) {
+	key := peerIdToKey(eth2Base, p).Child(metadataV2Suffix)
+	value, err := mb.ds.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching metadata v2 from datastore for peer %s: %s\n", p.Pretty(), err)
+	}
+	var md altair.MetaDataV2
+	if err := md.Deserialize(codec.NewDecodingReader(bytes.NewReader(value), uint64(len(value)))); err != nil {
+		return nil, fmt.Errorf("failed parse metadata v2 bytes from datastore: %v", err)
+	}
+	return &md, nil
+}
+
+func (mb *dsMetadataBook) storeMetadataV2(p peer.ID, md *altair.MetaDataV2) error {
+	key := peerIdToKey(eth2Base, p).Child(metadataV2Suffix)
+	size := md.FixedLength()
+	out := bytes.NewBuffer(make([]byte, 0, size))
+	if err := md.Serialize(codec.NewEncodingWriter(out)); err != nil {
+		return fmt.Errorf("failed encode metadata v2 bytes for datastore: %v", err)
+	}
+	if err := mb.ds.Put(key, out.Bytes()); err != nil {
+		return fmt.Errorf("failed to store metadata v2: %v", err)
+	}
+	return nil
+}
+
 func (mb *dsMetadataBook) loadClaim(p peer.ID) (beacon.SeqNr, error) {
 	key := peerIdToKey(eth2Base, p).Child(claimSuffix)
 	value, err := mb.ds.Get(key)
@@ -105,6 +136,25 @@ func (mb *dsMetadataBook) metadata(id peer.ID) *beacon.MetaData {
 	return &dat
 }
 
+func (mb *dsMetadataBook) MetadataV2(id peer.ID) *altair.MetaDataV2 {
+	mb.Lock()
+	defer mb.Unlock()
+	return mb.metadataV2(id)
+}
+
+func (mb *dsMetadataBook) metadataV2(id peer.ID) *altair.MetaDataV2 {
+	dat, ok := mb.metadatasV2[id]
+	if !ok {
+		md, err := mb.loadMetadataV2(id)
+		if err != nil {
+			return nil
+		}
+		mb.metadatasV2[id] = *md
+		return md
+	}
+	return &dat
+}
+
 func (mb *dsMetadataBook) ClaimedSeq(id peer.ID) (seq beacon.SeqNr, ok bool) {
 	mb.Lock()
 	defer mb.Unlock()
@@ -152,6 +202,26 @@ func (mb *dsMetadataBook) RegisterMetaFetch(id peer.ID) uint64 {
 func (mb *dsMetadataBook) RegisterMetadata(id peer.ID, md beacon.MetaData) (newer bool) {
 	mb.Lock()
 	defer mb.Unlock()
+	return mb.registerMetadata(id, md)
+}
+
+// RegisterMetadataV2 updates the Altair metadata, if newer than previous, and the phase0 metadata with it.
+func (mb *dsMetadataBook) RegisterMetadataV2(id peer.ID, md altair.MetaDataV2) (newer bool) {
+	mb.Lock()
+	defer mb.Unlock()
+	dat := mb.metadataV2(id)
+	newer = dat == nil || dat.SeqNumber < md.SeqNumber
+	if newer {
+		mb.metadatasV2[id] = md
+		_ = mb.storeMetadataV2(id, &md)
+	}
+	// the phase0 metadata may be older, if the peer served v1 before, or the same age, if the peer served it too.
+	v1Newer := mb.registerMetadata(id, md.V1())
+	return newer || v1Newer
+}
+
+// registerMetadata updates the metadata, the lock must be held
+func (mb *dsMetadataBook) registerMetadata(id peer.ID, md beacon.MetaData) (newer bool) {
 	dat := mb.metadata(id)
 	newer = dat == nil || dat.SeqNumber < md.SeqNumber
 	if newer {
@@ -186,6 +256,11 @@ func (mb *dsMetadataBook) flush() error {
 			return err
 		}
 	}
+	for id, md := range mb.metadatasV2 {
+		if err := mb.storeMetadataV2(id, &md); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
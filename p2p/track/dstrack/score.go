@@ -0,0 +1,278 @@
+package dstrack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/multiformats/go-base32"
+	"github.com/protolambda/rumor/p2p/track"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+var scoreSuffix = ds.NewKey("/score")
+
+// The score of a peer halves every half-life, so old behavior is forgotten over time
+const scoreHalfLife = 10 * time.Minute
+
+// Changed scores are kept in memory, and persisted to the datastore every flush interval, and on close.
+const scoreFlushInterval = time.Minute
+
+// The score is bounded, so a peer can recover from bad behavior, and cannot build up unlimited credit.
+const maxScore = 100.0
+
+// Latency above this threshold is penalized, by 1 point per threshold-duration, up to maxLatencyPenalty
+const (
+	latencyThreshold  = 250 * time.Millisecond
+	maxLatencyPenalty = 10.0
+)
+
+var scoreWeights = map[track.ScoreEvent]float64{
+	track.ScoreRPCSuccess:        1,
+	track.ScoreRPCFailure:        -5,
+	track.ScoreRPCTimeout:        -3,
+	track.ScoreDialSuccess:       1,
+	track.ScoreDialFailure:       -2,
+	track.ScoreGossipAccept:      0.1,
+	track.ScoreGossipIgnore:      -0.5,
+	track.ScoreGossipReject:      -10,
+	track.ScoreGoodbye:           0,
+	track.ScoreGoodbyeIrrelevant: -20,
+	track.ScoreGoodbyeFault:      -50,
+}
+
+// scoreRecord is the stored score, without decay since the time it was updated
+type scoreRecord struct {
+	Score   float64                     `json:"score"`
+	Updated time.Time                   `json:"updated"`
+	Counts  map[track.ScoreEvent]uint64 `json:"counts,omitempty"`
+}
+
+// decayed returns the score, decayed to the given time
+func (r *scoreRecord) decayed(t time.Time) float64 {
+	elapsed := t.Sub(r.Updated)
+	if elapsed <= 0 {
+		return r.Score
+	}
+	return r.Score * math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+}
+
+type dsScoreBook struct {
+	ds ds.Datastore
+	// latency of the peer, to penalize slow peers with
+	latency func(peer.ID) time.Duration
+	// cache score records, and make updates of the scores atomic.
+	// Unscored peers are cached as nil, to not hit the datastore again on every lookup.
+	sync.Mutex
+	records map[peer.ID]*scoreRecord
+	// peers with a score that changed since the last flush
+	dirty map[peer.ID]struct{}
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+var _ track.ScoreBook = (*dsScoreBook)(nil)
+
+func NewScoreBook(store ds.Datastore, latency func(peer.ID) time.Duration) (*dsScoreBook, error) {
+	sb := &dsScoreBook{
+		ds:      store,
+		latency: latency,
+		records: make(map[peer.ID]*scoreRecord),
+		dirty:   make(map[peer.ID]struct{}),
+		closeCh: make(chan struct{}),
+	}
+	go sb.flushLoop()
+	return sb, nil
+}
+
+func (sb *dsScoreBook) loadScore(p peer.ID) (*scoreRecord, error) {
+	key := peerIdToKey(eth2Base, p).Child(scoreSuffix)
+	value, err := sb.ds.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching score from datastore for peer %s: %w", p.Pretty(), err)
+	}
+	var rec scoreRecord
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return nil, fmt.Errorf("failed parse score from datastore: %v", err)
+	}
+	return &rec, nil
+}
+
+func (sb *dsScoreBook) storeScore(p peer.ID, rec *scoreRecord) error {
+	key := peerIdToKey(eth2Base, p).Child(scoreSuffix)
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed encode score for datastore: %v", err)
+	}
+	if err := sb.ds.Put(key, value); err != nil {
+		return fmt.Errorf("failed to store score: %v", err)
+	}
+	return nil
+}
+
+// get the cached record, or lazy-load it from the datastore.
+// The record is nil if the peer is not scored yet. Must hold the lock.
+func (sb *dsScoreBook) get(id peer.ID) (*scoreRecord, error) {
+	if rec, ok := sb.records[id]; ok {
+		return rec, nil
+	}
+	rec, err := sb.loadScore(id)
+	if errors.Is(err, ds.ErrNotFound) {
+		sb.records[id] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sb.records[id] = rec
+	return rec, nil
+}
+
+// view computes the score of the record at the given time. Must hold the lock.
+func (sb *dsScoreBook) view(id peer.ID, rec *scoreRecord, t time.Time) *track.PeerScore {
+	out := &track.PeerScore{
+		Score:   rec.decayed(t),
+		Updated: rec.Updated,
+		Counts:  make(map[track.ScoreEvent]uint64, len(rec.Counts)),
+	}
+	for k, v := range rec.Counts {
+		out.Counts[k] = v
+	}
+	if sb.latency != nil {
+		if lat := sb.latency(id); lat > latencyThreshold {
+			out.LatencyPenalty = math.Min(float64(lat-latencyThreshold)/float64(latencyThreshold), maxLatencyPenalty)
+			out.Score -= out.LatencyPenalty
+		}
+	}
+	return out
+}
+
+func (sb *dsScoreBook) Score(id peer.ID) *track.PeerScore {
+	sb.Lock()
+	defer sb.Unlock()
+	rec, err := sb.get(id)
+	if err != nil || rec == nil {
+		return nil
+	}
+	return sb.view(id, rec, time.Now())
+}
+
+// RegisterScore decays the score of the peer, and then applies the weight of the event.
+// The score is left untouched, and nil is returned, if the existing score could not be loaded.
+func (sb *dsScoreBook) RegisterScore(id peer.ID, ev track.ScoreEvent) *track.PeerScore {
+	sb.Lock()
+	defer sb.Unlock()
+	now := time.Now()
+	rec, err := sb.get(id)
+	if err != nil {
+		return nil
+	}
+	if rec == nil {
+		rec = &scoreRecord{Counts: make(map[track.ScoreEvent]uint64)}
+		sb.records[id] = rec
+	}
+	if rec.Counts == nil {
+		rec.Counts = make(map[track.ScoreEvent]uint64)
+	}
+	rec.Score = math.Max(-maxScore, math.Min(maxScore, rec.decayed(now)+scoreWeights[ev]))
+	rec.Updated = now
+	rec.Counts[ev] += 1
+	// persisted with the next flush, to not hit the datastore on every event
+	sb.dirty[id] = struct{}{}
+	return sb.view(id, rec, now)
+}
+
+// ScoredPeers lists the peers with a score in the datastore, or in the cache
+func (sb *dsScoreBook) ScoredPeers() peer.IDSlice {
+	sb.Lock()
+	defer sb.Unlock()
+	seen := make(map[peer.ID]struct{}, len(sb.records))
+	var out peer.IDSlice
+	for id, rec := range sb.records {
+		if rec == nil {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	res, err := sb.ds.Query(query.Query{Prefix: eth2Base.String(), KeysOnly: true})
+	if err != nil {
+		return out
+	}
+	defer res.Close()
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			break
+		}
+		key := ds.RawKey(entry.Key)
+		if !strings.HasSuffix(key.String(), scoreSuffix.String()) {
+			continue
+		}
+		idBytes, err := base32.RawStdEncoding.DecodeString(key.Parent().BaseNamespace())
+		if err != nil {
+			continue
+		}
+		id := peer.ID(idBytes)
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (sb *dsScoreBook) flushLoop() {
+	ticker := time.NewTicker(scoreFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = sb.flush()
+		case <-sb.closeCh:
+			return
+		}
+	}
+}
+
+// flush stores the changed scores to the datastore. The datastore is written without holding the lock,
+// so registering new scores is not blocked. Scores that fail to store are retried with the next flush.
+func (sb *dsScoreBook) flush() error {
+	sb.Lock()
+	changed := make(map[peer.ID]scoreRecord, len(sb.dirty))
+	for id := range sb.dirty {
+		rec := *sb.records[id]
+		rec.Counts = make(map[track.ScoreEvent]uint64, len(sb.records[id].Counts))
+		for k, v := range sb.records[id].Counts {
+			rec.Counts[k] = v
+		}
+		changed[id] = rec
+	}
+	sb.dirty = make(map[peer.ID]struct{})
+	sb.Unlock()
+
+	var firstErr error
+	for id, rec := range changed {
+		if err := sb.storeScore(id, &rec); err != nil {
+			sb.Lock()
+			sb.dirty[id] = struct{}{}
+			sb.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (sb *dsScoreBook) Close() error {
+	sb.closeOnce.Do(func() {
+		close(sb.closeCh)
+	})
+	return sb.flush()
+}
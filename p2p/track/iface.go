@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	ds "github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/protolambda/rumor/p2p/altair"
 	"github.com/protolambda/rumor/p2p/track/dstee"
 	"github.com/protolambda/zrnt/eth2/beacon"
 	"time"
@@ -35,10 +37,102 @@ type StatusBook interface {
 
 type MetadataBook interface {
 	Metadata(peer.ID) *beacon.MetaData
+	// MetadataV2 retrieves the Altair metadata, and may be nil if the peer did not serve it yet
+	MetadataV2(peer.ID) *altair.MetaDataV2
 	ClaimedSeq(peer.ID) (seq beacon.SeqNr, ok bool)
 	RegisterSeqClaim(id peer.ID, seq beacon.SeqNr) (newer bool)
 	RegisterMetaFetch(peer.ID) uint64
 	RegisterMetadata(id peer.ID, md beacon.MetaData) (newer bool)
+	// RegisterMetadataV2 updates the Altair metadata, and the phase0 metadata with it, if newer than before
+	RegisterMetadataV2(id peer.ID, md altair.MetaDataV2) (newer bool)
+}
+
+// GoodbyeRecord is the latest goodbye of a peer
+type GoodbyeRecord struct {
+	Reason beacon.Goodbye `json:"reason"`
+	Time   time.Time      `json:"time"`
+	// Total number of goodbyes of the peer
+	Count uint64 `json:"count"`
+	// Do not reconnect to the peer before this time
+	BackoffUntil time.Time `json:"backoff_until"`
+}
+
+// InBackoff returns true if the peer should not be reconnected to at the given time
+func (r *GoodbyeRecord) InBackoff(t time.Time) bool {
+	return r != nil && t.Before(r.BackoffUntil)
+}
+
+type GoodbyeBook interface {
+	// Goodbye retrieves the latest goodbye of the peer, and may be nil if the peer never said goodbye
+	Goodbye(peer.ID) *GoodbyeRecord
+	// RegisterGoodbye records a goodbye of the peer, with a backoff before reconnecting to the peer
+	RegisterGoodbye(id peer.ID, reason beacon.Goodbye, backoff time.Duration) *GoodbyeRecord
+}
+
+// ConnectionRecord is the connection history of a peer
+type ConnectionRecord struct {
+	// First time a connection with the peer was made or attempted
+	FirstSeen time.Time `json:"first_seen"`
+	// Nil if never connected
+	LastConnected *time.Time `json:"last_connected,omitempty"`
+	// Nil if never disconnected
+	LastDisconnected *time.Time `json:"last_disconnected,omitempty"`
+	// Total time connected to the peer, excluding the current connection if any
+	ConnectedDuration time.Duration `json:"connected_duration"`
+	Inbound           uint64        `json:"inbound"`
+	Outbound          uint64        `json:"outbound"`
+	DialFailures      uint64        `json:"dial_failures"`
+}
+
+type ConnectionBook interface {
+	// Connections retrieves the connection history of the peer, and may be nil if the peer was never connected to
+	Connections(peer.ID) *ConnectionRecord
+	// RegisterConnected records a new connection with the peer.
+	// The Register methods return the updated record, or nil if the existing record could not be loaded.
+	RegisterConnected(id peer.ID, dir network.Direction) *ConnectionRecord
+	// RegisterDisconnected records a closed connection with the peer
+	RegisterDisconnected(id peer.ID) *ConnectionRecord
+	// RegisterDialFailure records a failed attempt to connect to the peer
+	RegisterDialFailure(id peer.ID) *ConnectionRecord
+}
+
+// ScoreEvent is a kind of peer behavior that affects the score of the peer
+type ScoreEvent string
+
+const (
+	ScoreRPCSuccess   ScoreEvent = "rpc_success"
+	ScoreRPCFailure   ScoreEvent = "rpc_failure"
+	ScoreRPCTimeout   ScoreEvent = "rpc_timeout"
+	ScoreDialSuccess  ScoreEvent = "dial_success"
+	ScoreDialFailure  ScoreEvent = "dial_failure"
+	ScoreGossipAccept ScoreEvent = "gossip_accept"
+	ScoreGossipIgnore ScoreEvent = "gossip_ignore"
+	ScoreGossipReject ScoreEvent = "gossip_reject"
+	// Goodbyes are scored by the severity of the reason, see GoodbyeScoreEvent
+	ScoreGoodbye           ScoreEvent = "goodbye"
+	ScoreGoodbyeIrrelevant ScoreEvent = "goodbye_irrelevant"
+	ScoreGoodbyeFault      ScoreEvent = "goodbye_fault"
+)
+
+// PeerScore is the reputation of a peer, decayed to the time it was retrieved at
+type PeerScore struct {
+	// Total score, including the latency penalty
+	Score float64 `json:"score"`
+	// Penalty for a high latency, subtracted from the total score
+	LatencyPenalty float64 `json:"latency_penalty,omitempty"`
+	// Time of the last scored event
+	Updated time.Time `json:"updated"`
+	// Number of scored events, by kind
+	Counts map[ScoreEvent]uint64 `json:"counts,omitempty"`
+}
+
+type ScoreBook interface {
+	// Score retrieves the score of the peer, decayed to the current time, and may be nil if the peer was never scored
+	Score(peer.ID) *PeerScore
+	// RegisterScore scores an event of the peer, and returns the updated score
+	RegisterScore(id peer.ID, ev ScoreEvent) *PeerScore
+	// ScoredPeers lists all peers that have a score
+	ScoredPeers() peer.IDSlice
 }
 
 type PeerAllData struct {
@@ -58,14 +152,23 @@ type PeerAllData struct {
 	NextForkVersion *beacon.Version    `json:"enr_next_fork_version,omitempty"`
 	NextForkEpoch   *beacon.Epoch      `json:"enr_next_fork_epoch,omitempty"`
 
-	Attnets *beacon.AttnetBits `json:"enr_attnets,omitempty"`
+	Attnets  *beacon.AttnetBits  `json:"enr_attnets,omitempty"`
+	Syncnets *altair.SyncnetBits `json:"enr_syncnets,omitempty"`
 
 	// Metadata with highest sequence number
 	MetaData *beacon.MetaData `json:"metadata,omitempty"`
+	// Altair metadata with highest sequence number
+	MetaDataV2 *altair.MetaDataV2 `json:"metadata_v2,omitempty"`
 	// Highest claimed seq nr, we may not have the actual corresponding metadata yet.
 	ClaimedSeq beacon.SeqNr `json:"claimed_seq,omitempty"`
 	// Latest status
 	Status *beacon.Status `json:"status,omitempty"`
+	// Latest goodbye
+	Goodbye *GoodbyeRecord `json:"goodbye,omitempty"`
+	// Current score
+	Score *PeerScore `json:"score,omitempty"`
+	// Connection history
+	Connections *ConnectionRecord `json:"connections,omitempty"`
 	// Latest ENR
 	ENR *enode.Node `json:"enr,omitempty"`
 }
@@ -100,8 +203,11 @@ type ExtendedPeerstore interface {
 	Datastore() ds.Batching
 	peerstore.Peerstore
 	StatusBook
+	GoodbyeBook
+	ScoreBook
+	ConnectionBook
 	MetadataBook
 	ENRBook
+	IdentifyBook
 	AllDataGetter
-	// TODO: maybe track when we've last been connected to a peer?
 }
@@ -0,0 +1,92 @@
+package track
+
+import (
+	"context"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/protolambda/rumor/p2p/rpc/methods"
+	"github.com/protolambda/rumor/p2p/rpc/reqresp"
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/ztyp/codec"
+)
+
+// RPCScoreEvent is the score event of an RPC request that was made to a peer.
+// The request succeeded if there was no error and the response had a success result code.
+func RPCScoreEvent(err error, success bool) ScoreEvent {
+	if err != nil {
+		if kind, ok := reqresp.ErrKind(err); ok && kind == reqresp.TimeoutErrKind {
+			return ScoreRPCTimeout
+		}
+		return ScoreRPCFailure
+	}
+	if !success {
+		return ScoreRPCFailure
+	}
+	return ScoreRPCSuccess
+}
+
+// ScoredRequest runs the request like RPCMethod.RunRequest, and scores the peer by the outcome, see RPCScoreEvent.
+// The request is successful if all response chunks have a success result code.
+// The book may be nil, to not score the request.
+func ScoredRequest(book ScoreBook, m *reqresp.RPCMethod, ctx context.Context, newStreamFn reqresp.NewStreamFn,
+	peerId peer.ID, comp reqresp.Compression, req reqresp.RequestInput, maxRespChunks uint64, madeRequest func() error,
+	onResponse reqresp.OnResponseListener) error {
+	success := true
+	err := m.RunRequest(ctx, newStreamFn, peerId, comp, req, maxRespChunks, madeRequest,
+		func(chunk reqresp.ChunkedResponseHandler) error {
+			if chunk.ResultCode() != reqresp.SuccessCode {
+				success = false
+			}
+			return onResponse(chunk)
+		})
+	if book != nil {
+		book.RegisterScore(peerId, RPCScoreEvent(err, success))
+	}
+	return err
+}
+
+// ScoredRequestListener wraps the listener of requests from peers, to penalize peers that send invalid requests.
+// The book may be nil, to not score the requests.
+func ScoredRequestListener(book ScoreBook, listener reqresp.OnRequestListener) reqresp.OnRequestListener {
+	if book == nil {
+		return listener
+	}
+	return func(ctx context.Context, peerId peer.ID, handler reqresp.ChunkedRequestHandler) {
+		listener(ctx, peerId, &scoredRequestHandler{ChunkedRequestHandler: handler, book: book, peerId: peerId})
+	}
+}
+
+// scoredRequestHandler scores the peer when its request cannot be read
+type scoredRequestHandler struct {
+	reqresp.ChunkedRequestHandler
+	book   ScoreBook
+	peerId peer.ID
+}
+
+func (h *scoredRequestHandler) ReadRequest(dest codec.Deserializable) error {
+	err := h.ChunkedRequestHandler.ReadRequest(dest)
+	if err != nil {
+		h.book.RegisterScore(h.peerId, ScoreRPCFailure)
+	}
+	return err
+}
+
+func (h *scoredRequestHandler) RawRequest() ([]byte, error) {
+	data, err := h.ChunkedRequestHandler.RawRequest()
+	if err != nil {
+		h.book.RegisterScore(h.peerId, ScoreRPCFailure)
+	}
+	return data, err
+}
+
+// GoodbyeScoreEvent is the score event of a goodbye of a peer.
+// Shutting down, or having too many peers, is neutral. Other known reasons are scored by severity.
+func GoodbyeScoreEvent(reason beacon.Goodbye) ScoreEvent {
+	switch reason {
+	case methods.GoodbyeIrrelevantNetwork:
+		return ScoreGoodbyeIrrelevant
+	case methods.GoodbyeFaultError, methods.GoodbyeUnableToVerifyNetwork, methods.GoodbyeBadScore, methods.GoodbyeBanned:
+		return ScoreGoodbyeFault
+	default:
+		return ScoreGoodbye
+	}
+}